@@ -0,0 +1,155 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultMigrationsTable table records applied migration steps when Config.MigrationsTable is blank
+const DefaultMigrationsTable = "migrations"
+
+// MigrationStep a step of ordered migrations, either a schema change or a data change
+type MigrationStep struct {
+	ID     string
+	Schema func(gorm.Migrator) error
+	Data   func(*gorm.DB) error
+}
+
+// migrationRecord row of the migrations table
+type migrationRecord struct {
+	ID        string `gorm:"primarykey;size:191"`
+	AppliedAt time.Time
+}
+
+func (m Migrator) migrationsTable() string {
+	if m.MigrationsTable != "" {
+		return m.MigrationsTable
+	}
+	return DefaultMigrationsTable
+}
+
+// AppliedMigrations returns IDs of migration steps recorded in the migrations table in applied order
+func (m Migrator) AppliedMigrations() (ids []string, err error) {
+	table := m.migrationsTable()
+	if !m.migratorOf(m.DB).HasTable(table) {
+		return nil, nil
+	}
+
+	var records []migrationRecord
+	err = m.DB.Table(table).Order("applied_at").Order("id").Find(&records).Error
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+	return
+}
+
+// RunMigrations run migration steps in order, skips steps already recorded in the migrations table
+func (m Migrator) RunMigrations(steps ...MigrationStep) (err error) {
+	seen := map[string]bool{}
+	for _, step := range steps {
+		if step.ID == "" {
+			return errors.New("migration step requires an ID")
+		} else if seen[step.ID] {
+			return fmt.Errorf("duplicated migration step %v", step.ID)
+		} else if (step.Schema == nil) == (step.Data == nil) {
+			return fmt.Errorf("migration step %v requires either a schema change or a data change", step.ID)
+		}
+		seen[step.ID] = true
+	}
+
+	table := m.migrationsTable()
+	if locker, ok := m.migratorOf(m.DB).(MigrationLockInterface); ok {
+		lock, unlock := locker.MigrationLockSQL(table)
+
+		// session locks are held by the connection, steps run on it until unlocked
+		if pool, ok := m.DB.Statement.ConnPool.(interface {
+			Conn(context.Context) (*sql.Conn, error)
+		}); ok {
+			conn, err := pool.Conn(m.DB.Statement.Context)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			m.DB = m.DB.Session(&gorm.Session{Context: m.DB.Statement.Context})
+			m.DB.Statement.ConnPool = conn
+		}
+
+		if err := m.DB.Exec(lock).Error; err != nil {
+			return err
+		}
+
+		defer func() {
+			if unlockErr := m.DB.Exec(unlock).Error; err == nil {
+				err = unlockErr
+			}
+		}()
+	}
+
+	if !m.migratorOf(m.DB).HasTable(table) {
+		creator := m
+		creator.DB = m.DB.Table(table)
+		if err := creator.CreateTable(&migrationRecord{}); err != nil {
+			return err
+		}
+	}
+
+	ids, err := m.AppliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	applied := map[string]bool{}
+	for _, id := range ids {
+		applied[id] = true
+	}
+
+	for _, step := range steps {
+		if !applied[step.ID] {
+			if err := m.runMigrationStep(table, step); err != nil {
+				return fmt.Errorf("failed to run migration step %v: %w", step.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// MigrationLockInterface dialects implement it to serialize RunMigrations with a session lock, e.g: pg_advisory_lock (Postgres)
+type MigrationLockInterface interface {
+	MigrationLockSQL(name string) (lock string, unlock string)
+}
+
+// ImplicitCommitInterface dialects implement it if DDL commits the transaction implicitly, e.g: MySQL
+type ImplicitCommitInterface interface {
+	DDLCommitsImplicitly() bool
+}
+
+func (m Migrator) runMigrationStep(table string, step MigrationStep) error {
+	record := func(tx *gorm.DB) error {
+		return tx.Table(table).Create(&migrationRecord{ID: step.ID, AppliedAt: time.Now()}).Error
+	}
+
+	if committer, ok := m.migratorOf(m.DB).(ImplicitCommitInterface); ok && step.Schema != nil && committer.DDLCommitsImplicitly() {
+		if err := step.Schema(m.migratorOf(m.DB)); err != nil {
+			return err
+		}
+		return record(m.DB)
+	}
+
+	// recorded before the change, concurrent runs without the migration lock wait for the record and fail on its primary key
+	return m.DB.Transaction(func(tx *gorm.DB) error {
+		if err := record(tx); err != nil {
+			return err
+		}
+
+		if step.Schema != nil {
+			return step.Schema(m.migratorOf(tx))
+		}
+		return step.Data(tx)
+	})
+}
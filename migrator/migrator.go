@@ -1,10 +1,17 @@
 package migrator
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -18,9 +25,56 @@ type Migrator struct {
 
 // Config schema config
 type Config struct {
-	CreateIndexAfterCreateTable             bool
-	AllowDeferredConstraintsWhenAutoMigrate bool
-	DB                                      *gorm.DB
+	CreateIndexAfterCreateTable             bool // create indexes with CREATE INDEX after CREATE TABLE instead of inline
+	AllowDeferredConstraintsWhenAutoMigrate bool // allow DEFERRABLE constraints created by AutoMigrate
+	SkipUnsupported                         bool // skip operations returning ErrNotImplemented, recorded as skip_<operation>
+
+	// opt-ins of AutoMigrate reconciling existing tables with models
+	MigrateColumnTypesWhenAutoMigrate         bool // alter columns whose data type differs from the model, e.g: int => bigint
+	MigrateDefaultValuesWhenAutoMigrate       bool // set or drop column defaults changed by the model
+	MigrateNullabilityWhenAutoMigrate         bool // set or drop NOT NULL of columns changed by the model
+	MigrateColumnCommentsWhenAutoMigrate      bool // set column comments changed by the model
+	MigrateUniqueWhenAutoMigrate              bool // add or drop unique constraints of columns gaining or losing unique, see AlterColumnUnique
+	RecreateChangedConstraintsWhenAutoMigrate bool // drop and create check constraints and foreign keys changed by the model
+	RecreateGeneratedColumnsWhenAutoMigrate   bool // drop and add generated columns whose expression changed
+	ReorderColumnsWhenAutoMigrate             bool // move existing columns to the order of ColumnOrder (MySQL)
+	RemoveEnumValuesWhenAutoMigrate           bool // recreate enum checks even if values are removed, adding values is always allowed
+	PromoteUniqueIndexesWhenAutoMigrate       bool // promote unique indexes on primary key columns to primary key of tables without one
+	DropObsoleteForeignKeysWhenAutoMigrate    bool // drop foreign keys of relationships removed from the model, the columns are kept
+	DropUnusedColumnsWhenAutoMigrate          bool // drop columns no field maps to anymore, columns of ignored fields are kept
+
+	// column changes
+	AllowDestructiveColumnChanges bool                            // allow column changes of ColumnChangeDestructive risk, e.g: narrowing types
+	ColumnChangeHook              func(change ColumnChange) error // review column type changes of AutoMigrate, returns error to block the change
+
+	// constraints and indexes
+	ValidateCheckConstraints bool   // count rows violating check constraints before adding them, returns CheckViolationError
+	CreateForeignKeyIndexes  bool   // create backing indexes of foreign keys not covered by other indexes
+	AddForeignKeysWithColumn bool   // add the foreign key of a column in the statement of AddColumn
+	AddForeignKeysNotValid   bool   // add foreign keys to existing tables with NOT VALID, see ValidatePendingConstraints (Postgres)
+	SoftDeleteUniqueIndexes  bool   // scope unique indexes of soft deletable models to rows not deleted, ignored by mysql
+	IndexMaintenanceWorkers  int    // parallel workers building indexes, e.g: max_parallel_maintenance_workers (Postgres)
+	MaxIndexKeyLength        int    // byte limit of index keys, defaults to DefaultMaxIndexKeyLength, e.g: 767 (MySQL COMPACT)
+	ColumnOrder              string // column order of created tables, ColumnOrderAsDefined (default), ColumnOrderPKFirst or ColumnOrderAlphabetical
+
+	// execution of DDL statements
+	SavePointPerModel       bool                                              // roll back failed models to their savepoint and continue, ignored by mysql
+	MigrateStatementTimeout time.Duration                                     // cancel DDL statements running longer, e.g: statement_timeout (Postgres), a context deadline for others
+	MigrateLockTimeout      time.Duration                                     // fail DDL statements waiting longer for locks, e.g: lock_timeout (Postgres)
+	InlineDDL               bool                                              // render DDL statements with values inlined instead of bind vars
+	OnlineSchemaChangeHook  func(change OnlineSchemaChange) error             // run ALTER TABLE statements with a tool instead, e.g: gh-ost (MySQL)
+	DataTypeHook            func(field *schema.Field, dataType string) string // rewrite data types of columns, e.g: adding COLLATE
+	PreMigrate              map[string][]string                               // raw SQL run before migrating the table, e.g: {"users": {"CREATE EXTENSION citext"}}
+	PostMigrate             map[string][]string                               // raw SQL run after migrating the table, e.g: {"users": {"ANALYZE users"}}
+	ProgressHook            func(progress MigrateProgress)                    // report each model migrated by AutoMigrate, e.g: logging 2/5 users
+
+	// table settings
+	TableOwner              string // owner of tables created by AutoMigrate (Postgres)
+	ReplicaIdentity         string // replica identity of migrated tables, e.g: FULL, USING INDEX idx_users_email (Postgres)
+	MigrationsTable         string // table of steps applied by RunMigrations, defaults to DefaultMigrationsTable
+	PendingConstraintsTable string // table of constraints of AddForeignKeysNotValid, defaults to DefaultPendingConstraintsTable
+
+	DB *gorm.DB
 	gorm.Dialector
 }
 
@@ -28,6 +82,100 @@ type GormDataTypeInterface interface {
 	GormDBDataType(*gorm.DB, *schema.Field) string
 }
 
+// AutoMigrateInterface auto migration with results, plans and validation, e.g: db.Migrator().(AutoMigrateInterface).PlanAutoMigrate(&User{})
+type AutoMigrateInterface interface {
+	AutoMigrateWithResult(dst ...interface{}) (AutoMigrateResult, error)
+	AutoMigrateContext(ctx context.Context, dst ...interface{}) error
+	PlanAutoMigrate(dst ...interface{}) (AutoMigrateResult, error)
+	Validate(dst ...interface{}) error
+	RepairSchema(dst ...interface{}) error
+	DiffModels(a, b interface{}) (*SchemaDiff, error)
+}
+
+// SchemaInterface migrator scoped to a schema
+type SchemaInterface interface {
+	WithSchema(name string) gorm.Migrator
+}
+
+// TableInterface table operations besides gorm.Migrator
+type TableInterface interface {
+	BuildCreateTableSQL(dst interface{}) (string, []interface{}, error)
+	CreateTableLike(dst, src interface{}, including ...string) error
+	CreateTableAs(dst string, query *gorm.DB) error
+	IsTableEmpty(dst interface{}) (bool, error)
+	SetTableSchema(dst interface{}, schema string) error
+	SetAutovacuum(dst interface{}, enabled bool) error
+	WithAutovacuumDisabled(dst interface{}, fc func() error) error
+}
+
+// ColumnInterface column operations besides gorm.Migrator
+type ColumnInterface interface {
+	ShadowAlterColumn(dst interface{}, field string, batchSize int) error
+	AlterColumnsNullability(dst interface{}, fields ...string) error
+	AlterColumnUnique(dst interface{}, field string) error
+	MigrateColumn(dst interface{}, field *schema.Field, columnType *sql.ColumnType) error
+	HasColumnType(dst interface{}, column, dataType string) (bool, error)
+	HasDefault(dst interface{}, column string) (bool, error)
+}
+
+// ConstraintInterface constraint operations besides gorm.Migrator
+type ConstraintInterface interface {
+	CreateConstraints(dst interface{}) error
+}
+
+// IndexInterface index operations besides gorm.Migrator
+type IndexInterface interface {
+	CreateIndexOn(dst interface{}, name string, table string) error
+	DropIndexIfExists(dst interface{}, name string) error
+	HasIndexColumns(dst interface{}, columns ...string) (bool, error)
+	PromoteToPrimaryKey(dst interface{}, index string) error
+}
+
+// ConfigInterface dialect migrators could run with the options of the calling Migrator
+type ConfigInterface interface {
+	WithConfig(config Config) gorm.Migrator
+}
+
+// WithConfig copy of m running with the options of config, keeps DB and Dialector of m
+func (m Migrator) WithConfig(config Config) gorm.Migrator {
+	return m.withOptionsOf(Migrator{Config: config})
+}
+
+// migratorOf migrator of the dialect running with db and options of m, which db.Migrator() resets
+func (m Migrator) migratorOf(db *gorm.DB) gorm.Migrator {
+	dialectMigrator := db.Migrator()
+	if dialect, ok := dialectMigrator.(Migrator); ok {
+		return dialect.withOptionsOf(m)
+	}
+
+	if configurer, ok := dialectMigrator.(ConfigInterface); ok {
+		// WithConfig promoted from the anonymous Migrator field would drop methods of the dialect migrator
+		if configured := configurer.WithConfig(m.Config); !isMigrator(configured) {
+			return configured
+		}
+	}
+	return dialectMigrator
+}
+
+func isMigrator(migrator gorm.Migrator) bool {
+	_, ok := migrator.(Migrator)
+	return ok
+}
+
+// withOptionsOf copy of m with the config of options, keeps DB and Dialector of m
+func (m Migrator) withOptionsOf(options Migrator) Migrator {
+	db, dialector := m.DB, m.Dialector
+	m.Config = options.Config
+	m.DB, m.Dialector = db, dialector
+	return m
+}
+
+// withDB copy of m running with db
+func (m Migrator) withDB(db *gorm.DB) Migrator {
+	m.DB = db
+	return m
+}
+
 func (m Migrator) RunWithValue(value interface{}, fc func(*gorm.Statement) error) error {
 	stmt := &gorm.Statement{DB: m.DB}
 	if m.DB.Statement != nil {
@@ -43,11 +191,364 @@ func (m Migrator) RunWithValue(value interface{}, fc func(*gorm.Statement) error
 	return fc(stmt)
 }
 
+// StatementTimeoutInterface dialects implement it to limit the execution time of DDL statements in current session
+type StatementTimeoutInterface interface {
+	StatementTimeoutSQL(timeout time.Duration) (set string, reset string)
+}
+
+// InlineLiteralInterface dialects implement it to render a value as a SQL literal with the dialect's quoting
+type InlineLiteralInterface interface {
+	InlineLiteralOf(value interface{}) string
+}
+
+// inlineDialector binds vars as literals, used to render DDL without placeholders
+type inlineDialector struct {
+	gorm.Dialector
+	m Migrator
+}
+
+func (dialector inlineDialector) BindVarTo(writer clause.Writer, stmt *gorm.Statement, v interface{}) {
+	writer.WriteString(dialector.m.inlineLiteralsOf().InlineLiteralOf(v))
+}
+
+// inlineDDL render sql with identifiers quoted and values inlined as literals
+func (m Migrator) inlineDDL(sql string, values ...interface{}) string {
+	tx := m.DB.Session(&gorm.Session{Context: m.DB.Statement.Context})
+	tx.Dialector = inlineDialector{Dialector: m.Dialector, m: m}
+	tx.Statement.SQL = strings.Builder{}
+	tx.Statement.Vars = nil
+	clause.Expr{SQL: sql, Vars: values}.Build(tx.Statement)
+	return tx.Statement.SQL.String()
+}
+
+// InlineLiteralOf render value as a standard SQL literal, dialects override it for their escaping rules, e.g: backslashes (MySQL)
+func (m Migrator) InlineLiteralOf(value interface{}) string {
+	return m.inlineLiteralOf(value, m.InlineLiteralOf)
+}
+
+// inlineLiteralsOf literal rendering of the dialect migrator, migrators not embedding Migrator use m
+func (m Migrator) inlineLiteralsOf() InlineLiteralInterface {
+	if inliner, ok := m.migratorOf(m.DB).(InlineLiteralInterface); ok {
+		return inliner
+	}
+	return m
+}
+
+// inlineLiteralOf render value as a standard SQL literal, values converted to other types are rendered with literalOf
+func (m Migrator) inlineLiteralOf(value interface{}, literalOf func(interface{}) string) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case driver.Valuer:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return "NULL"
+		}
+		dv, err := v.Value()
+		if err != nil {
+			m.DB.AddError(err)
+			return "NULL"
+		}
+		return literalOf(dv)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		return literalOf(v.Format("2006-01-02 15:04:05.999999"))
+	case []byte:
+		return fmt.Sprintf("X'%x'", v)
+	case string:
+		return "'" + strings.Replace(v, "'", "''", -1) + "'"
+	default:
+		switch rv := reflect.ValueOf(value); rv.Kind() {
+		case reflect.Ptr:
+			if rv.IsNil() {
+				return "NULL"
+			}
+			return literalOf(rv.Elem().Interface())
+		case reflect.Bool:
+			return literalOf(rv.Bool())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return literalOf(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return literalOf(rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			return literalOf(rv.Float())
+		case reflect.String:
+			return literalOf(rv.String())
+		}
+		return literalOf(fmt.Sprint(value))
+	}
+}
+
+// execDDL execute DDL statement with MigrateStatementTimeout and MigrateLockTimeout
+func (m Migrator) execDDL(sql string, values ...interface{}) error {
+	return m.execDDLWith(nil, sql, values...)
+}
+
+// execDDLWith execute DDL statement like execDDL, with transaction settings set before it
+func (m Migrator) execDDLWith(sets []string, sql string, values ...interface{}) error {
+	if change, ok := m.onlineSchemaChangeOf(sql, values...); ok {
+		return m.OnlineSchemaChangeHook(change)
+	}
+
+	if m.InlineDDL {
+		sql, values = "?", []interface{}{clause.Expr{SQL: m.inlineDDL(sql, values...)}}
+	}
+
+	var (
+		ctx    = m.DB.Statement.Context
+		resets []string
+	)
+	sets = append([]string{}, sets...)
+
+	if m.MigrateStatementTimeout > 0 {
+		var (
+			cancel     context.CancelFunc
+			set, reset string
+		)
+		ctx, cancel = context.WithTimeout(ctx, m.MigrateStatementTimeout)
+		defer cancel()
+
+		// max_execution_time of mysql only limits SELECT statements, others are limited by the context deadline
+		if timeouter, ok := m.migratorOf(m.DB).(StatementTimeoutInterface); ok {
+			set, reset = timeouter.StatementTimeoutSQL(m.MigrateStatementTimeout)
+		}
+
+		if set != "" {
+			sets = append(sets, set)
+		}
+
+		if reset != "" {
+			resets = append(resets, reset)
+		}
+	}
+
+	if m.MigrateLockTimeout > 0 {
+		var set, reset string
+		if timeouter, ok := m.migratorOf(m.DB).(LockTimeoutInterface); ok {
+			set, reset = timeouter.LockTimeoutSQL(m.MigrateLockTimeout)
+		}
+
+		if set != "" {
+			sets = append(sets, set)
+		}
+
+		if reset != "" {
+			resets = append(resets, reset)
+		}
+	}
+
+	if len(sets) == 0 {
+		return m.DB.Session(&gorm.Session{Context: ctx}).Exec(sql, values...).Error
+	}
+
+	// settings are local to the transaction, e.g: SET LOCAL (Postgres), dialects without them reset the session, e.g: lock_wait_timeout (MySQL)
+	// the transaction isn't bound to the deadline, so the session is reset on its connection after the statement is cancelled
+	return m.DB.Transaction(func(tx *gorm.DB) (err error) {
+		defer func() {
+			for _, reset := range resets {
+				if resetErr := tx.Exec(reset).Error; err == nil {
+					err = resetErr
+				}
+			}
+		}()
+
+		for _, set := range sets {
+			if err := tx.Exec(set).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Session(&gorm.Session{Context: ctx}).Exec(sql, values...).Error
+	})
+}
+
+// OnlineSchemaChange ALTER TABLE statement delegated to Config.OnlineSchemaChangeHook
+type OnlineSchemaChange struct {
+	Database string
+	Table    string
+	Alter    string // alter spec with values inlined, e.g: ADD `age` bigint
+	SQL      string // the whole statement, e.g: ALTER TABLE `users` ADD `age` bigint
+}
+
+// OnlineSchemaChangeInterface dialects implement it to split ALTER TABLE statements delegated to OnlineSchemaChangeHook
+type OnlineSchemaChangeInterface interface {
+	OnlineSchemaChangeOf(sql string, values ...interface{}) (change OnlineSchemaChange, ok bool)
+}
+
+// onlineSchemaChangeOf ALTER TABLE statement to delegate to OnlineSchemaChangeHook
+func (m Migrator) onlineSchemaChangeOf(sql string, values ...interface{}) (change OnlineSchemaChange, ok bool) {
+	if m.OnlineSchemaChangeHook == nil {
+		return
+	}
+
+	if _, planning := m.DB.Statement.ConnPool.(*planConnPool); planning {
+		return
+	}
+
+	if changer, isChanger := m.migratorOf(m.DB).(OnlineSchemaChangeInterface); isChanger {
+		return changer.OnlineSchemaChangeOf(sql, values...)
+	}
+	return
+}
+
+// LockTimeoutInterface dialects implement it to limit the time DDL statements wait for locks
+type LockTimeoutInterface interface {
+	LockTimeoutSQL(timeout time.Duration) (set string, reset string)
+}
+
+// ReflectionQueriesInterface dialects could override reflection queries of HasTable, HasColumn, HasConstraint and HasIndex
+type ReflectionQueriesInterface interface {
+	QueryForTableExists(stmt *gorm.Statement) (sql string, values []interface{})
+	QueryForColumnExists(stmt *gorm.Statement, name string) (sql string, values []interface{})
+	QueryForConstraintExists(stmt *gorm.Statement, name string) (sql string, values []interface{})
+	QueryForIndexExists(stmt *gorm.Statement, name string) (sql string, values []interface{})
+}
+
+// reflectionQueries reflection queries overridden by the dialect migrator, migrators not embedding Migrator use the default ones
+func (m Migrator) reflectionQueries() ReflectionQueriesInterface {
+	if queries, ok := m.migratorOf(m.DB).(ReflectionQueriesInterface); ok {
+		return queries
+	}
+	return m
+}
+
+// IdentifierFoldingInterface dialects could fold unquoted identifiers the way they store them, e.g: upper case for oracle
+type IdentifierFoldingInterface interface {
+	FoldIdentifier(name string) string
+}
+
+// schemaContextKey context key of the schema set by WithSchema
+type schemaContextKey struct{}
+
+// WithSchema returns migrator scoping tables to the schema, e.g: WithSchema("tenant_x").AutoMigrate(&User{})
+func (m Migrator) WithSchema(name string) gorm.Migrator {
+	m.DB = m.DB.Session(&gorm.Session{Context: context.WithValue(m.DB.Statement.Context, schemaContextKey{}, name)})
+	return m
+}
+
+// schemaName returns the schema set by WithSchema
+func (m Migrator) schemaName() string {
+	name, _ := m.DB.Statement.Context.Value(schemaContextKey{}).(string)
+	return name
+}
+
+// CurrentTable returns table of the statement, qualified with the schema set by WithSchema
+func (m Migrator) CurrentTable(stmt *gorm.Statement) interface{} {
+	return m.qualifiedTable(stmt.Table)
+}
+
+func (m Migrator) qualifiedTable(name string) clause.Table {
+	if schema := m.schemaName(); schema != "" {
+		return clause.Table{Name: m.DB.Statement.Quote(schema) + "." + m.DB.Statement.Quote(name), Raw: true}
+	}
+	return clause.Table{Name: name}
+}
+
+// qualifiedTableName unquoted table name qualified with the schema, e.g: OBJECT_ID('tenant_x.users') (SQL Server)
+func (m Migrator) qualifiedTableName(name string) string {
+	if schema := m.schemaName(); schema != "" {
+		return schema + "." + name
+	}
+	return name
+}
+
+// InformationSchemaInterface dialects implement it if table_schema of information_schema holds schemas
+type InformationSchemaInterface interface {
+	InformationSchemaOf() interface{}
+}
+
+// informationSchemaOf table_schema filtering information_schema queries, defaults to currentDatabase
+func (m Migrator) informationSchemaOf() interface{} {
+	if informationSchema, ok := m.migratorOf(m.DB).(InformationSchemaInterface); ok {
+		return informationSchema.InformationSchemaOf()
+	}
+	return m.currentDatabase()
+}
+
+// currentDatabase schema filtering reflection queries of information_schema, defaults to CurrentDatabase
+func (m Migrator) currentDatabase() string {
+	if schema := m.schemaName(); schema != "" {
+		return schema
+	}
+	return m.migratorOf(m.DB).CurrentDatabase()
+}
+
+// identifierCandidates returns names to match in reflection queries, objects might be created with quoted name or folded unquoted name
+func (m Migrator) identifierCandidates(name string) []string {
+	folded := strings.ToLower(name)
+	if folder, ok := m.Dialector.(IdentifierFoldingInterface); ok {
+		folded = folder.FoldIdentifier(name)
+	}
+
+	if folded == name {
+		return []string{name}
+	}
+	return []string{name, folded}
+}
+
 func (m Migrator) DataTypeOf(field *schema.Field) string {
+	dataType := m.dataTypeOf(field)
+	if m.DataTypeHook != nil {
+		dataType = m.DataTypeHook(field, dataType)
+	}
+
+	// binary columns have no collation
+	if field.DataType == schema.Bytes {
+		dataType = binaryCollateRegexp.ReplaceAllString(dataType, "")
+	} else if field.CollateProvider != "" {
+		dataType = collationRegexp.ReplaceAllStringFunc(dataType, func(collate string) string {
+			matches := collationRegexp.FindStringSubmatch(collate)
+			return matches[1] + m.collationOf(matches[2], field.CollateProvider)
+		})
+	}
+	return dataType
+}
+
+var (
+	binaryCollateRegexp = regexp.MustCompile(`(?i)\s+COLLATE\s+\S+`)
+	collationRegexp     = regexp.MustCompile(`(?i)(\bCOLLATE\s+)("[^"]*"|\S+)`)
+)
+
+// CollationInterface dialects implement it to render collations of providers, e.g: "de-DE-x-icu" (Postgres)
+type CollationInterface interface {
+	CollationOf(collate, provider string) string
+}
+
+// CollationOf render collation of the provider, collations are taken as they are unless dialects override it
+func (m Migrator) CollationOf(collate, provider string) string {
+	return collate
+}
+
+// collationOf collation of the provider rendered by the dialect migrator, collations without providers are taken as they are
+func (m Migrator) collationOf(collate, provider string) string {
+	if collator, ok := m.migratorOf(m.DB).(CollationInterface); ok && provider != "" {
+		return collator.CollationOf(collate, provider)
+	}
+	return collate
+}
+
+func (m Migrator) dataTypeOf(field *schema.Field) string {
 	if field.DBDataType != "" {
 		return field.DBDataType
 	}
 
+	if len(field.EnumValues) > 0 && !field.EnumCheck && m.enumsOf(m.migratorOf(m.DB)) != nil {
+		// types are created in the schema of WithSchema too
+		if m.schemaName() != "" {
+			return m.qualifiedTable(m.enumsOf(m.migratorOf(m.DB)).EnumTypeOf(field)).Name
+		}
+		return m.enumsOf(m.migratorOf(m.DB)).EnumTypeOf(field)
+	}
+
 	fieldValue := reflect.New(field.IndirectFieldType)
 	if dataTyper, ok := fieldValue.Interface().(GormDataTypeInterface); ok {
 		if dataType := dataTyper.GormDBDataType(m.DB, field); dataType != "" {
@@ -55,15 +556,28 @@ func (m Migrator) DataTypeOf(field *schema.Field) string {
 		}
 	}
 
-	return m.Dialector.DataTypeOf(field)
+	if field.DataType == schema.Bytes {
+		if typer, ok := m.migratorOf(m.DB).(BytesDataTypeInterface); ok {
+			if dataType := typer.BytesDataTypeOf(field); dataType != "" {
+				return dataType
+			}
+		}
+	}
+
+	dataType := m.Dialector.DataTypeOf(field)
+	if field.ArrayDimensions > 0 && dataType != "" && !strings.HasSuffix(dataType, "[]") {
+		dataType += strings.Repeat("[]", field.ArrayDimensions)
+	}
+	return dataType
 }
 
-func (m Migrator) FullDataTypeOf(field *schema.Field) (expr clause.Expr) {
-	expr.SQL = m.DataTypeOf(field)
+// BytesDataTypeInterface dialects implement it to pick variants of binary types by size, e.g: mediumblob (MySQL)
+type BytesDataTypeInterface interface {
+	BytesDataTypeOf(field *schema.Field) string
+}
 
-	if field.AutoIncrement {
-		expr.SQL += " AUTO_INCREMENT"
-	}
+func (m Migrator) FullDataTypeOf(field *schema.Field) (expr clause.Expr) {
+	expr.SQL = m.columnTypeOf(field)
 
 	if field.NotNull {
 		expr.SQL += " NOT NULL"
@@ -73,458 +587,3875 @@ func (m Migrator) FullDataTypeOf(field *schema.Field) (expr clause.Expr) {
 		expr.SQL += " UNIQUE"
 	}
 
-	if field.HasDefaultValue && field.DefaultValue != "" {
-		if field.DataType == schema.String {
-			defaultStmt := &gorm.Statement{Vars: []interface{}{field.DefaultValue}}
-			m.Dialector.BindVarTo(defaultStmt, defaultStmt, field.DefaultValue)
-			expr.SQL += " DEFAULT " + m.Dialector.Explain(defaultStmt.SQL.String(), field.DefaultValue)
-		} else {
-			expr.SQL += " DEFAULT " + field.DefaultValue
+	if defaultValue := m.defaultValueOf(field); defaultValue != "" && field.GeneratedExpression == "" {
+		expr.SQL += " " + defaultValue
+	}
+
+	if comment := m.commentOf(field); comment != "" {
+		if commenter := m.inlineCommentsOf(); commenter != nil {
+			expr.SQL += commenter.InlineCommentOf(comment)
 		}
 	}
 
 	return
 }
 
-// AutoMigrate
-func (m Migrator) AutoMigrate(values ...interface{}) error {
-	// TODO smart migrate data type
-	for _, value := range m.ReorderModels(values, true) {
-		tx := m.DB.Session(&gorm.Session{})
-		if !tx.Migrator().HasTable(value) {
-			if err := tx.Migrator().CreateTable(value); err != nil {
-				return err
-			}
-		} else {
-			if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
-				for _, field := range stmt.Schema.FieldsByDBName {
-					if !tx.Migrator().HasColumn(value, field.DBName) {
-						if err := tx.Migrator().AddColumn(value, field.DBName); err != nil {
-							return err
-						}
-					}
-				}
+// columnTypeOf data type of field with its auto increment or generated expression, without constraints, defaults and comments
+func (m Migrator) columnTypeOf(field *schema.Field) string {
+	if field.GeneratedExpression != "" {
+		return m.generatedColumnsOf(m.migratorOf(m.DB)).GeneratedColumnOf(field)
+	}
 
-				for _, rel := range stmt.Schema.Relationships.Relations {
-					if constraint := rel.ParseConstraint(); constraint != nil {
-						if !tx.Migrator().HasConstraint(value, constraint.Name) {
-							if err := tx.Migrator().CreateConstraint(value, constraint.Name); err != nil {
-								return err
-							}
-						}
-					}
+	dataType := m.DataTypeOf(field)
+	if field.AutoIncrement {
+		if incrementer, ok := m.migratorOf(m.DB).(AutoIncrementInterface); ok {
+			return incrementer.AutoIncrementOf(field, dataType)
+		}
+		return m.autoIncrementOf(field, dataType)
+	}
+	return dataType
+}
 
-					for _, chk := range stmt.Schema.ParseCheckConstraints() {
-						if !tx.Migrator().HasConstraint(value, chk.Name) {
-							if err := tx.Migrator().CreateConstraint(value, chk.Name); err != nil {
-								return err
-							}
-						}
-					}
+// AutoIncrementInterface dialects implement it to render auto increment columns from their data types
+type AutoIncrementInterface interface {
+	AutoIncrementOf(field *schema.Field, dataType string) string
+}
 
-					// create join table
-					if rel.JoinTable != nil {
-						joinValue := reflect.New(rel.JoinTable.ModelType).Interface()
-						if !tx.Migrator().HasTable(rel.JoinTable.Table) {
-							defer tx.Table(rel.JoinTable.Table).Migrator().CreateTable(joinValue)
-						} else {
-							defer tx.Table(rel.JoinTable.Table).Migrator().AutoMigrate(joinValue)
-						}
-					}
-				}
-				return nil
-			}); err != nil {
-				return err
-			}
-		}
+var autoIncrementRegexp = regexp.MustCompile(`(?i)auto_?increment|serial|identity`)
+
+// autoIncrementOf render auto increment column as dataType AUTO_INCREMENT, dialects using other syntax opt out with AutoIncrementInterface
+func (m Migrator) autoIncrementOf(field *schema.Field, dataType string) string {
+	// data types of dialects might be auto increment already, e.g: bigserial
+	if autoIncrementRegexp.MatchString(dataType) {
+		return dataType
 	}
+	return dataType + " AUTO_INCREMENT"
+}
 
-	return nil
+type GeneratedColumnInterface interface {
+	GeneratedColumnOf(*schema.Field) string
 }
 
-func (m Migrator) CreateTable(values ...interface{}) error {
-	for _, value := range m.ReorderModels(values, false) {
-		tx := m.DB.Session(&gorm.Session{})
-		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
-			var (
-				createTableSQL          = "CREATE TABLE ? ("
-				values                  = []interface{}{clause.Table{Name: stmt.Table}}
-				hasPrimaryKeyInDataType bool
-			)
+// generatedColumnsOf generated column syntax of the dialect migrator, migrators not embedding Migrator use m
+func (m Migrator) generatedColumnsOf(migrator gorm.Migrator) GeneratedColumnInterface {
+	if gen, ok := migrator.(GeneratedColumnInterface); ok {
+		return gen
+	}
+	return m
+}
 
-			for _, dbName := range stmt.Schema.DBNames {
-				field := stmt.Schema.FieldsByDBName[dbName]
-				createTableSQL += fmt.Sprintf("? ?")
-				hasPrimaryKeyInDataType = hasPrimaryKeyInDataType || strings.Contains(strings.ToUpper(field.DBDataType), "PRIMARY KEY")
-				values = append(values, clause.Column{Name: dbName}, m.FullDataTypeOf(field))
-				createTableSQL += ","
-			}
+// GeneratedColumnOf build generated column definition, e.g: int GENERATED ALWAYS AS (price * quantity) STORED
+func (m Migrator) GeneratedColumnOf(field *schema.Field) string {
+	sql := m.DataTypeOf(field) + " GENERATED ALWAYS AS (" + field.GeneratedExpression + ")"
+	if field.GeneratedStored {
+		sql += " STORED"
+	}
+	return sql
+}
 
-			if !hasPrimaryKeyInDataType && len(stmt.Schema.PrimaryFields) > 0 {
-				createTableSQL += "PRIMARY KEY ?,"
-				primaryKeys := []interface{}{}
-				for _, field := range stmt.Schema.PrimaryFields {
-					primaryKeys = append(primaryKeys, clause.Column{Name: field.DBName})
-				}
+type DefaultValueOfInterface interface {
+	DefaultValueOf(*schema.Field) string
+}
 
-				values = append(values, primaryKeys)
-			}
+// hasDefaultValue whether the field declares a column default, empty string defaults are declared with quotes
+func hasDefaultValue(field *schema.Field) bool {
+	return field.HasDefaultValue && (field.DefaultValue != "" || (field.DataType == schema.String && field.TagSettings["DEFAULT"] != ""))
+}
 
-			for _, idx := range stmt.Schema.ParseIndexes() {
-				if m.CreateIndexAfterCreateTable {
-					defer tx.Migrator().CreateIndex(value, idx.Name)
-				} else {
-					createTableSQL += "INDEX ? ?,"
-					values = append(values, clause.Expr{SQL: idx.Name}, tx.Migrator().(BuildIndexOptionsInterface).BuildIndexOptions(idx.Fields, stmt))
-				}
-			}
+// DefaultValueOf build column default clause
+func (m Migrator) DefaultValueOf(field *schema.Field) string {
+	if hasDefaultValue(field) {
+		return "DEFAULT " + m.defaultExprOf(field)
+	}
+	return ""
+}
 
-			for _, rel := range stmt.Schema.Relationships.Relations {
-				if constraint := rel.ParseConstraint(); constraint != nil {
-					sql, vars := buildConstraint(constraint)
-					createTableSQL += sql + ","
-					values = append(values, vars...)
-				}
+// defaultExprOf default value of the field as SQL expression, used to backfill NULLs
+func (m Migrator) defaultExprOf(field *schema.Field) string {
+	if field.DataType == schema.String {
+		return m.quoteString(field.DefaultValue)
+	}
+	return field.DefaultValue
+}
 
-				// create join table
-				if rel.JoinTable != nil {
-					joinValue := reflect.New(rel.JoinTable.ModelType).Interface()
-					if !tx.Migrator().HasTable(rel.JoinTable.Table) {
-						defer tx.Table(rel.JoinTable.Table).Migrator().CreateTable(joinValue)
-					}
-				}
-			}
+// defaultValueOf column default clause of the field, built by the dialect migrator if it overrides DefaultValueOf
+func (m Migrator) defaultValueOf(field *schema.Field) string {
+	if valuer, ok := m.migratorOf(m.DB).(DefaultValueOfInterface); ok {
+		return valuer.DefaultValueOf(field)
+	}
+	return m.DefaultValueOf(field)
+}
+
+// quoteString quote string as SQL literal for statements don't accept bind vars
+func (m Migrator) quoteString(str string) string {
+	return m.inlineLiteralsOf().InlineLiteralOf(str)
+}
+
+// AutoMigrate
+func (m Migrator) AutoMigrate(values ...interface{}) error {
+	return m.autoMigrate(nil, values...)
+}
+
+// AutoMigrateWithResult run auto migration and returns operations performed for each table
+func (m Migrator) AutoMigrateWithResult(values ...interface{}) (result AutoMigrateResult, err error) {
+	err = m.autoMigrate(&result, values...)
+	return
+}
+
+// AutoMigrateContext run auto migration with ctx, stops before the next model once ctx is done
+func (m Migrator) AutoMigrateContext(ctx context.Context, values ...interface{}) error {
+	m.DB = m.DB.Session(&gorm.Session{Context: ctx})
+	return m.autoMigrate(nil, values...)
+}
+
+// MigrateProgress model migrated by AutoMigrate, reported to Config.ProgressHook, e.g: 2/5 users
+type MigrateProgress struct {
+	Table  string
+	Index  int // position of the model starting from 1
+	Total  int
+	Result TableMigrateResult // operations performed on the table
+	Error  error
+}
+
+// reportProgress report the model migrated to ProgressHook, its operations are recorded in result since tables
+func (m Migrator) reportProgress(result *AutoMigrateResult, tables int, idx, total int, value interface{}, err error) {
+	progress := MigrateProgress{Index: idx + 1, Total: total, Error: err}
+	if len(result.Tables) > tables {
+		progress.Result = result.Tables[tables]
+	}
+
+	if progress.Table = progress.Result.Table; progress.Table == "" {
+		progress.Table, _ = m.tableNameOf(value)
+	}
+	m.ProgressHook(progress)
+}
+
+func (m Migrator) autoMigrate(result *AutoMigrateResult, values ...interface{}) error {
+	var (
+		savePoints   = m.SavePointPerModel && m.inTransaction()
+		models       = m.ReorderModels(values, true)
+		failedTables []string
+		firstErr     error
+	)
+
+	// operations of models are recorded to be reported
+	if result == nil && m.ProgressHook != nil {
+		result = &AutoMigrateResult{}
+	}
+
+	for idx, value := range models {
+		if err := m.DB.Statement.Context.Err(); err != nil {
+			return err
+		}
+
+		tables := 0
+		if result != nil {
+			tables = len(result.Tables)
+		}
+
+		var set, rollback, release string
+		if savePoints {
+			set, rollback, release = m.savePointSQL(fmt.Sprintf("gorm_migrate_%d", idx))
+		}
+
+		// dialects without savepoints leave set blank, e.g: MySQL, whose DDL statements commit implicitly
+		if set == "" {
+			err := m.autoMigrateModel(result, value)
+			if m.ProgressHook != nil {
+				m.reportProgress(result, tables, idx, len(models), value, err)
+			}
+
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := m.DB.Exec(set).Error; err != nil {
+			return err
+		}
+
+		err := m.autoMigrateModel(result, value)
+		if err != nil {
+			if rollbackErr := m.DB.Exec(rollback).Error; rollbackErr != nil {
+				return rollbackErr
+			}
+
+			table, _ := m.tableNameOf(value)
+			if result != nil && len(result.Tables) > tables {
+				// changes of the model are rolled back, so are its operations
+				result.Tables = result.Tables[:tables+1]
+				result.Tables[tables].Operations, result.Tables[tables].SQL, result.Tables[tables].Error = nil, nil, err
+			}
+
+			if failedTables = append(failedTables, table); firstErr == nil {
+				firstErr = err
+			}
+		} else if release != "" {
+			if err := m.DB.Exec(release).Error; err != nil {
+				return err
+			}
+		}
+
+		if m.ProgressHook != nil {
+			m.reportProgress(result, tables, idx, len(models), value, err)
+		}
+	}
+
+	if len(failedTables) > 0 {
+		return fmt.Errorf("failed to migrate tables %v, changes of other tables are kept: %w", strings.Join(failedTables, ", "), firstErr)
+	}
+	return nil
+}
+
+// inTransaction whether the migrator runs in a transaction, statements of PlanAutoMigrate are not executed
+func (m Migrator) inTransaction() bool {
+	if _, ok := m.DB.Statement.ConnPool.(*planConnPool); ok {
+		return false
+	}
+	_, ok := m.DB.Statement.ConnPool.(gorm.TxCommitter)
+	return ok
+}
+
+// SavePointInterface dialects implement it to set, roll back to and release savepoints, blank if unsupported
+type SavePointInterface interface {
+	SavePointSQL(name string) (set, rollback, release string)
+}
+
+// savePointSQL statements to set, roll back to and release savepoint, defaults to SAVEPOINT
+func (m Migrator) savePointSQL(name string) (set, rollback, release string) {
+	if savePointer, ok := m.migratorOf(m.DB).(SavePointInterface); ok {
+		return savePointer.SavePointSQL(name)
+	}
+	return "SAVEPOINT " + name, "ROLLBACK TO SAVEPOINT " + name, "RELEASE SAVEPOINT " + name
+}
+
+// TableRebuildInterface dialects implement it if altering columns recreates the table, which drops its triggers, e.g: SQLite
+type TableRebuildInterface interface {
+	AlterColumnRebuildsTable() bool
+}
+
+// IndexChangedInterface dialects implement it to compare reflected indexes with the model, changed indexes are recreated
+type IndexChangedInterface interface {
+	IndexChanged(live IndexInfo, idx schema.Index) bool
+}
+
+// indexComparerOf implementer of IndexChangedInterface, nil if none, only classes of indexes are compared then
+func (m Migrator) indexComparerOf() IndexChangedInterface {
+	if comparer, ok := m.migratorOf(m.DB).(IndexChangedInterface); ok {
+		return comparer
+	}
+	return nil
+}
+
+// IndexCommentInterface dialects implement it to update comments of indexes in place, e.g: COMMENT ON INDEX (Postgres)
+type IndexCommentInterface interface {
+	SetIndexComment(value interface{}, name, comment string) error
+}
+
+// indexCommenterOf implementer of IndexCommentInterface, nil if none, changed comments of indexes are logged then
+func (m Migrator) indexCommenterOf() IndexCommentInterface {
+	if commenter, ok := m.migratorOf(m.DB).(IndexCommentInterface); ok {
+		return commenter
+	}
+	return nil
+}
+
+// autoMigrateModel migrate table of the model, join tables of its many2many relations are migrated after it
+func (m Migrator) autoMigrateModel(result *AutoMigrateResult, value interface{}) error {
+	var (
+		tx       = m.DB.Session(&gorm.Session{})
+		resultID = -1
+		table    string
+		identity string
+	)
+
+	if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		table = stmt.Table
+		identity = m.replicaIdentityOf(value, stmt)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if result != nil {
+		resultID = len(result.Tables)
+		result.Tables = append(result.Tables, TableMigrateResult{Table: table})
+	}
+
+	for _, sql := range m.PreMigrate[table] {
+		if err := tx.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+
+	record := func(typ, name string) {
+		if resultID != -1 {
+			result.Tables[resultID].Operations = append(result.Tables[resultID].Operations, MigrateOperation{Type: typ, Name: name, Lock: m.LockImpactOf(typ)})
+		}
+	}
+
+	// apply record succeeded operation, with SkipUnsupported, operations not implemented by the dialect are logged and recorded as skip_<type>
+	apply := func(typ, name string, err error) error {
+		if err == nil {
+			record(typ, name)
+		} else if m.SkipUnsupported && errors.Is(err, gorm.ErrNotImplemented) {
+			m.DB.Logger.Warn(m.DB.Statement.Context, "skip %v %v, it is not supported by %v", typ, name, m.Dialector.Name())
+			record("skip_"+typ, name)
+		} else {
+			return err
+		}
+		return nil
+	}
+
+	if !m.migratorOf(tx).HasTable(value) {
+		// create table with current config, e.g: InlineDDL, SoftDeleteUniqueIndexes
+		if err := m.CreateTable(value); err != nil {
+			return err
+		}
+		record("create_table", "")
+
+		if m.TableOwner != "" {
+			// the table is created already, dialects without table owners skip it instead of leaving the migration half done
+			if err := m.tableOwnersOf(m.migratorOf(tx)).SetTableOwner(value, m.TableOwner); errors.Is(err, gorm.ErrNotImplemented) {
+				m.DB.Logger.Warn(m.DB.Statement.Context, "skip set_table_owner %v, it is not supported by %v", m.TableOwner, m.Dialector.Name())
+				record("skip_set_table_owner", m.TableOwner)
+			} else if err != nil {
+				return err
+			} else {
+				record("set_table_owner", m.TableOwner)
+			}
+		}
+
+		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			for _, dbName := range stmt.Schema.DBNames {
+				if field := stmt.Schema.FieldsByDBName[dbName]; field.Storage != "" {
+					if err := m.columnStoragesOf(m.migratorOf(tx)).SetColumnStorage(value, dbName, field.Storage); errors.Is(err, gorm.ErrNotImplemented) {
+						m.DB.Logger.Warn(m.DB.Statement.Context, "column storage of %v.%v is not supported by %v", stmt.Table, dbName, m.Dialector.Name())
+					} else if err := apply("set_column_storage", dbName, err); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	} else {
+		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			var (
+				liveConstraints      = map[string]ConstraintInfo{}
+				reflectedConstraints []ConstraintInfo
+			)
+
+			if m.RecreateChangedConstraintsWhenAutoMigrate || m.DropObsoleteForeignKeysWhenAutoMigrate {
+				var err error
+				if reflectedConstraints, err = m.constraintsOf(m.migratorOf(tx)).GetConstraints(value); errors.Is(err, gorm.ErrNotImplemented) {
+					m.DB.Logger.Warn(m.DB.Statement.Context, "constraints of %v are not reconciled, reflecting them is not supported by %v", stmt.Table, m.Dialector.Name())
+				} else if err != nil {
+					return err
+				}
+			}
+
+			if m.RecreateChangedConstraintsWhenAutoMigrate {
+				for _, constraint := range reflectedConstraints {
+					liveConstraints[constraint.Name] = constraint
+				}
+			}
+
+			columnTypes, err := m.migratorOf(tx).ColumnTypes(value)
+			if err != nil {
+				return err
+			}
+
+			nullabilityChanges := map[string]bool{}
+			for _, dbName := range stmt.Schema.DBNames {
+				field := stmt.Schema.FieldsByDBName[dbName]
+				if enums := m.enumsOf(m.migratorOf(tx)); enums != nil && len(field.EnumValues) > 0 {
+					if err := enums.MigrateEnum(value, field); err != nil {
+						return err
+					}
+				}
+
+				if !m.migratorOf(tx).HasColumn(value, field.DBName) {
+					if err := m.migratorOf(tx).AddColumn(value, field.DBName); err != nil {
+						return err
+					}
+					record("add_column", field.DBName)
+					continue
+				}
+
+				for _, columnType := range columnTypes {
+					if columnType.Name() == field.DBName {
+						var rebuildTable bool
+						if m.MigrateColumnTypesWhenAutoMigrate && m.columnTypeChanged(field, columnType) {
+							change := ColumnChange{
+								Table: stmt.Table, Column: field.DBName, From: columnType.DatabaseTypeName(), To: m.DataTypeOf(field),
+								Risk: m.columnChangeRisk(field, columnType),
+							}
+
+							if m.ColumnChangeHook != nil {
+								if err := m.ColumnChangeHook(change); err != nil {
+									return err
+								}
+							}
+
+							if change.Risk == ColumnChangeDestructive && !m.AllowDestructiveColumnChanges {
+								// no data to lose in empty tables
+								if empty, err := m.withDB(tx).IsTableEmpty(value); err != nil {
+									return err
+								} else if !empty {
+									return fmt.Errorf("changing column %v.%v from %v to %v might lose data, set AllowDestructiveColumnChanges to allow it", stmt.Table, field.DBName, change.From, change.To)
+								}
+							}
+							record("alter_column", field.DBName)
+
+							// altering columns might recreate the table, which drops its triggers
+							if rebuilder, ok := m.migratorOf(m.DB).(TableRebuildInterface); ok {
+								rebuildTable = rebuilder.AlterColumnRebuildsTable()
+							}
+						}
+
+						// columns could change types and nullability at once
+						if m.MigrateNullabilityWhenAutoMigrate && !field.PrimaryKey {
+							if nullable, ok := columnType.Nullable(); ok && nullable == field.NotNull {
+								nullabilityChanges[field.DBName] = true
+							}
+						}
+
+						migrateColumn := func() error {
+							return m.withDB(tx).MigrateColumn(value, field, columnType)
+						}
+
+						if !m.MigrateColumnTypesWhenAutoMigrate {
+							break
+						} else if rebuildTable {
+							if err := m.preserveTriggers(value, migrateColumn); err != nil {
+								return err
+							}
+						} else if err := migrateColumn(); err != nil {
+							return err
+						}
+						break
+					}
+				}
+
+				if m.MigrateDefaultValuesWhenAutoMigrate && field.HasDefaultValue && field.DefaultValue != "" && field.GeneratedExpression == "" {
+					liveDefault, err := m.columnDefaultsOf(m.migratorOf(tx)).ColumnDefaultOf(value, field.DBName)
+					if errors.Is(err, gorm.ErrNotImplemented) {
+						m.DB.Logger.Warn(m.DB.Statement.Context, "column default of %v.%v is not supported by %v", stmt.Table, field.DBName, m.Dialector.Name())
+					} else if err != nil {
+						return err
+					} else if normalizeDefaultValue(field, liveDefault) != normalizeDefaultValue(field, field.DefaultValue) {
+						// columns becoming NOT NULL get defaults with nullability, see AlterColumnsNullability
+						if becomesNotNull := field.NotNull && nullabilityChanges[field.DBName]; !becomesNotNull {
+							if err := m.alterColumnDefault(tx, value, stmt, field); err != nil {
+								return err
+							}
+						}
+						record("alter_column_default", field.DBName)
+					}
+				}
+
+				if field.Storage != "" {
+					storage, err := m.columnStoragesOf(m.migratorOf(tx)).ColumnStorageOf(value, field.DBName)
+					if errors.Is(err, gorm.ErrNotImplemented) {
+						m.DB.Logger.Warn(m.DB.Statement.Context, "column storage of %v.%v is not supported by %v", stmt.Table, field.DBName, m.Dialector.Name())
+					} else if err != nil {
+						return err
+					} else if storage != field.Storage {
+						if err := apply("set_column_storage", field.DBName, m.columnStoragesOf(m.migratorOf(tx)).SetColumnStorage(value, field.DBName, field.Storage)); err != nil {
+							return err
+						}
+					}
+				}
+
+				if comment := m.commentOf(field); comment != "" && m.MigrateColumnCommentsWhenAutoMigrate {
+					liveComment, err := m.columnCommentsOf(m.migratorOf(tx)).ColumnCommentOf(value, field.DBName)
+					if errors.Is(err, gorm.ErrNotImplemented) {
+						m.DB.Logger.Warn(m.DB.Statement.Context, "column comment of %v.%v is not supported by %v", stmt.Table, field.DBName, m.Dialector.Name())
+					} else if err != nil {
+						return err
+					} else if merged := m.MergeColumnComment(liveComment, comment); merged != liveComment {
+						if err := apply("alter_column_comment", field.DBName, m.columnCommentsOf(m.migratorOf(tx)).SetColumnComment(value, field.DBName, merged)); err != nil {
+							return err
+						}
+					} else if !strings.HasPrefix(comment, ColumnCommentNamespace) && liveComment != comment {
+						m.DB.Logger.Warn(m.DB.Statement.Context, "comment of column %v.%v is changed outside of the model, use the %v prefix to manage part of the comment", stmt.Table, field.DBName, ColumnCommentNamespace)
+					}
+				}
+
+				if field.GeneratedExpression != "" && m.RecreateGeneratedColumnsWhenAutoMigrate {
+					if changed, err := m.generatedColumnChanged(value, field); errors.Is(err, gorm.ErrNotImplemented) {
+						m.DB.Logger.Warn(m.DB.Statement.Context, "generated column %v.%v is not reconciled, reflecting it is not supported by %v", stmt.Table, field.DBName, m.Dialector.Name())
+					} else if err != nil {
+						return err
+					} else if changed {
+						if err := m.recreateColumn(value, field); err != nil {
+							return err
+						}
+						record("recreate_column", field.DBName)
+					}
+				}
+			}
+
+			if m.DropUnusedColumnsWhenAutoMigrate {
+				var dropped []string
+				for _, name := range m.unusedColumns(stmt, columnTypes) {
+					if err := m.migratorOf(tx).DropColumn(value, name); err != nil {
+						return fmt.Errorf("failed to drop unused column %v.%v, dropped columns %v: %w", stmt.Table, name, dropped, err)
+					}
+					dropped = append(dropped, name)
+					record("drop_column", name)
+				}
+			}
+
+			if m.MigrateUniqueWhenAutoMigrate {
+				indexes, err := m.indexesOf(m.migratorOf(tx)).GetIndexes(value)
+				if errors.Is(err, gorm.ErrNotImplemented) {
+					m.DB.Logger.Warn(m.DB.Statement.Context, "unique columns of %v are not reconciled, reflecting indexes is not supported by %v", stmt.Table, m.Dialector.Name())
+				} else if err != nil {
+					return err
+				} else {
+					for _, dbName := range stmt.Schema.DBNames {
+						if _, changed := m.columnUniqueChanged(stmt, stmt.Schema.FieldsByDBName[dbName], indexes); changed {
+							if err := apply("alter_column_unique", dbName, m.withDB(tx).AlterColumnUnique(value, dbName)); err != nil {
+								return err
+							}
+						}
+					}
+				}
+			}
+
+			if len(nullabilityChanges) > 0 {
+				names := make([]string, 0, len(nullabilityChanges))
+				for name := range nullabilityChanges {
+					names = append(names, name)
+				}
+
+				sort.Strings(names)
+				if err := m.withDB(tx).AlterColumnsNullability(value, names...); err != nil {
+					return err
+				}
+
+				for _, name := range names {
+					record("alter_column_nullability", name)
+				}
+			}
+
+			if m.ReorderColumnsWhenAutoMigrate {
+				if err := m.withDB(tx).reorderColumns(value, stmt, record); errors.Is(err, gorm.ErrNotImplemented) {
+					m.DB.Logger.Warn(m.DB.Statement.Context, "reordering columns of %v is not supported by %v", stmt.Table, m.Dialector.Name())
+				} else if err != nil {
+					return err
+				}
+			}
+
+			for _, rel := range sortedRelations(stmt.Schema) {
+				if constraint := rel.ParseConstraint(); constraint != nil {
+					if !m.migratorOf(tx).HasConstraint(value, constraint.Name) {
+						if err := apply("create_constraint", constraint.Name, m.migratorOf(tx).CreateConstraint(value, constraint.Name)); err != nil {
+							return err
+						}
+					} else if live, ok := liveConstraints[constraint.Name]; ok {
+						if !equalConstraintAction(live.OnDelete, constraint.OnDelete) || !equalConstraintAction(live.OnUpdate, constraint.OnUpdate) {
+							err := m.migratorOf(tx).DropConstraint(value, constraint.Name)
+							if err == nil {
+								err = m.migratorOf(tx).CreateConstraint(value, constraint.Name)
+							}
+
+							if err := apply("recreate_constraint", constraint.Name, err); err != nil {
+								return err
+							}
+						}
+					}
+				}
+
+				// create join table
+				if rel.JoinTable != nil {
+					joinValue := reflect.New(rel.JoinTable.ModelType).Interface()
+					if !m.migratorOf(tx).HasTable(rel.JoinTable.Table) {
+						defer m.migratorOf(tx.Table(rel.JoinTable.Table)).CreateTable(joinValue)
+						if result != nil {
+							result.Tables = append(result.Tables, TableMigrateResult{
+								Table: rel.JoinTable.Table, Operations: []MigrateOperation{{Type: "create_table"}},
+							})
+						}
+					} else if result != nil {
+						joinMigrator := m
+						joinMigrator.DB = tx.Table(rel.JoinTable.Table)
+						// progress is reported for models passed to AutoMigrate only
+						joinMigrator.ProgressHook = nil
+						defer joinMigrator.autoMigrate(result, joinValue)
+					} else {
+						defer m.migratorOf(tx.Table(rel.JoinTable.Table)).AutoMigrate(joinValue)
+					}
+				}
+			}
+
+			if m.DropObsoleteForeignKeysWhenAutoMigrate {
+				for _, name := range m.obsoleteForeignKeys(stmt, reflectedConstraints) {
+					if err := apply("drop_constraint", name, m.migratorOf(tx).DropConstraint(value, name)); err != nil {
+						return err
+					}
+				}
+			}
+
+			for _, chk := range sortedChecks(m.parseCheckConstraints(stmt)) {
+				if liveName, ok := m.liveConstraintName(tx, value, chk.Name, chk.LegacyName); !ok {
+					err := m.validateCheckConstraint(stmt, chk)
+					if err == nil {
+						err = m.migratorOf(tx).CreateConstraint(value, chk.Name)
+					}
+
+					if errors.Is(err, gorm.ErrNotImplemented) && !m.SkipUnsupported {
+						m.warnConstraintNotReconciled(stmt, chk.Name)
+					} else if err := apply("create_constraint", chk.Name, err); err != nil {
+						return err
+					}
+				} else if live, ok := liveConstraints[liveName]; ok && live.Definition != "" && normalizeCheckConstraint(live.Definition) != normalizeCheckConstraint(chk.Constraint) {
+					if chk.Enum {
+						if added, removed := enumValuesChanged(live.Definition, chk.Field.EnumValues); len(removed) > 0 && !m.RemoveEnumValuesWhenAutoMigrate {
+							return fmt.Errorf("failed to migrate enum check %v, removing values %v requires RemoveEnumValuesWhenAutoMigrate", chk.Name, strings.Join(removed, ","))
+						} else if len(added) == 0 && len(removed) == 0 {
+							// same values rewritten by the database, e.g: mood = ANY (ARRAY[...]) (Postgres)
+							continue
+						}
+					}
+
+					// validate before dropping, so the table won't be left without the constraint
+					err := m.validateCheckConstraint(stmt, chk)
+					if err == nil {
+						err = m.migratorOf(tx).DropConstraint(value, liveName)
+					}
+
+					if err == nil {
+						err = m.migratorOf(tx).CreateConstraint(value, chk.Name)
+					}
+
+					if err := apply("recreate_constraint", chk.Name, err); err != nil {
+						return err
+					}
+				}
+			}
+
+			for _, unique := range sortedUniqueConstraints(stmt.Schema.ParseUniqueConstraints()) {
+				if _, ok := m.liveConstraintName(tx, value, unique.Name, unique.LegacyName); !ok {
+					if err := m.migratorOf(tx).CreateConstraint(value, unique.Name); errors.Is(err, gorm.ErrNotImplemented) && !m.SkipUnsupported {
+						m.warnConstraintNotReconciled(stmt, unique.Name)
+					} else if err := apply("create_constraint", unique.Name, err); err != nil {
+						return err
+					}
+				}
+			}
+			if m.PromoteUniqueIndexesWhenAutoMigrate && len(stmt.Schema.PrimaryFields) > 0 {
+				// tables without constraint reflection can't change primary keys in place either, e.g: SQLite
+				if live, _, err := m.uniqueIndexOfPrimaryKey(value, stmt, ""); errors.Is(err, gorm.ErrNotImplemented) {
+					m.DB.Logger.Warn(m.DB.Statement.Context, "promoting unique indexes of %v to primary key is not supported by %v", stmt.Table, m.Dialector.Name())
+				} else if err != nil {
+					return err
+				} else if live != nil {
+					if err := apply("promote_primary_key", live.Name, m.withDB(tx).PromoteToPrimaryKey(value, live.Name)); err != nil {
+						return err
+					}
+				}
+			}
+
+			var (
+				indexes        = sortedIndexes(m.parseIndexes(stmt))
+				liveIndexes    = map[string]IndexInfo{}
+				indexComparer  = m.indexComparerOf()
+				indexCommenter = m.indexCommenterOf()
+				needReflect    bool
+			)
+
+			for _, idx := range indexes {
+				// dialects comparing index definitions reflect every index, the default access method of indexes without type could be changed too
+				needReflect = needReflect || indexComparer != nil || idx.Comment != "" || strings.ToUpper(idx.Class) == "FULLTEXT"
+			}
+
+			if needReflect {
+				// without reflection, only missing indexes are created
+				reflectedIndexes, err := m.indexesOf(m.migratorOf(tx)).GetIndexes(value)
+				if err != nil && !errors.Is(err, gorm.ErrNotImplemented) {
+					return err
+				}
+
+				for _, idx := range reflectedIndexes {
+					liveIndexes[idx.Name] = idx
+				}
+			}
+
+			for _, idx := range indexes {
+				if idx.Manual {
+					continue
+				}
+
+				if !m.migratorOf(tx).HasIndex(value, idx.Name) {
+					if err := apply("create_index", idx.Name, m.createIndex(tx, stmt, value, idx)); err != nil {
+						return err
+					}
+					continue
+				}
+
+				live, ok := liveIndexes[idx.Name]
+				if !ok {
+					continue
+				}
+
+				if live.Class != strings.ToUpper(idx.Class) || (indexComparer != nil && indexComparer.IndexChanged(live, idx)) {
+					err := m.withDB(tx).DropIndexIfExists(value, idx.Name)
+					if err == nil {
+						err = m.createIndex(tx, stmt, value, idx)
+					}
+
+					if err := apply("recreate_index", idx.Name, err); err != nil {
+						return err
+					}
+					live.Comment = ""
+				}
+
+				if idx.Comment != "" && live.Comment != idx.Comment {
+					if indexCommenter != nil {
+						if err := indexCommenter.SetIndexComment(value, idx.Name, idx.Comment); err != nil {
+							return err
+						}
+						record("comment_index", idx.Name)
+					} else {
+						m.DB.Logger.Warn(m.DB.Statement.Context, "comment of index %v on %v changed, it requires recreating the index to update", idx.Name, stmt.Table)
+					}
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	// set after indexes are migrated, which USING INDEX requires
+	if identity != "" {
+		// dialects without replica identities fail to set it, see SkipUnsupported
+		live, err := m.replicaIdentitiesOf(m.migratorOf(tx)).ReplicaIdentityOf(value)
+		if err != nil && !errors.Is(err, gorm.ErrNotImplemented) {
+			return err
+		}
+
+		if normalized, err := normalizeReplicaIdentity(identity); err != nil {
+			return err
+		} else if live != normalized {
+			if err := apply("set_replica_identity", normalized, m.replicaIdentitiesOf(m.migratorOf(tx)).SetReplicaIdentity(value, normalized)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, sql := range m.PostMigrate[table] {
+		if err := tx.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+
+	if pool, ok := tx.Statement.ConnPool.(*planConnPool); ok && resultID != -1 {
+		result.Tables[resultID].SQL = pool.flush()
+	}
+	return nil
+}
+
+// obsoleteForeignKeys live foreign keys on columns of the model that no relationship declares
+func (m Migrator) obsoleteForeignKeys(stmt *gorm.Statement, constraints []ConstraintInfo) (names []string) {
+	declared := map[string]bool{}
+	for _, rel := range sortedRelations(stmt.Schema) {
+		if constraint := rel.ParseConstraint(); constraint != nil {
+			declared[constraint.Name] = true
+		}
+	}
+
+	for _, constraint := range constraints {
+		if constraint.Type != "FOREIGN KEY" || declared[constraint.Name] || len(constraint.Columns) == 0 {
+			continue
+		}
+
+		obsolete := true
+		for _, column := range constraint.Columns {
+			if _, ok := stmt.Schema.FieldsByDBName[column]; !ok {
+				obsolete = false
+			}
+		}
+
+		if obsolete {
+			names = append(names, constraint.Name)
+		}
+	}
+	sort.Strings(names)
+	return
+}
+
+// unusedColumns live columns no field of the model maps to, columns named after ignored fields are kept
+func (m Migrator) unusedColumns(stmt *gorm.Statement, columnTypes []*sql.ColumnType) (names []string) {
+	used := map[string]bool{}
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName != "" {
+			used[field.DBName] = true
+		} else {
+			used[m.DB.NamingStrategy.ColumnName(stmt.Table, field.Name)] = true
+		}
+	}
+
+	for _, columnType := range columnTypes {
+		if _, ok := stmt.Schema.FieldsByDBName[columnType.Name()]; !ok && !used[columnType.Name()] {
+			names = append(names, columnType.Name())
+		}
+	}
+	return
+}
+
+// ValidateError discrepancies between models and database found by Validate
+type ValidateError struct {
+	Discrepancies []string
+}
+
+func (err ValidateError) Error() string {
+	return "schema mismatch: " + strings.Join(err.Discrepancies, "; ")
+}
+
+// Validate check database matches models without migrating, returns ValidateError describing all discrepancies
+func (m Migrator) Validate(values ...interface{}) error {
+	var (
+		tx            = m.DB.Session(&gorm.Session{})
+		discrepancies []string
+	)
+
+	for _, value := range values {
+		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			if !m.migratorOf(tx).HasTable(value) {
+				discrepancies = append(discrepancies, fmt.Sprintf("table %v is missing", stmt.Table))
+				return nil
+			}
+
+			columnTypes, err := m.migratorOf(tx).ColumnTypes(value)
+			if err != nil {
+				return err
+			}
+
+			liveColumns := map[string]*sql.ColumnType{}
+			for _, columnType := range columnTypes {
+				liveColumns[columnType.Name()] = columnType
+				if _, ok := stmt.Schema.FieldsByDBName[columnType.Name()]; !ok {
+					discrepancies = append(discrepancies, fmt.Sprintf("column %v.%v is not in model", stmt.Table, columnType.Name()))
+				}
+			}
+
+			for _, dbName := range stmt.Schema.DBNames {
+				field := stmt.Schema.FieldsByDBName[dbName]
+				if columnType, ok := liveColumns[dbName]; !ok {
+					discrepancies = append(discrepancies, fmt.Sprintf("column %v.%v is missing", stmt.Table, dbName))
+				} else if m.columnTypeChanged(field, columnType) {
+					discrepancies = append(discrepancies, fmt.Sprintf("column %v.%v type mismatch, expects %v, got %v", stmt.Table, dbName, m.DataTypeOf(field), columnType.DatabaseTypeName()))
+				}
+			}
+
+			for _, idx := range sortedIndexes(m.parseIndexes(stmt)) {
+				if idx.Manual {
+					continue
+				}
+
+				if !m.migratorOf(tx).HasIndex(value, idx.Name) {
+					discrepancies = append(discrepancies, fmt.Sprintf("index %v on %v is missing", idx.Name, stmt.Table))
+				}
+			}
+
+			// skip constraints if database doesn't support reflecting them
+			if constraints, err := m.constraintsOf(m.migratorOf(tx)).GetConstraints(value); err == nil {
+				liveConstraints := map[string]bool{}
+				for _, constraint := range constraints {
+					liveConstraints[constraint.Name] = true
+				}
+
+				var names []string
+				legacyNames := map[string]string{}
+				for _, rel := range sortedRelations(stmt.Schema) {
+					if constraint := rel.ParseConstraint(); constraint != nil {
+						names = append(names, constraint.Name)
+					}
+				}
+
+				for _, chk := range sortedChecks(m.parseCheckConstraints(stmt)) {
+					names = append(names, chk.Name)
+					legacyNames[chk.Name] = chk.LegacyName
+				}
+
+				for _, unique := range sortedUniqueConstraints(stmt.Schema.ParseUniqueConstraints()) {
+					names = append(names, unique.Name)
+					legacyNames[unique.Name] = unique.LegacyName
+				}
+
+				for _, name := range names {
+					if !liveConstraints[name] && (legacyNames[name] == "" || !liveConstraints[legacyNames[name]]) {
+						discrepancies = append(discrepancies, fmt.Sprintf("constraint %v on %v is missing", name, stmt.Table))
+					}
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	if len(discrepancies) > 0 {
+		return ValidateError{Discrepancies: discrepancies}
+	}
+	return nil
+}
+
+// RepairSchema create missing indexes and constraints of models, recreates changed indexes, columns are untouched
+func (m Migrator) RepairSchema(values ...interface{}) error {
+	tx := m.DB.Session(&gorm.Session{})
+
+	for _, value := range m.ReorderModels(values, false) {
+		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			if !m.migratorOf(tx).HasTable(value) {
+				return fmt.Errorf("failed to repair schema of %v, table is missing", stmt.Table)
+			}
+
+			repair := func(typ, name string, err error) error {
+				if err != nil && m.SkipUnsupported && errors.Is(err, gorm.ErrNotImplemented) {
+					m.DB.Logger.Warn(m.DB.Statement.Context, "skip %v %v, it is not supported by %v", typ, name, m.Dialector.Name())
+					return nil
+				}
+				return err
+			}
+
+			for _, rel := range sortedRelations(stmt.Schema) {
+				if constraint := rel.ParseConstraint(); constraint != nil && !m.migratorOf(tx).HasConstraint(value, constraint.Name) {
+					if err := repair("create_constraint", constraint.Name, m.migratorOf(tx).CreateConstraint(value, constraint.Name)); err != nil {
+						return err
+					}
+				}
+			}
+
+			for _, chk := range sortedChecks(m.parseCheckConstraints(stmt)) {
+				if _, ok := m.liveConstraintName(tx, value, chk.Name, chk.LegacyName); !ok {
+					err := m.validateCheckConstraint(stmt, chk)
+					if err == nil {
+						err = m.migratorOf(tx).CreateConstraint(value, chk.Name)
+					}
+
+					if err := repair("create_constraint", chk.Name, err); err != nil {
+						return err
+					}
+				}
+			}
+
+			for _, unique := range sortedUniqueConstraints(stmt.Schema.ParseUniqueConstraints()) {
+				if _, ok := m.liveConstraintName(tx, value, unique.Name, unique.LegacyName); !ok {
+					if err := repair("create_constraint", unique.Name, m.migratorOf(tx).CreateConstraint(value, unique.Name)); err != nil {
+						return err
+					}
+				}
+			}
+
+			// without reflection, only missing indexes are created
+			reflectedIndexes, err := m.indexesOf(m.migratorOf(tx)).GetIndexes(value)
+			reflected := err == nil
+			if err != nil && !errors.Is(err, gorm.ErrNotImplemented) {
+				return err
+			}
+
+			liveIndexes := map[string]IndexInfo{}
+			for _, idx := range reflectedIndexes {
+				liveIndexes[idx.Name] = idx
+			}
+
+			for _, idx := range sortedIndexes(m.parseIndexes(stmt)) {
+				if idx.Manual {
+					continue
+				}
+
+				if live, ok := liveIndexes[idx.Name]; (reflected && !ok) || (!reflected && !m.migratorOf(tx).HasIndex(value, idx.Name)) {
+					if err := m.createIndex(tx, stmt, value, idx); err != nil {
+						return err
+					}
+				} else if ok && (live.Class != strings.ToUpper(idx.Class) || indexColumnsChanged(live, idx)) {
+					if err := m.withDB(tx).DropIndexIfExists(value, idx.Name); err != nil {
+						return err
+					}
+
+					if err := m.createIndex(tx, stmt, value, idx); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SchemaDiff differences between schemas of two models, e.g: "column name: varchar(100) != text"
+type SchemaDiff struct {
+	Columns     []string
+	Indexes     []string
+	Constraints []string
+}
+
+// Empty schemas of models are equivalent
+func (diff SchemaDiff) Empty() bool {
+	return len(diff.Columns) == 0 && len(diff.Indexes) == 0 && len(diff.Constraints) == 0
+}
+
+// DiffModels compare parsed schemas of two models without querying database, reports differences of columns, types, indexes and constraints
+func (m Migrator) DiffModels(a, b interface{}) (*SchemaDiff, error) {
+	var (
+		diff        = &SchemaDiff{}
+		names       [2]string
+		columns     [2]map[string]string
+		indexes     [2]map[string]string
+		constraints [2]map[string]string
+	)
+
+	for i, value := range []interface{}{a, b} {
+		columns[i], indexes[i], constraints[i] = map[string]string{}, map[string]string{}, map[string]string{}
+		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			names[i] = stmt.Schema.Name
+			for _, dbName := range stmt.Schema.DBNames {
+				expr := m.FullDataTypeOf(stmt.Schema.FieldsByDBName[dbName])
+				columns[i][dbName] = m.inlineDDL(expr.SQL, expr.Vars...)
+			}
+
+			for name, idx := range m.parseIndexes(stmt) {
+				var options []string
+				for _, opt := range idx.Fields {
+					option := opt.Expression
+					if option == "" && opt.Field != nil {
+						option = opt.DBName
+					}
+					if opt.Length > 0 {
+						option += fmt.Sprintf("(%d)", opt.Length)
+					}
+					options = append(options, strings.Join(strings.Fields(strings.Join([]string{option, m.collationOf(opt.Collate, opt.CollateProvider), opt.Sort, opt.Nulls}, " ")), " "))
+				}
+				definition := strings.TrimSpace(idx.Class + " INDEX")
+				if idx.Type != "" {
+					definition += " USING " + idx.Type
+				}
+				definition += " (" + strings.Join(options, ",") + ")"
+				if idx.Where != "" {
+					definition += " WHERE " + idx.Where
+				}
+				indexes[i][name] = definition
+			}
+
+			for _, rel := range sortedRelations(stmt.Schema) {
+				if constraint := rel.ParseConstraint(); constraint != nil {
+					sql, values := m.buildConstraint(constraint)
+					constraints[i][constraint.Name] = m.inlineDDL(sql, values...)
+				}
+			}
+
+			for _, chk := range sortedChecks(m.parseCheckConstraints(stmt)) {
+				sql, values := m.buildCheckConstraint(chk)
+				constraints[i][chk.Name] = m.inlineDDL(sql, values...)
+			}
+
+			for _, unique := range sortedUniqueConstraints(stmt.Schema.ParseUniqueConstraints()) {
+				sql, values := m.buildUniqueConstraint(unique)
+				constraints[i][unique.Name] = m.inlineDDL(sql, values...)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	diff.Columns = diffDefinitions("column", names, columns)
+	diff.Indexes = diffDefinitions("index", names, indexes)
+	diff.Constraints = diffDefinitions("constraint", names, constraints)
+	return diff, nil
+}
+
+// diffDefinitions compare definitions of two models by name, results are sorted by name
+func diffDefinitions(kind string, models [2]string, definitions [2]map[string]string) (results []string) {
+	var keys []string
+	for name := range definitions[0] {
+		keys = append(keys, name)
+	}
+
+	for name := range definitions[1] {
+		if _, ok := definitions[0][name]; !ok {
+			keys = append(keys, name)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, name := range keys {
+		a, inA := definitions[0][name]
+		b, inB := definitions[1][name]
+		if !inA {
+			results = append(results, fmt.Sprintf("%v %v only exists in %v", kind, name, models[1]))
+		} else if !inB {
+			results = append(results, fmt.Sprintf("%v %v only exists in %v", kind, name, models[0]))
+		} else if a != b {
+			results = append(results, fmt.Sprintf("%v %v: %v != %v", kind, name, a, b))
+		}
+	}
+	return
+}
+
+// AfterTableCreatedInterface models implement it to run one-time setup after the table is created
+type AfterTableCreatedInterface interface {
+	AfterTableCreated(*gorm.DB) error
+}
+
+// CreateTable create table in database for values
+func (m Migrator) CreateTable(values ...interface{}) error {
+	for _, value := range m.ReorderModels(values, false) {
+		tx := m.DB.Session(&gorm.Session{})
+		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			for _, dbName := range stmt.Schema.DBNames {
+				if enums := m.enumsOf(m.migratorOf(tx)); enums != nil && len(stmt.Schema.FieldsByDBName[dbName].EnumValues) > 0 {
+					if err := enums.MigrateEnum(value, stmt.Schema.FieldsByDBName[dbName]); err != nil {
+						return err
+					}
+				}
+			}
+
+			createTableSQL, values, err := m.BuildCreateTableSQL(value)
+			if err != nil {
+				return err
+			}
+
+			var afterIndexes []schema.Index
+			for _, idx := range sortedIndexes(m.parseIndexes(stmt)) {
+				if (m.CreateIndexAfterCreateTable || idx.Deferred || idx.Where != "") && !idx.Manual {
+					afterIndexes = append(afterIndexes, idx)
+				}
+			}
+
+			for _, rel := range sortedRelations(stmt.Schema) {
+				// create join table
+				if rel.JoinTable != nil {
+					joinValue := reflect.New(rel.JoinTable.ModelType).Interface()
+					if !m.migratorOf(tx).HasTable(rel.JoinTable.Table) {
+						defer m.migratorOf(tx.Table(rel.JoinTable.Table)).CreateTable(joinValue)
+					}
+				}
+			}
+
+			if err := m.execDDL(createTableSQL, values...); err != nil {
+				return err
+			}
+
+			// indexes excluded from CREATE TABLE, e.g: deferred or partial indexes, a missing one would leave uniqueness unenforced
+			for _, idx := range afterIndexes {
+				if err := m.createIndex(tx, stmt, value, idx); err != nil {
+					if m.SkipUnsupported && errors.Is(err, gorm.ErrNotImplemented) {
+						m.DB.Logger.Warn(m.DB.Statement.Context, "skip create_index %v, it is not supported by %v", idx.Name, m.Dialector.Name())
+						continue
+					}
+					return err
+				}
+			}
+
+			for _, rel := range sortedRelations(stmt.Schema) {
+				if constraint := rel.ParseConstraint(); constraint != nil {
+					if err := m.createForeignKeyIndex(value, stmt, constraint); err != nil {
+						return err
+					}
+				}
+			}
+
+			for _, dbName := range stmt.Schema.DBNames {
+				if err := m.commentColumn(value, stmt.Schema.FieldsByDBName[dbName]); err != nil {
+					return err
+				}
+			}
+
+			if creator, ok := value.(AfterTableCreatedInterface); ok {
+				return creator.AfterTableCreated(tx)
+			} else if creator, ok := reflect.New(stmt.Schema.ModelType).Interface().(AfterTableCreatedInterface); ok {
+				return creator.AfterTableCreated(tx)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BuildCreateTableSQL build create table SQL and vars of value without executing it
+func (m Migrator) BuildCreateTableSQL(value interface{}) (createTableSQL string, values []interface{}, err error) {
+	err = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		var (
+			hasPrimaryKeyInDataType bool
+			parentTable             string
+		)
+		createTableSQL, values = "CREATE TABLE ? (", []interface{}{m.CurrentTable(stmt)}
+
+		// skips writing WAL for fast loads, e.g: db.Set("gorm:table_unlogged", true), see SetTableLogged
+		if unlogged, ok := m.DB.Get("gorm:table_unlogged"); ok && unlogged == true {
+			logger, ok := m.migratorOf(m.DB).(UnloggedTableInterface)
+			if !ok {
+				return gorm.ErrNotImplemented
+			}
+			createTableSQL = logger.UnloggedTableSQL()
+		}
+
+		if parent, ok := m.DB.Get("gorm:table_inherits"); ok {
+			if _, ok := m.migratorOf(m.DB).(TableInheritanceInterface); !ok {
+				return gorm.ErrNotImplemented
+			}
+
+			var err error
+			if parentTable, err = m.tableNameOf(parent); err != nil {
+				return err
+			}
+		}
+
+		dbNames, err := m.columnOrderOf(stmt)
+		if err != nil {
+			return err
+		}
+
+		for _, dbName := range dbNames {
+			field := stmt.Schema.FieldsByDBName[dbName]
+			createTableSQL += fmt.Sprintf("? ?")
+			hasPrimaryKeyInDataType = hasPrimaryKeyInDataType || strings.Contains(strings.ToUpper(m.columnTypeOf(field)), "PRIMARY KEY")
+			values = append(values, clause.Column{Name: dbName}, m.FullDataTypeOf(field))
+			createTableSQL += ","
+		}
+
+		if !hasPrimaryKeyInDataType && len(stmt.Schema.PrimaryFields) > 0 {
+			createTableSQL += "PRIMARY KEY ?"
+			primaryKeys := []interface{}{}
+			for _, field := range stmt.Schema.PrimaryFields {
+				primaryKeys = append(primaryKeys, clause.Column{Name: field.DBName})
+			}
+
+			if clauses, ok := m.migratorOf(m.DB).(ConstraintClausesInterface); ok {
+				createTableSQL += clauses.PrimaryKeyClausesOf(stmt.Schema.PrimaryFields)
+			}
+			createTableSQL += ","
+
+			values = append(values, primaryKeys)
+		}
+
+		if !m.CreateIndexAfterCreateTable {
+			for _, idx := range sortedIndexes(m.parseIndexes(stmt)) {
+				// partial indexes can't be declared inline
+				if idx.Deferred || idx.Manual || idx.Where != "" {
+					continue
+				}
+
+				if idx.Class != "" {
+					createTableSQL += idx.Class + " "
+				}
+				createTableSQL += "INDEX ?"
+				if idx.Type != "" {
+					createTableSQL += " USING " + idx.Type
+				}
+				createTableSQL += " ?"
+				if idx.Parser != "" {
+					createTableSQL += " WITH PARSER " + idx.Parser
+				}
+				createTableSQL += ","
+				values = append(values, clause.Column{Name: idx.Name}, m.indexOptionsOf(m.migratorOf(m.DB)).BuildIndexOptions(idx.Fields, stmt))
+			}
+		}
+
+		for _, rel := range sortedRelations(stmt.Schema) {
+			if constraint := rel.ParseConstraint(); constraint != nil {
+				sql, vars := m.buildConstraint(constraint)
+				createTableSQL += sql + ","
+				values = append(values, vars...)
+			}
+		}
+
+		for _, chk := range sortedChecks(m.parseCheckConstraints(stmt)) {
+			sql, vars := m.buildCheckConstraint(chk)
+			createTableSQL += sql + ","
+			values = append(values, vars...)
+		}
+
+		for _, unique := range sortedUniqueConstraints(stmt.Schema.ParseUniqueConstraints()) {
+			sql, vars := m.buildUniqueConstraint(unique)
+			createTableSQL += sql + ","
+			values = append(values, vars...)
+		}
+
+		createTableSQL = strings.TrimSuffix(createTableSQL, ",")
+
+		createTableSQL += ")"
+
+		if parentTable != "" {
+			createTableSQL += m.migratorOf(m.DB).(TableInheritanceInterface).InheritsSQL()
+			values = append(values, m.qualifiedTable(parentTable))
+		}
+
+		if tableOption, ok := m.tableOptionsOf(value, stmt); ok {
+			createTableSQL += tableOption
+		}
+		return nil
+	})
+	return
+}
+
+// UnloggedTableInterface dialects implement it to switch tables between logged and unlogged, e.g: CREATE UNLOGGED TABLE (Postgres)
+type UnloggedTableInterface interface {
+	UnloggedTableSQL() string
+	SetTableLogged(value interface{}, logged bool) error
+}
+
+// TableInheritanceInterface dialects implement it to create tables inheriting gorm:table_inherits, e.g: INHERITS (?) (Postgres)
+type TableInheritanceInterface interface {
+	InheritsSQL() string
+}
+
+// TableOptionsInterface models implement it to replace gorm:table_options of their tables, e.g: " ENGINE=MEMORY"
+type TableOptionsInterface interface {
+	TableOptions() string
+}
+
+// tableOptionsOf table options appended to CREATE TABLE, options of the model take precedence over gorm:table_options
+func (m Migrator) tableOptionsOf(value interface{}, stmt *gorm.Statement) (string, bool) {
+	if optioner, ok := value.(TableOptionsInterface); ok {
+		return optioner.TableOptions(), true
+	} else if stmt.Schema != nil {
+		if optioner, ok := reflect.New(stmt.Schema.ModelType).Interface().(TableOptionsInterface); ok {
+			return optioner.TableOptions(), true
+		}
+	}
+
+	if tableOption, ok := m.DB.Get("gorm:table_options"); ok {
+		return fmt.Sprint(tableOption), true
+	}
+	return "", false
+}
+
+// DropTableInterface dialects implement it to drop tables with their own statement, e.g: DROP TABLE IF EXISTS ? CASCADE (Postgres)
+type DropTableInterface interface {
+	DropTableSQL() string
+}
+
+func (m Migrator) DropTable(values ...interface{}) error {
+	dropTableSQL := "DROP TABLE IF EXISTS ?"
+	if dropper, ok := m.migratorOf(m.DB).(DropTableInterface); ok {
+		dropTableSQL = dropper.DropTableSQL()
+	}
+
+	values = m.ReorderModels(values, false)
+	for i := len(values) - 1; i >= 0; i-- {
+		if err := m.RunWithValue(values[i], func(stmt *gorm.Statement) error {
+			return m.execDDL(dropTableSQL, m.CurrentTable(stmt))
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m Migrator) HasTable(value interface{}) bool {
+	var count int64
+
+	m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		sql, values := m.reflectionQueries().QueryForTableExists(stmt)
+		return m.DB.Raw(sql, values...).Row().Scan(&count)
+	})
+
+	return count > 0
+}
+
+// IsTableEmpty check whether the table has no rows, e.g: SELECT CASE WHEN EXISTS (SELECT 1 FROM ?) THEN 0 ELSE 1 END
+func (m Migrator) IsTableEmpty(value interface{}) (empty bool, err error) {
+	err = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		return m.DB.Raw("SELECT CASE WHEN EXISTS (SELECT 1 FROM ?) THEN 0 ELSE 1 END", m.CurrentTable(stmt)).Row().Scan(&empty)
+	})
+	return
+}
+
+// TableDDLInterface dialects implement it to return the DDL of tables stored by the database
+type TableDDLInterface interface {
+	GetTableDDL(value interface{}) (string, error)
+}
+
+// GetTableDDL returns the DDL of the table stored by the database, e.g: SHOW CREATE TABLE ? (MySQL)
+func (m Migrator) GetTableDDL(value interface{}) (string, error) {
+	return "", gorm.ErrNotImplemented
+}
+
+func (m Migrator) QueryForTableExists(stmt *gorm.Statement) (string, []interface{}) {
+	return "SELECT count(*) FROM information_schema.tables WHERE table_schema = ? AND table_name IN ? AND table_type = ?",
+		[]interface{}{m.informationSchemaOf(), m.identifierCandidates(stmt.Table), "BASE TABLE"}
+}
+
+// RenameTableInterface dialects implement it to name the renamed table, e.g: unqualified as it stays in its schema (Postgres)
+type RenameTableInterface interface {
+	RenamedTableOf(name string) interface{}
+}
+
+func (m Migrator) RenameTable(oldName, newName interface{}) error {
+	oldTable, err := m.tableNameOf(oldName)
+	if err != nil {
+		return err
+	}
+
+	newTable, err := m.tableNameOf(newName)
+	if err != nil {
+		return err
+	}
+
+	var renamed interface{} = m.qualifiedTable(newTable)
+	if renamer, ok := m.migratorOf(m.DB).(RenameTableInterface); ok {
+		renamed = renamer.RenamedTableOf(newTable)
+	}
+	return m.execDDL("ALTER TABLE ? RENAME TO ?", m.qualifiedTable(oldTable), renamed)
+}
+
+func (m Migrator) tableNameOf(value interface{}) (string, error) {
+	if v, ok := value.(string); ok {
+		return v, nil
+	}
+
+	stmt := &gorm.Statement{DB: m.DB}
+	if err := stmt.Parse(value); err != nil {
+		return "", err
+	}
+	return stmt.Table, nil
+}
+
+// TableLikeInterface dialects implement it to create tables with the structure of another table, e.g: CREATE TABLE ? LIKE ? (MySQL)
+type TableLikeInterface interface {
+	CreateTableLikeSQL(including ...string) string
+}
+
+// CreateTableLike create table with the structure of another table, e.g: CREATE TABLE ? (LIKE ? INCLUDING ALL)
+func (m Migrator) CreateTableLike(dst, src interface{}, including ...string) error {
+	liker, ok := m.migratorOf(m.DB).(TableLikeInterface)
+	if !ok {
+		return gorm.ErrNotImplemented
+	}
+
+	dstTable, err := m.tableNameOf(dst)
+	if err != nil {
+		return err
+	}
+
+	srcTable, err := m.tableNameOf(src)
+	if err != nil {
+		return err
+	}
+
+	return m.execDDL(liker.CreateTableLikeSQL(including...), m.qualifiedTable(dstTable), m.qualifiedTable(srcTable))
+}
+
+// PartitionOption option converting table to partitioned table, e.g: {By: "RANGE (created_at)"}
+type PartitionOption struct {
+	By        string // partition strategy and key, e.g: RANGE (created_at), LIST (region), HASH (id)
+	Partition string // the table is renamed to it and attached as a partition, defaults to <table>_legacy
+	Values    string // partition bound of existing rows, e.g: FROM (MINVALUE) TO ('2021-01-01'), IN ('eu'), defaults to DEFAULT
+}
+
+// PartitionInterface dialects implement it to convert tables to partitioned tables
+type PartitionInterface interface {
+	ConvertToPartitioned(value interface{}, option PartitionOption) error
+}
+
+// ConvertToPartitioned convert table to partitioned table by attaching it as a partition (Postgres)
+func (m Migrator) ConvertToPartitioned(value interface{}, option PartitionOption) error {
+	return gorm.ErrNotImplemented
+}
+
+// CreateTableAs create table from query results, e.g: CREATE TABLE ? AS SELECT ...
+func (m Migrator) CreateTableAs(dst string, query *gorm.DB) error {
+	return m.execDDL("CREATE TABLE ? AS ?", m.qualifiedTable(dst), query)
+}
+
+// TableOwnerInterface dialects implement it to transfer table ownership
+type TableOwnerInterface interface {
+	SetTableOwner(value interface{}, owner string) error
+}
+
+// SetTableOwner transfer table ownership, e.g: ALTER TABLE ? OWNER TO ? (Postgres)
+func (m Migrator) SetTableOwner(value interface{}, owner string) error {
+	return gorm.ErrNotImplemented
+}
+
+// tableOwnersOf table ownership of the dialect migrator, migrators not embedding Migrator use m
+func (m Migrator) tableOwnersOf(migrator gorm.Migrator) TableOwnerInterface {
+	if reflector, ok := migrator.(TableOwnerInterface); ok {
+		return reflector
+	}
+	return m
+}
+
+// TableSchemaInterface dialects implement it to move tables to another schema
+type TableSchemaInterface interface {
+	MoveTableToSchema(stmt *gorm.Statement, schema string) error
+}
+
+// SetTableSchema move table and its pending constraints to another schema, e.g: ALTER TABLE ? SET SCHEMA ?
+func (m Migrator) SetTableSchema(value interface{}, schema string) error {
+	mover, ok := m.migratorOf(m.DB).(TableSchemaInterface)
+	if !ok {
+		return gorm.ErrNotImplemented
+	}
+
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if err := mover.MoveTableToSchema(stmt, schema); err != nil {
+			return err
+		}
+
+		if !m.migratorOf(m.DB).HasTable(m.pendingConstraintsTable()) {
+			return nil
+		}
+		return m.pendingConstraints().Where(map[string]interface{}{"schema": m.schemaName(), "table": stmt.Table}).Update("schema", schema).Error
+	})
+}
+
+// TableReplicaIdentityInterface models implement it to override Config.ReplicaIdentity of their tables, e.g: FULL
+type TableReplicaIdentityInterface interface {
+	TableReplicaIdentity() string
+}
+
+type ReplicaIdentityInterface interface {
+	ReplicaIdentityOf(value interface{}) (string, error)
+	SetReplicaIdentity(value interface{}, identity string) error
+}
+
+// replicaIdentitiesOf replica identity reflection of the dialect migrator, migrators not embedding Migrator use m
+func (m Migrator) replicaIdentitiesOf(migrator gorm.Migrator) ReplicaIdentityInterface {
+	if reflector, ok := migrator.(ReplicaIdentityInterface); ok {
+		return reflector
+	}
+	return m
+}
+
+// normalizeReplicaIdentity returns replica identity in the form reflected by ReplicaIdentityOf, e.g: USING INDEX idx_users_email
+func normalizeReplicaIdentity(identity string) (string, error) {
+	fields := strings.Fields(identity)
+	if len(fields) == 1 {
+		switch upper := strings.ToUpper(fields[0]); upper {
+		case "DEFAULT", "FULL", "NOTHING":
+			return upper, nil
+		}
+	} else if len(fields) == 3 && strings.EqualFold(fields[0], "USING") && strings.EqualFold(fields[1], "INDEX") {
+		return "USING INDEX " + fields[2], nil
+	}
+	return "", fmt.Errorf("invalid replica identity %v", identity)
+}
+
+// replicaIdentityOf replica identity to migrate the table to, blank means unmanaged
+func (m Migrator) replicaIdentityOf(value interface{}, stmt *gorm.Statement) string {
+	if identifier, ok := value.(TableReplicaIdentityInterface); ok {
+		return identifier.TableReplicaIdentity()
+	} else if stmt.Schema != nil {
+		if identifier, ok := reflect.New(stmt.Schema.ModelType).Interface().(TableReplicaIdentityInterface); ok {
+			return identifier.TableReplicaIdentity()
+		}
+	}
+	return m.ReplicaIdentity
+}
+
+// SetReplicaIdentity set replica identity of the table for logical replication, e.g: ALTER TABLE ? REPLICA IDENTITY USING INDEX ? (Postgres)
+func (m Migrator) SetReplicaIdentity(value interface{}, identity string) error {
+	return gorm.ErrNotImplemented
+}
+
+// StorageParametersInterface dialects implement it to set and reset storage parameters of tables
+type StorageParametersInterface interface {
+	SetTableStorageParameters(value interface{}, parameters map[string]string) error
+	ResetTableStorageParameters(value interface{}, names ...string) error
+}
+
+// storageParametersOf storage parameters of the dialect, nil if the dialect doesn't support them
+func (m Migrator) storageParametersOf() StorageParametersInterface {
+	if setter, ok := m.migratorOf(m.DB).(StorageParametersInterface); ok {
+		return setter
+	}
+	return nil
+}
+
+// SetAutovacuum enable or disable autovacuum of the table, e.g: ALTER TABLE ? SET (autovacuum_enabled = false) (Postgres)
+func (m Migrator) SetAutovacuum(value interface{}, enabled bool) error {
+	if setter := m.storageParametersOf(); setter != nil {
+		return setter.SetTableStorageParameters(value, map[string]string{"autovacuum_enabled": strconv.FormatBool(enabled)})
+	}
+	return gorm.ErrNotImplemented
+}
+
+// WithAutovacuumDisabled disable autovacuum of the table while fc bulk loads it, then reset it even if fc fails
+func (m Migrator) WithAutovacuumDisabled(value interface{}, fc func() error) error {
+	if err := m.SetAutovacuum(value, false); err != nil {
+		return err
+	}
+
+	err := fc()
+	if resetErr := m.storageParametersOf().ResetTableStorageParameters(value, "autovacuum_enabled"); err == nil {
+		err = resetErr
+	}
+	return err
+}
+
+// SetTableLogged switch table between logged and unlogged, e.g: ALTER TABLE ? SET LOGGED (Postgres)
+func (m Migrator) SetTableLogged(value interface{}, logged bool) error {
+	return gorm.ErrNotImplemented
+}
+
+// ReplicaIdentityOf reflect replica identity of the table, e.g: FULL, USING INDEX idx_users_email (Postgres)
+func (m Migrator) ReplicaIdentityOf(value interface{}) (string, error) {
+	return "", gorm.ErrNotImplemented
+}
+
+// ColumnForeignKeyInterface dialects implement it to add foreign keys of columns in their ALTER TABLE ADD statement
+type ColumnForeignKeyInterface interface {
+	ColumnForeignKeyOf(constraint *schema.Constraint) (sql string, vars []interface{})
+}
+
+func (m Migrator) AddColumn(value interface{}, field string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if field := stmt.Schema.LookUpField(field); field != nil {
+			var (
+				sql        = "ALTER TABLE ? ADD ? ?"
+				values     = []interface{}{m.CurrentTable(stmt), clause.Column{Name: field.DBName}, m.FullDataTypeOf(field)}
+				constraint = m.columnConstraint(stmt, field)
+			)
+
+			if constraint != nil {
+				if builder, ok := m.migratorOf(m.DB).(ColumnForeignKeyInterface); ok {
+					constraintSQL, vars := builder.ColumnForeignKeyOf(constraint)
+					sql += constraintSQL
+					values = append(values, vars...)
+				} else {
+					constraintSQL, vars := m.buildConstraint(constraint)
+					sql += ", ADD " + constraintSQL
+					values = append(values, vars...)
+				}
+			}
+
+			if err := m.execDDL(sql, values...); err != nil {
+				return err
+			}
+
+			if constraint != nil {
+				if err := m.createForeignKeyIndex(value, stmt, constraint); err != nil {
+					return err
+				}
+			}
+
+			return m.commentColumn(value, field)
+		}
+		return fmt.Errorf("failed to look up field with name: %s", field)
+	})
+}
+
+// columnConstraint returns the foreign key constraint made of the field alone if AddForeignKeysWithColumn is set
+func (m Migrator) columnConstraint(stmt *gorm.Statement, field *schema.Field) *schema.Constraint {
+	if !m.AddForeignKeysWithColumn {
+		return nil
+	}
+
+	for _, rel := range sortedRelations(stmt.Schema) {
+		if constraint := rel.ParseConstraint(); constraint != nil && len(constraint.ForeignKeys) == 1 && constraint.ForeignKeys[0] == field {
+			return constraint
+		}
+	}
+	return nil
+}
+
+func (m Migrator) DropColumn(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if field := stmt.Schema.LookUpField(name); field != nil {
+			name = field.DBName
+		}
+
+		return m.execDDL(
+			"ALTER TABLE ? DROP COLUMN ?", m.CurrentTable(stmt), clause.Column{Name: name},
+		)
+	})
+}
+
+func (m Migrator) AlterColumn(value interface{}, field string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if field := stmt.Schema.LookUpField(field); field != nil {
+			return m.alterColumnType(value, stmt, field)
+		}
+		return fmt.Errorf("failed to look up field with name: %s", field)
+	})
+}
+
+// alterColumnType alter type of the column, e.g: ALTER TABLE ? ALTER COLUMN ? TYPE ?, indexes on it are rebuilt if its collation changed
+func (m Migrator) alterColumnType(value interface{}, stmt *gorm.Statement, field *schema.Field) error {
+	if err := m.execDDL(
+		"ALTER TABLE ? ALTER COLUMN ? TYPE ?",
+		m.CurrentTable(stmt), clause.Column{Name: field.DBName}, m.FullDataTypeOf(field),
+	); err != nil {
+		return err
+	}
+
+	if collateRegexp.MatchString(m.DataTypeOf(field)) {
+		return m.reindexColumn(value, stmt, field)
+	}
+	return nil
+}
+
+var collateRegexp = regexp.MustCompile(`(?i)\bcollate\b`)
+
+// ReindexInterface dialects implement it to rebuild indexes, e.g: REINDEX INDEX ? (Postgres)
+type ReindexInterface interface {
+	ReindexIndex(value interface{}, name string) error
+}
+
+// reindexColumn rebuild indexes on the column after its collation changed, as they might be ordered by the old collation
+func (m Migrator) reindexColumn(value interface{}, stmt *gorm.Statement, field *schema.Field) error {
+	for _, idx := range sortedIndexes(m.parseIndexes(stmt)) {
+		for _, opt := range idx.Fields {
+			if opt.Field != field || !m.migratorOf(m.DB).HasIndex(value, idx.Name) {
+				continue
+			}
+
+			reindexer, ok := m.migratorOf(m.DB).(ReindexInterface)
+			if !ok {
+				m.DB.Logger.Warn(m.DB.Statement.Context, "index %v should be reindexed after changing collation of %v.%v", idx.Name, stmt.Table, field.DBName)
+			} else if err := reindexer.ReindexIndex(value, idx.Name); err != nil {
+				return fmt.Errorf("failed to reindex %v after changing collation of %v.%v: %w", idx.Name, stmt.Table, field.DBName, err)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// ShadowAlterColumn change column type through a shadow column kept in sync by triggers and backfilled in batches
+func (m Migrator) ShadowAlterColumn(value interface{}, name string, batchSize int) error {
+	if batchSize <= 0 {
+		return fmt.Errorf("invalid batch size %v for shadow column migration", batchSize)
+	}
+
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		tx := m.DB.Session(&gorm.Session{})
+		field := stmt.Schema.LookUpField(name)
+		if field == nil {
+			return fmt.Errorf("failed to look up field with name: %s", name)
+		}
+
+		primaryField := stmt.Schema.PrioritizedPrimaryField
+		if primaryField == nil || (primaryField.DataType != schema.Int && primaryField.DataType != schema.Uint) {
+			return fmt.Errorf("shadow column migration of %v requires an integer primary key", field.DBName)
+		}
+
+		// columns with defaults are added NOT NULL at once, existing rows get the default until backfilled, NULLs are copied as the default
+		// NOT NULL of columns without defaults and UNIQUE are added after the backfill
+		var (
+			shadow   = *field
+			sourceOf = func(column string) string { return column }
+		)
+
+		shadow.DBName, shadow.Unique = field.DBName+"_new", false
+		if field.NotNull && hasDefaultValue(field) {
+			sourceOf = func(column string) string {
+				return "COALESCE(" + column + ", " + m.defaultExprOf(field) + ")"
+			}
+		} else {
+			shadow.NotNull = false
+		}
+
+		triggerer, ok := m.migratorOf(m.DB).(ShadowTriggersInterface)
+		if !ok {
+			return gorm.ErrNotImplemented
+		}
+
+		triggers, err := triggerer.ShadowSyncTriggers(stmt, sourceOf, field.DBName, shadow.DBName, primaryField.DBName)
+		if err != nil {
+			return err
+		}
+
+		if !m.migratorOf(tx).HasColumn(value, shadow.DBName) {
+			if err := m.execDDL(
+				"ALTER TABLE ? ADD ? ?", m.CurrentTable(stmt), clause.Column{Name: shadow.DBName}, m.FullDataTypeOf(&shadow),
+			); err != nil {
+				return err
+			}
+		}
+
+		// triggers left by a failed run are replaced
+		for _, trigger := range triggers {
+			if err := tx.Exec(trigger.Drop).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Exec(trigger.Create).Error; err != nil {
+				return err
+			}
+		}
+
+		// rows written after the range is taken are synced by the triggers
+		var minID, maxID sql.NullInt64
+		if err := tx.Raw(
+			"SELECT MIN(?), MAX(?) FROM ?", clause.Column{Name: primaryField.DBName}, clause.Column{Name: primaryField.DBName}, m.CurrentTable(stmt),
+		).Row().Scan(&minID, &maxID); err != nil {
+			return err
+		}
+
+		for start := minID.Int64; minID.Valid && start <= maxID.Int64; start += int64(batchSize) {
+			if err := tx.Exec(
+				"UPDATE ? SET ? = "+sourceOf(m.DB.Statement.Quote(clause.Column{Name: field.DBName}))+" WHERE ? >= ? AND ? < ?",
+				m.CurrentTable(stmt), clause.Column{Name: shadow.DBName},
+				clause.Column{Name: primaryField.DBName}, start, clause.Column{Name: primaryField.DBName}, start+int64(batchSize),
+			).Error; err != nil {
+				return err
+			}
+		}
+
+		return m.swapShadowColumn(stmt, func(swapper Migrator) error {
+			for i := len(triggers) - 1; i >= 0; i-- {
+				if err := swapper.DB.Exec(triggers[i].Drop).Error; err != nil {
+					return err
+				}
+			}
+
+			var indexes []schema.Index
+			for _, idx := range sortedIndexes(m.parseIndexes(stmt)) {
+				for _, opt := range idx.Fields {
+					if opt.Field == field {
+						if m.migratorOf(swapper.DB).HasIndex(value, idx.Name) {
+							if err := m.migratorOf(swapper.DB).DropIndex(value, idx.Name); err != nil {
+								return err
+							}
+						}
+						indexes = append(indexes, idx)
+						break
+					}
+				}
+			}
+
+			if err := swapper.preserveTriggers(value, func() error {
+				if err := m.migratorOf(swapper.DB).DropColumn(value, field.DBName); err != nil {
+					return err
+				}
+				return m.migratorOf(swapper.DB).RenameColumn(value, shadow.DBName, field.DBName)
+			}); err != nil {
+				return err
+			}
+
+			for _, idx := range indexes {
+				if err := swapper.createIndex(swapper.DB, stmt, value, idx); err != nil {
+					return err
+				}
+			}
+
+			if field.NotNull && !shadow.NotNull {
+				if err := swapper.AlterColumnsNullability(value, field.DBName); err != nil {
+					return err
+				}
+			}
+
+			if field.Unique {
+				if err := swapper.AlterColumnUnique(value, field.DBName); err != nil {
+					return err
+				}
+			}
+
+			return swapper.commentColumn(value, field)
+		})
+	})
+}
+
+// ShadowSyncTrigger trigger copying writes of a column to its shadow column while it is backfilled
+type ShadowSyncTrigger struct {
+	Create string
+	Drop   string
+}
+
+// ShadowTriggersInterface dialects implement it to create triggers copying writes of column to shadow
+type ShadowTriggersInterface interface {
+	ShadowSyncTriggers(stmt *gorm.Statement, sourceOf func(column string) string, column, shadow, primaryKey string) ([]ShadowSyncTrigger, error)
+}
+
+// ShadowSwapInterface dialects implement it to block writes while fc replaces the column with its shadow column
+type ShadowSwapInterface interface {
+	SwapShadowColumn(stmt *gorm.Statement, fc func(swapper Migrator) error) error
+}
+
+// swapShadowColumn run fc replacing the column with its shadow column, with writes blocked if the dialect supports it
+func (m Migrator) swapShadowColumn(stmt *gorm.Statement, fc func(swapper Migrator) error) error {
+	if swapper, ok := m.migratorOf(m.DB).(ShadowSwapInterface); ok {
+		return swapper.SwapShadowColumn(stmt, fc)
+	}
+	return fc(m.withDB(m.DB.Session(&gorm.Session{})))
+}
+
+func (m Migrator) HasColumn(value interface{}, field string) bool {
+	var count int64
+	m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		name := field
+		if field := stmt.Schema.LookUpField(field); field != nil {
+			name = field.DBName
+		}
+
+		sql, values := m.reflectionQueries().QueryForColumnExists(stmt, name)
+		return m.DB.Raw(sql, values...).Row().Scan(&count)
+	})
+
+	return count > 0
+}
+
+func (m Migrator) QueryForColumnExists(stmt *gorm.Statement, name string) (string, []interface{}) {
+	return "SELECT count(*) FROM INFORMATION_SCHEMA.columns WHERE table_schema = ? AND table_name IN ? AND column_name IN ?",
+		[]interface{}{m.informationSchemaOf(), m.identifierCandidates(stmt.Table), m.identifierCandidates(name)}
+}
+
+// RenameColumn rename column, indexes named after the old column are renamed to match the model
+func (m Migrator) RenameColumn(value interface{}, oldName, newName string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if field := stmt.Schema.LookUpField(oldName); field != nil {
+			oldName = field.DBName
+		}
+
+		if field := stmt.Schema.LookUpField(newName); field != nil {
+			newName = field.DBName
+		}
+
+		if !m.migratorOf(m.DB).HasColumn(value, oldName) {
+			return fmt.Errorf("failed to rename column %v of table %v: column %v does not exist", oldName, stmt.Table, oldName)
+		}
+
+		if m.migratorOf(m.DB).HasColumn(value, newName) {
+			return fmt.Errorf("failed to rename column %v of table %v: column %v already exists", oldName, stmt.Table, newName)
+		}
+
+		if err := m.execDDL(
+			"ALTER TABLE ? RENAME COLUMN ? TO ?",
+			m.CurrentTable(stmt), clause.Column{Name: oldName}, clause.Column{Name: newName},
+		); err != nil {
+			return err
+		}
+
+		var (
+			oldIndexName = m.DB.NamingStrategy.IndexName(stmt.Schema.Table, oldName)
+			newIndexName = m.DB.NamingStrategy.IndexName(stmt.Schema.Table, newName)
+		)
+
+		if idx := stmt.Schema.LookIndex(newIndexName); idx != nil && idx.Name == newIndexName && !m.migratorOf(m.DB).HasIndex(value, newIndexName) && m.migratorOf(m.DB).HasIndex(value, oldIndexName) {
+			if err := m.migratorOf(m.DB).RenameIndex(value, oldIndexName, newIndexName); err != nil {
+				m.DB.Logger.Warn(m.DB.Statement.Context, "failed to rename index %v to %v after renaming column, got error %v", oldIndexName, newIndexName, err)
+			} else if m.migratorOf(m.DB).HasIndex(value, oldIndexName) {
+				return m.migratorOf(m.DB).DropIndex(value, oldIndexName)
+			}
+		}
+		return nil
+	})
+}
+
+// ColumnNullabilityInterface dialects implement it to alter nullability of columns in one ALTER TABLE statement
+type ColumnNullabilityInterface interface {
+	ColumnNullabilityActionsOf(field *schema.Field, defaultValue string) (actions []string, values []interface{})
+}
+
+// AlterColumnsNullability change nullability of columns to match the model, NULLs are backfilled with defaults
+func (m Migrator) AlterColumnsNullability(value interface{}, fields ...string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		var (
+			actions []string
+			values  = []interface{}{m.CurrentTable(stmt)}
+		)
+
+		for _, name := range fields {
+			field := stmt.Schema.LookUpField(name)
+			if field == nil {
+				return fmt.Errorf("failed to look up field with name: %s", name)
+			}
+
+			defaultValue := m.defaultValueOf(field)
+			if field.NotNull && hasDefaultValue(field) && field.GeneratedExpression == "" {
+				// backfill NULLs with the default first, adding NOT NULL fails on them
+				if err := m.DB.Exec(
+					"UPDATE ? SET ? = "+m.defaultExprOf(field)+" WHERE ? IS NULL",
+					m.CurrentTable(stmt), clause.Column{Name: field.DBName}, clause.Column{Name: field.DBName},
+				).Error; err != nil {
+					return err
+				}
+			} else {
+				defaultValue = ""
+			}
+
+			if nullifier, ok := m.migratorOf(m.DB).(ColumnNullabilityInterface); ok {
+				fieldActions, fieldValues := nullifier.ColumnNullabilityActionsOf(field, defaultValue)
+				actions = append(actions, fieldActions...)
+				values = append(values, fieldValues...)
+			} else if err := m.migratorOf(m.DB).AlterColumn(value, field.DBName); err != nil {
+				return err
+			}
+		}
+
+		if len(actions) == 0 {
+			return nil
+		}
+		return m.execDDL("ALTER TABLE ? "+strings.Join(actions, ", "), values...)
+	})
+}
+
+// ColumnUniqueInterface dialects implement it to add or drop uniqueness of columns without unique constraints, e.g: unique indexes (SQLite)
+type ColumnUniqueInterface interface {
+	AddColumnUnique(stmt *gorm.Statement, field *schema.Field, name string) error
+	DropColumnUnique(value interface{}, live IndexInfo) error
+}
+
+// AlterColumnUnique add or drop the unique constraint of the column to match the model, see ColumnUniqueInterface
+func (m Migrator) AlterColumnUnique(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		field := stmt.Schema.LookUpField(name)
+		if field == nil {
+			return fmt.Errorf("failed to look up field with name: %s", name)
+		}
+
+		indexes, err := m.indexesOf(m.migratorOf(m.DB)).GetIndexes(value)
+		if err != nil {
+			return err
+		}
+
+		live, changed := m.columnUniqueChanged(stmt, field, indexes)
+		if !changed {
+			return nil
+		}
+
+		uniquer, ok := m.migratorOf(m.DB).(ColumnUniqueInterface)
+		if field.Unique {
+			uniqueName := schema.UniqueNameOf(m.DB.NamingStrategy, stmt.Table, field.DBName)
+			if ok {
+				return uniquer.AddColumnUnique(stmt, field, uniqueName)
+			}
+			return m.addColumnUnique(stmt, field, uniqueName)
+		}
+
+		if ok {
+			return uniquer.DropColumnUnique(value, *live)
+		}
+		return m.migratorOf(m.DB).DropConstraint(value, live.Name)
+	})
+}
+
+// addColumnUnique add unique constraint name of the column, e.g: ALTER TABLE ? ADD CONSTRAINT ? UNIQUE (?)
+func (m Migrator) addColumnUnique(stmt *gorm.Statement, field *schema.Field, name string) error {
+	return m.execDDL(
+		"ALTER TABLE ? ADD CONSTRAINT ? UNIQUE (?)",
+		m.CurrentTable(stmt), clause.Column{Name: name}, clause.Column{Name: field.DBName},
+	)
+}
+
+// columnUniqueChanged compare unique of field with live single column unique indexes named like column unique constraints
+func (m Migrator) columnUniqueChanged(stmt *gorm.Statement, field *schema.Field, indexes []IndexInfo) (live *IndexInfo, changed bool) {
+	if field.PrimaryKey {
+		return nil, false
+	}
+
+	// uniqueness declared by unique indexes or constraints of the model
+	if !field.Unique {
+		for _, idx := range sortedIndexes(m.parseIndexes(stmt)) {
+			if strings.ToUpper(idx.Class) == "UNIQUE" && len(idx.Fields) == 1 && idx.Fields[0].Field == field {
+				return nil, false
+			}
+		}
+
+		for _, unique := range sortedUniqueConstraints(stmt.Schema.ParseUniqueConstraints()) {
+			if len(unique.Fields) == 1 && unique.Fields[0] == field {
+				return nil, false
+			}
+		}
+	}
+
+	for idx := range indexes {
+		if index := indexes[idx]; index.Unique && index.Where == "" && len(index.Columns) == 1 && index.Columns[0] == field.DBName {
+			if field.Unique {
+				return &index, false
+			}
+
+			switch index.Name {
+			case schema.UniqueNameOf(m.DB.NamingStrategy, stmt.Table, field.DBName), stmt.Table + "_" + field.DBName + "_key", field.DBName:
+				return &index, true
+			}
+
+			if strings.HasPrefix(index.Name, "sqlite_autoindex_") {
+				live = &index
+			}
+		}
+	}
+	return live, field.Unique || live != nil
+}
+
+func (m Migrator) MigrateColumn(value interface{}, field *schema.Field, columnType *sql.ColumnType) error {
+	if m.columnTypeChanged(field, columnType) {
+		return m.migratorOf(m.DB).AlterColumn(value, field.DBName)
+	}
+	return nil
+}
+
+func (m Migrator) columnTypeChanged(field *schema.Field, columnType *sql.ColumnType) bool {
+	var (
+		declaredType = m.normalizeDataType(m.DataTypeOf(field))
+		realType     = m.normalizeDataType(columnType.DatabaseTypeName())
+		alterColumn  = declaredType != "" && realType != "" && declaredType != realType
+		explicitType = field.DBDataType != "" || !isBuiltinDataType(field.DataType)
+	)
+
+	// explicit or custom data types are authoritative, databases might reflect them without qualifiers
+	if alterColumn && explicitType && strings.HasPrefix(declaredType, realType+" ") {
+		alterColumn = false
+	}
+
+	// user-defined types are reflected as their base type or oid, e.g: domain `email` => `text`
+	if alterColumn && (field.DBDataType != "" || len(field.EnumValues) > 0) && !strings.Contains(declaredType, " ") && m.typesOf(m.migratorOf(m.DB)).HasType(declaredType) {
+		alterColumn = false
+	}
+
+	if length, ok := columnType.Length(); ok && !alterColumn && field.DataType == schema.String && field.Size > 0 && length > 0 && length != int64(field.Size) {
+		alterColumn = true
+	}
+
+	if !alterColumn && declaredType == "numeric" {
+		declaredPrecision, declaredScale, declaredOk := m.declaredDecimalSize(field)
+		precision, scale, ok := decimalSizeOf(columnType)
+		alterColumn = declaredOk && ok && (declaredPrecision != precision || (declaredScale >= 0 && declaredScale != scale))
+	}
+	return alterColumn
+}
+
+var decimalSizeRegexp = regexp.MustCompile(`^\s*\w+\s*\(\s*(\d+)\s*(?:,\s*(\d+)\s*)?\)`)
+
+// parseDecimalSize parse precision and scale of decimal types, e.g: decimal(10,2), scale is -1 if omitted
+func parseDecimalSize(dataType string) (precision, scale int64, ok bool) {
+	matches := decimalSizeRegexp.FindStringSubmatch(dataType)
+	if len(matches) != 3 {
+		return 0, 0, false
+	}
+
+	precision, _ = strconv.ParseInt(matches[1], 10, 64)
+	scale = -1
+	if matches[2] != "" {
+		scale, _ = strconv.ParseInt(matches[2], 10, 64)
+	}
+	return precision, scale, true
+}
+
+// declaredDecimalSize precision and scale of decimal field from its data type or precision tag, scale is -1 if only precision is tagged
+func (m Migrator) declaredDecimalSize(field *schema.Field) (precision, scale int64, ok bool) {
+	if precision, scale, ok = parseDecimalSize(m.DataTypeOf(field)); ok {
+		if scale < 0 {
+			scale = 0
+		}
+		return
+	}
+
+	if field.Precision > 0 {
+		return int64(field.Precision), -1, true
+	}
+	return 0, 0, false
+}
+
+// decimalSizeOf reflected precision and scale of decimal columns, sqlite reports them in the type name only, e.g: decimal(10,2)
+func decimalSizeOf(columnType *sql.ColumnType) (precision, scale int64, ok bool) {
+	if precision, scale, ok = columnType.DecimalSize(); ok && precision > 0 {
+		return
+	}
+
+	if precision, scale, ok = parseDecimalSize(columnType.DatabaseTypeName()); ok && scale < 0 {
+		scale = 0
+	}
+	return
+}
+
+type GenerationExpressionInterface interface {
+	GenerationExpressionOf(value interface{}, name string) (string, error)
+}
+
+// generationExpressionsOf generation expression reflection of the dialect migrator, migrators not embedding Migrator use m
+func (m Migrator) generationExpressionsOf(migrator gorm.Migrator) GenerationExpressionInterface {
+	if reflector, ok := migrator.(GenerationExpressionInterface); ok {
+		return reflector
+	}
+	return m
+}
+
+func (m Migrator) GenerationExpressionOf(value interface{}, name string) (string, error) {
+	return "", gorm.ErrNotImplemented
+}
+
+type GenerationStoredInterface interface {
+	GenerationStoredOf(value interface{}, name string) (bool, error)
+}
+
+// generationStoragesOf stored generated column reflection of the dialect migrator, migrators not embedding Migrator use m
+func (m Migrator) generationStoragesOf(migrator gorm.Migrator) GenerationStoredInterface {
+	if reflector, ok := migrator.(GenerationStoredInterface); ok {
+		return reflector
+	}
+	return m
+}
+
+// GenerationStoredOf reflect whether the generated column is STORED
+func (m Migrator) GenerationStoredOf(value interface{}, name string) (bool, error) {
+	return false, gorm.ErrNotImplemented
+}
+
+type ColumnDefaultInterface interface {
+	ColumnDefaultOf(value interface{}, name string) (string, error)
+}
+
+// columnDefaultsOf column default reflection of the dialect migrator, migrators not embedding Migrator use m
+func (m Migrator) columnDefaultsOf(migrator gorm.Migrator) ColumnDefaultInterface {
+	if reflector, ok := migrator.(ColumnDefaultInterface); ok {
+		return reflector
+	}
+	return m
+}
+
+// SequenceInterface dialects implement it to manage sequences generating column values
+type SequenceInterface interface {
+	SetColumnDefaultSequence(value interface{}, column, sequence string) error
+	ColumnSequenceOf(value interface{}, column string) (string, error)
+	GetSequenceValue(name string) (int64, error)
+	SyncColumnSequence(value interface{}, column string) error
+}
+
+// SetColumnDefaultSequence make column default to the next value of the sequence (Postgres)
+func (m Migrator) SetColumnDefaultSequence(value interface{}, column, sequence string) error {
+	return gorm.ErrNotImplemented
+}
+
+// ColumnSequenceOf reflect sequence owned by the column, e.g: public.users_id_seq of serial columns, empty if the column owns none (Postgres)
+func (m Migrator) ColumnSequenceOf(value interface{}, column string) (string, error) {
+	return "", gorm.ErrNotImplemented
+}
+
+// GetSequenceValue reflect last value of the sequence, e.g: SELECT last_value FROM ? (Postgres)
+func (m Migrator) GetSequenceValue(name string) (int64, error) {
+	return 0, gorm.ErrNotImplemented
+}
+
+// SyncColumnSequence restart sequence owned by the column above the column's max value (Postgres)
+func (m Migrator) SyncColumnSequence(value interface{}, column string) error {
+	return gorm.ErrNotImplemented
+}
+
+// ColumnDefaultOf reflect column default expression, empty if the column has no default
+func (m Migrator) ColumnDefaultOf(value interface{}, name string) (string, error) {
+	return "", gorm.ErrNotImplemented
+}
+
+// HasDefault check whether the column has a default value, returns error if the column doesn't exist
+func (m Migrator) HasDefault(value interface{}, column string) (bool, error) {
+	defaultValue, err := m.columnDefaultsOf(m.migratorOf(m.DB)).ColumnDefaultOf(value, column)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			return fmt.Errorf("failed to check default of %v.%v, column not found", stmt.Table, column)
+		})
+	}
+	return defaultValue != "", err
+}
+
+// AlterColumnDefaultInterface dialects implement it to change column defaults in place
+type AlterColumnDefaultInterface interface {
+	AlterColumnDefault(stmt *gorm.Statement, field *schema.Field) error
+}
+
+// alterColumnDefault change column default, dialects without AlterColumnDefaultInterface redefine the column
+func (m Migrator) alterColumnDefault(tx *gorm.DB, value interface{}, stmt *gorm.Statement, field *schema.Field) error {
+	if alterer, ok := m.migratorOf(m.DB).(AlterColumnDefaultInterface); ok {
+		return alterer.AlterColumnDefault(stmt, field)
+	}
+	return m.migratorOf(tx).AlterColumn(value, field.DBName)
+}
+
+var (
+	defaultValueCastRegexp      = regexp.MustCompile(`::[a-z ]+(\[\])?$`)
+	defaultValueTimestampRegexp = regexp.MustCompile(`^(current_timestamp|now|localtimestamp)(\s*\(\s*\d*\s*\))?$`)
+)
+
+// normalizeDefaultValue normalize default expression for comparison, e.g: `'jinzhu'::character varying` => `jinzhu`
+func normalizeDefaultValue(field *schema.Field, value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for wrappedInParentheses(value) {
+		value = strings.TrimSpace(value[1 : len(value)-1])
+	}
+
+	value = defaultValueCastRegexp.ReplaceAllString(value, "")
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+
+	if field.DataType == schema.Time && defaultValueTimestampRegexp.MatchString(value) {
+		return "current_timestamp"
+	}
+
+	if field.DataType == schema.Bool {
+		switch value {
+		case "1":
+			return "true"
+		case "0":
+			return "false"
+		}
+	}
+	return value
+}
+
+type ColumnStorageInterface interface {
+	ColumnStorageOf(value interface{}, name string) (string, error)
+	SetColumnStorage(value interface{}, column, strategy string) error
+}
+
+// columnStoragesOf column storage reflection of the dialect migrator, migrators not embedding Migrator use m
+func (m Migrator) columnStoragesOf(migrator gorm.Migrator) ColumnStorageInterface {
+	if reflector, ok := migrator.(ColumnStorageInterface); ok {
+		return reflector
+	}
+	return m
+}
+
+// EnumInterface dialects implement it to migrate fields with enum values to native enum types, other dialects use check constraints
+type EnumInterface interface {
+	EnumTypeOf(field *schema.Field) string
+	MigrateEnum(value interface{}, field *schema.Field) error
+}
+
+// enumsOf native enum types of the dialect, nil if enum values are migrated to check constraints
+func (m Migrator) enumsOf(migrator gorm.Migrator) EnumInterface {
+	if enum, ok := migrator.(EnumInterface); ok {
+		return enum
+	}
+	return nil
+}
+
+// parseCheckConstraints check constraints of model, dialects with native enum types use them instead of check constraints for enum values
+func (m Migrator) parseCheckConstraints(stmt *gorm.Statement) map[string]schema.Check {
+	checks := stmt.Schema.ParseCheckConstraints()
+	if m.enumsOf(m.migratorOf(m.DB)) != nil {
+		for name, chk := range checks {
+			if chk.Enum && !chk.Field.EnumCheck {
+				delete(checks, name)
+			}
+		}
+	}
+	return checks
+}
+
+// ColumnStorageOf reflect column storage strategy, e.g: EXTENDED (Postgres)
+func (m Migrator) ColumnStorageOf(value interface{}, name string) (string, error) {
+	return "", gorm.ErrNotImplemented
+}
+
+// SetColumnStorage set column storage strategy, e.g: ALTER TABLE ? ALTER COLUMN ? SET STORAGE EXTERNAL (Postgres)
+func (m Migrator) SetColumnStorage(value interface{}, column, strategy string) error {
+	return gorm.ErrNotImplemented
+}
+
+// ColumnCommentNamespace prefix of comments managed by the migrator, e.g: `gorm:"comment:gorm:schema_version=3f2a"`
+const ColumnCommentNamespace = "gorm:"
+
+// ColumnMetaNamespace prefix of the comment line carrying metadata of the field, e.g: gorm:meta:pii=true,retention=30d
+const ColumnMetaNamespace = ColumnCommentNamespace + "meta:"
+
+// commentOf column comment of the field, metadata is appended as a line of ColumnMetaNamespace
+func (m Migrator) commentOf(field *schema.Field) string {
+	if len(field.Meta) == 0 {
+		return field.Comment
+	}
+
+	keys := make([]string, 0, len(field.Meta))
+	for key := range field.Meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for idx, key := range keys {
+		pairs[idx] = key + "=" + field.Meta[key]
+	}
+
+	meta := ColumnMetaNamespace + strings.Join(pairs, ",")
+	if field.Comment == "" {
+		return meta
+	}
+	return field.Comment + "\n" + meta
+}
+
+// ColumnMetaOf reflect metadata of column from the ColumnMetaNamespace line of its comment, e.g: {"pii": "true"}
+func (m Migrator) ColumnMetaOf(value interface{}, name string) (map[string]string, error) {
+	comment, err := m.columnCommentsOf(m.migratorOf(m.DB)).ColumnCommentOf(value, name)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := map[string]string{}
+	for _, line := range strings.Split(comment, "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, ColumnMetaNamespace) {
+			for _, pair := range strings.Split(strings.TrimPrefix(line, ColumnMetaNamespace), ",") {
+				if kv := strings.SplitN(pair, "=", 2); len(kv) == 2 {
+					meta[kv[0]] = kv[1]
+				}
+			}
+		}
+	}
+	return meta, nil
+}
+
+// ColumnCommentInterface dialects implement it to reflect and set column comment
+type ColumnCommentInterface interface {
+	ColumnCommentOf(value interface{}, name string) (string, error)
+	SetColumnComment(value interface{}, column, comment string) error
+}
+
+// columnCommentsOf column comment reflection of the dialect migrator, migrators not embedding Migrator use m
+func (m Migrator) columnCommentsOf(migrator gorm.Migrator) ColumnCommentInterface {
+	if reflector, ok := migrator.(ColumnCommentInterface); ok {
+		return reflector
+	}
+	return m
+}
+
+// ColumnCommentOf reflect column comment, supported by mysql and postgres
+func (m Migrator) ColumnCommentOf(value interface{}, name string) (string, error) {
+	return "", gorm.ErrNotImplemented
+}
+
+// SetColumnComment set column comment, e.g: COMMENT ON COLUMN ?.? IS 'comment' (Postgres), mysql modifies the column with the comment
+func (m Migrator) SetColumnComment(value interface{}, column, comment string) error {
+	return gorm.ErrNotImplemented
+}
+
+// InlineCommentInterface dialects implement it to declare column comments in column definitions, e.g: COMMENT 'comment' (MySQL)
+type InlineCommentInterface interface {
+	InlineCommentOf(comment string) string
+}
+
+// inlineCommentsOf inline comments of the dialect, nil if comments are set after creating columns
+func (m Migrator) inlineCommentsOf() InlineCommentInterface {
+	if commenter, ok := m.migratorOf(m.DB).(InlineCommentInterface); ok {
+		return commenter
+	}
+	return nil
+}
+
+// commentColumn set comment of the created column, unless the dialect declares comments inline or doesn't support them
+func (m Migrator) commentColumn(value interface{}, field *schema.Field) error {
+	comment := m.commentOf(field)
+	if comment == "" || m.inlineCommentsOf() != nil {
+		return nil
+	}
+
+	if err := m.SetColumnComment(value, field.DBName, comment); !errors.Is(err, gorm.ErrNotImplemented) {
+		return err
+	}
+	return nil
+}
+
+// MergeColumnComment merge model comment into live column comment, only namespaced lines replace lines of the live comment
+func (m Migrator) MergeColumnComment(live, comment string) string {
+	if live == "" {
+		return comment
+	}
+
+	lines := strings.Split(live, "\n")
+	for _, line := range strings.Split(comment, "\n") {
+		if !strings.HasPrefix(line, ColumnCommentNamespace) {
+			continue
+		}
+
+		var (
+			merged   []string
+			replaced bool
+			isMeta   = strings.HasPrefix(line, ColumnMetaNamespace)
+		)
+
+		for _, liveLine := range lines {
+			if trimmed := strings.TrimSpace(liveLine); strings.HasPrefix(trimmed, ColumnCommentNamespace) && strings.HasPrefix(trimmed, ColumnMetaNamespace) == isMeta {
+				if !replaced {
+					merged, replaced = append(merged, line), true
+				}
+			} else {
+				merged = append(merged, liveLine)
+			}
+		}
+
+		if !replaced {
+			merged = append(merged, line)
+		}
+		lines = merged
+	}
+	return strings.Join(lines, "\n")
+}
+
+// MigrateGeneratedColumn drop and re-add generated column and its indexes if its generation changed
+func (m Migrator) MigrateGeneratedColumn(value interface{}, field *schema.Field) error {
+	if changed, err := m.generatedColumnChanged(value, field); err != nil || !changed {
+		return err
+	}
+	return m.recreateColumn(value, field)
+}
+
+func (m Migrator) generatedColumnChanged(value interface{}, field *schema.Field) (bool, error) {
+	liveExpression, err := m.generationExpressionsOf(m.migratorOf(m.DB)).GenerationExpressionOf(value, field.DBName)
+	if err != nil || liveExpression == "" {
+		return false, err
+	}
+
+	if normalizeCheckConstraint(liveExpression) != normalizeCheckConstraint(field.GeneratedExpression) {
+		return true, nil
+	}
+
+	// switching between STORED and VIRTUAL requires recreating the column
+	stored, err := m.generationStoragesOf(m.migratorOf(m.DB)).GenerationStoredOf(value, field.DBName)
+	if errors.Is(err, gorm.ErrNotImplemented) {
+		return false, nil
+	}
+	return stored != field.GeneratedStored, err
+}
+
+// recreateColumn drop and re-add column, and recreate indexes on it
+func (m Migrator) recreateColumn(value interface{}, field *schema.Field) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		var indexes []schema.Index
+		for _, idx := range sortedIndexes(m.parseIndexes(stmt)) {
+			for _, opt := range idx.Fields {
+				if opt.Field == field && m.migratorOf(m.DB).HasIndex(value, idx.Name) {
+					if err := m.migratorOf(m.DB).DropIndex(value, idx.Name); err != nil {
+						return err
+					}
+					indexes = append(indexes, idx)
+					break
+				}
+			}
+		}
+
+		if err := m.migratorOf(m.DB).DropColumn(value, field.DBName); err != nil {
+			return err
+		}
+
+		if err := m.migratorOf(m.DB).AddColumn(value, field.DBName); err != nil {
+			return err
+		}
+
+		for _, idx := range indexes {
+			if err := m.createIndex(m.DB, stmt, value, idx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+const (
+	ColumnChangeSafe        = "safe"        // widening, e.g: integer => bigint, varchar(100) => text
+	ColumnChangeLossy       = "lossy"       // values are kept but semantics change, e.g: integer => text, numeric => double precision
+	ColumnChangeDestructive = "destructive" // values might be truncated or fail to convert, e.g: bigint => integer, varchar(100) => varchar(50), text => integer
+)
+
+// ColumnChange column type change proposed by AutoMigrate
+type ColumnChange struct {
+	Table  string
+	Column string
+	From   string
+	To     string
+	Risk   string
+}
+
+// dataTypeFamilies family and rank of normalized data types, a lower rank in same family holds less values
+var dataTypeFamilies = map[string]struct {
+	family string
+	rank   int
+}{
+	"boolean": {"integer", 0}, "tinyint": {"integer", 0}, "bit": {"integer", 0}, "smallint": {"integer", 1}, "mediumint": {"integer", 2}, "integer": {"integer", 3}, "bigint": {"integer", 4},
+	"real": {"float", 1}, "float": {"float", 1}, "double precision": {"float", 2}, "numeric": {"numeric", 1},
+	"char": {"string", 1}, "nchar": {"string", 1}, "varchar": {"string", 2}, "nvarchar": {"string", 2}, "tinytext": {"string", 2}, "text": {"string", 3}, "mediumtext": {"string", 4}, "longtext": {"string", 5},
+	"date": {"time", 1}, "datetime": {"time", 2}, "timestamp": {"time", 2}, "timestamptz": {"time", 2}, "datetimeoffset": {"time", 2},
+	"varbinary": {"binary", 1}, "tinyblob": {"binary", 1}, "blob": {"binary", 2}, "bytea": {"binary", 2}, "mediumblob": {"binary", 3}, "longblob": {"binary", 4},
+}
+
+// columnChangeRisk classify column type change as safe, lossy or destructive
+func (m Migrator) columnChangeRisk(field *schema.Field, columnType *sql.ColumnType) string {
+	from, fromOk := dataTypeFamilies[m.normalizeDataType(columnType.DatabaseTypeName())]
+	to, toOk := dataTypeFamilies[m.normalizeDataType(m.DataTypeOf(field))]
+
+	switch {
+	case !fromOk || !toOk:
+		return ColumnChangeLossy
+	case from.family != to.family:
+		switch {
+		case to.family == "string", from.family == "integer" && to.family == "numeric":
+			return ColumnChangeLossy
+		case (from.family == "integer" || from.family == "numeric") && to.family == "float", from.family == "float" && to.family == "numeric":
+			return ColumnChangeLossy
+		}
+		return ColumnChangeDestructive
+	case to.rank < from.rank:
+		return ColumnChangeDestructive
+	}
+
+	if length, ok := columnType.Length(); ok && from.family == "string" && to.rank == from.rank && field.Size > 0 && length > 0 && int64(field.Size) < length {
+		return ColumnChangeDestructive
+	}
+
+	if from.family == "numeric" && to.family == "numeric" {
+		declaredPrecision, declaredScale, declaredOk := m.declaredDecimalSize(field)
+		if precision, scale, ok := decimalSizeOf(columnType); declaredOk && ok {
+			// both scale and integer digits should be kept, e.g: decimal(10,2) => decimal(10,4) loses integer digits
+			if declaredScale < 0 {
+				declaredScale = scale
+			}
+
+			if declaredScale < scale || declaredPrecision-declaredScale < precision-scale {
+				return ColumnChangeDestructive
+			}
+		}
+	}
+	return ColumnChangeSafe
+}
+
+func isBuiltinDataType(dataType schema.DataType) bool {
+	switch dataType {
+	case schema.Bool, schema.Int, schema.Uint, schema.Float, schema.String, schema.Time, schema.Bytes:
+		return true
+	}
+	return false
+}
+
+var (
+	// names of the same data type, types holding different values aren't aliases, e.g: tinyint, bit or longtext
+	dataTypeAliases = map[string]string{
+		"int8": "bigint", "bigserial": "bigint", "int": "integer", "int4": "integer", "serial": "integer",
+		"int2": "smallint", "smallserial": "smallint", "bool": "boolean",
+		"decimal": "numeric", "float8": "double precision", "double": "double precision", "float4": "real",
+		"character varying": "varchar", "character": "char", "bpchar": "char",
+		"timestamp with time zone": "timestamptz", "timestamp without time zone": "timestamp",
+	}
+	dataTypeModifiers = map[string]bool{
+		"primary": true, "not": true, "null": true, "default": true, "unique": true, "auto_increment": true,
+		"autoincrement": true, "identity": true, "collate": true, "check": true, "references": true, "comment": true,
+	}
+	dataTypeSizeRegexp = regexp.MustCompile(`\([^)]*\)`)
+)
+
+// NormalizeDataTypeInterface dialects implement it to normalize data types with their aliases, e.g: boolean => tinyint (MySQL)
+type NormalizeDataTypeInterface interface {
+	NormalizeDataType(dataType string) string
+}
+
+// normalizeDataType normalize data type with aliases of the dialect
+func (m Migrator) normalizeDataType(dataType string) string {
+	dataType = normalizeDataType(dataType)
+	if normalizer, ok := m.migratorOf(m.DB).(NormalizeDataTypeInterface); ok {
+		return normalizer.NormalizeDataType(dataType)
+	}
+	return dataType
+}
+
+// normalizeDataType normalize data type to compare declared type with reflected ones, e.g: `INT8` => `bigint`
+func normalizeDataType(dataType string) string {
+	dataType = dataTypeSizeRegexp.ReplaceAllString(strings.ToLower(strings.TrimSpace(dataType)), "")
+
+	var isArray bool
+	if strings.HasPrefix(dataType, "_") {
+		dataType, isArray = strings.TrimPrefix(dataType, "_"), true
+	} else if idx := strings.Index(dataType, "[]"); idx != -1 {
+		dataType, isArray = dataType[:idx], true
+	}
+
+	var words []string
+	for _, word := range strings.Fields(dataType) {
+		if dataTypeModifiers[word] && len(words) > 0 {
+			break
+		} else if word != "unsigned" && word != "zerofill" {
+			words = append(words, word)
+		}
+	}
+
+	dataType = strings.Join(words, " ")
+	if alias, ok := dataTypeAliases[dataType]; ok {
+		dataType = alias
+	}
+
+	if isArray {
+		dataType += "[]"
+	}
+	return dataType
+}
+
+// ColumnOrderInterface dialects implement it to reflect column names ordered by ordinal position
+type ColumnOrderInterface interface {
+	GetColumnOrder(value interface{}) ([]string, error)
+}
+
+// GetColumnOrder reflect column names ordered by ordinal position
+func (m Migrator) GetColumnOrder(value interface{}) ([]string, error) {
+	return nil, gorm.ErrNotImplemented
+}
+
+// columnOrdersOf column order reflection of the dialect migrator, migrators not embedding Migrator use m
+func (m Migrator) columnOrdersOf(migrator gorm.Migrator) ColumnOrderInterface {
+	if reflector, ok := migrator.(ColumnOrderInterface); ok {
+		return reflector
+	}
+	return m
+}
+
+// column orders of Config.ColumnOrder
+const (
+	ColumnOrderAsDefined    = ""             // order of struct fields
+	ColumnOrderPKFirst      = "pk-first"     // primary keys first, others in order of struct fields
+	ColumnOrderAlphabetical = "alphabetical" // all columns sorted by name
+)
+
+// columnOrderOf columns of the model in order of Config.ColumnOrder
+func (m Migrator) columnOrderOf(stmt *gorm.Statement) ([]string, error) {
+	dbNames := append([]string{}, stmt.Schema.DBNames...)
+	switch m.ColumnOrder {
+	case ColumnOrderAsDefined:
+	case ColumnOrderPKFirst:
+		sort.SliceStable(dbNames, func(i, j int) bool {
+			return stmt.Schema.FieldsByDBName[dbNames[i]].PrimaryKey && !stmt.Schema.FieldsByDBName[dbNames[j]].PrimaryKey
+		})
+	case ColumnOrderAlphabetical:
+		sort.Strings(dbNames)
+	default:
+		return nil, fmt.Errorf("unsupported column order %v", m.ColumnOrder)
+	}
+	return dbNames, nil
+}
+
+// ColumnPositionInterface dialects implement it to move columns, e.g: AFTER ? (MySQL), columns are moved first if after is blank
+type ColumnPositionInterface interface {
+	MoveColumn(stmt *gorm.Statement, name, after string) error
+}
+
+// reorderColumns move columns to the order of model fields, returns gorm.ErrNotImplemented if the dialect can't move columns
+func (m Migrator) reorderColumns(value interface{}, stmt *gorm.Statement, record func(typ, name string)) error {
+	mover, ok := m.migratorOf(m.DB).(ColumnPositionInterface)
+	if !ok {
+		return gorm.ErrNotImplemented
+	}
+
+	columns, err := m.columnOrdersOf(m.migratorOf(m.DB)).GetColumnOrder(value)
+	if err != nil {
+		return err
+	}
+
+	var live, expected []string
+	for _, column := range columns {
+		if _, ok := stmt.Schema.FieldsByDBName[column]; ok {
+			live = append(live, column)
+		}
+	}
+
+	dbNames, err := m.columnOrderOf(stmt)
+	if err != nil {
+		return err
+	}
+
+	for _, dbName := range dbNames {
+		for _, column := range live {
+			if column == dbName {
+				expected = append(expected, dbName)
+				break
+			}
+		}
+	}
+
+	for idx, dbName := range expected {
+		if live[idx] == dbName {
+			continue
+		}
+
+		var after string
+		if idx > 0 {
+			after = expected[idx-1]
+		}
+
+		if err := mover.MoveColumn(stmt, dbName, after); err != nil {
+			return err
+		}
+		record("reorder_column", dbName)
+
+		for i := idx + 1; i < len(live); i++ {
+			if live[i] == dbName {
+				copy(live[idx+1:i+1], live[idx:i])
+				live[idx] = dbName
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// ColumnTypes column types of table or view, e.g: ColumnTypes("user_views")
+func (m Migrator) ColumnTypes(value interface{}) (columnTypes []*sql.ColumnType, err error) {
+	err = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		rows, err := m.DB.Raw("select * from ?", m.CurrentTable(stmt)).Rows()
+		if err == nil {
+			defer rows.Close()
+			columnTypes, err = rows.ColumnTypes()
+		}
+		return err
+	})
+	return
+}
+
+// HasColumnType check column's current type equals the data type after normalizing, e.g: `int8` equals `bigint`
+func (m Migrator) HasColumnType(value interface{}, column, dataType string) (has bool, err error) {
+	err = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if field := stmt.Schema.LookUpField(column); field != nil {
+			column = field.DBName
+		}
+
+		columnTypes, err := m.migratorOf(m.DB).ColumnTypes(value)
+		for _, columnType := range columnTypes {
+			if columnType.Name() == column {
+				has = m.normalizeDataType(columnType.DatabaseTypeName()) == m.normalizeDataType(dataType)
+				break
+			}
+		}
+		return err
+	})
+	return
+}
+
+// ViewReplaceInterface dialects implement it to replace views with their own statement, e.g: CREATE OR ALTER VIEW ? AS ? (SQL Server)
+type ViewReplaceInterface interface {
+	ReplaceViewSQL(name string) (string, error)
+}
+
+// CreateView create view with the query, vars of the query are inlined as views can't be created with bind vars
+func (m Migrator) CreateView(name string, option gorm.ViewOption) error {
+	if option.Query == nil {
+		return errors.New("query is required to create view")
+	}
+
+	query := option.Query.Session(&gorm.Session{Context: option.Query.Statement.Context, DryRun: true, WithConditions: true})
+	query.Dialector = inlineDialector{Dialector: m.Dialector, m: m}
+	query.Statement.SQL = strings.Builder{}
+	query.Statement.Vars = nil
+	query.Callback().Query().Execute(query)
+	if query.Error != nil {
+		return query.Error
+	}
+
+	createViewSQL := "CREATE VIEW ? AS ?"
+	if replacer, ok := m.migratorOf(m.DB).(ViewReplaceInterface); ok && option.Replace {
+		var err error
+		if createViewSQL, err = replacer.ReplaceViewSQL(name); err != nil {
+			return err
+		}
+	} else if option.Replace {
+		createViewSQL = "CREATE OR REPLACE VIEW ? AS ?"
+	}
+
+	if option.CheckOption != "" {
+		createViewSQL += " " + option.CheckOption
+	}
+
+	return m.execDDL(createViewSQL, clause.Table{Name: name}, clause.Expr{SQL: query.Statement.SQL.String()})
+}
+
+func (m Migrator) DropView(name string) error {
+	return m.execDDL("DROP VIEW IF EXISTS ?", clause.Table{Name: name})
+}
+
+// TypeOption user-defined type option, e.g: {Definition: "(x integer, y integer)"}
+type TypeOption struct {
+	Domain     bool
+	Definition string
+}
+
+// TypeInterface dialects implement it to manage user-defined types
+type TypeInterface interface {
+	CreateType(name string, option TypeOption) error
+	DropType(name string) error
+	HasType(name string) bool
+}
+
+// CreateType create user-defined type, e.g: CREATE TYPE ? AS (x integer, y integer), CREATE DOMAIN ? AS text (Postgres)
+func (m Migrator) CreateType(name string, option TypeOption) error {
+	return gorm.ErrNotImplemented
+}
+
+func (m Migrator) DropType(name string) error {
+	return gorm.ErrNotImplemented
+}
+
+func (m Migrator) HasType(name string) bool {
+	return false
+}
+
+// typesOf user-defined types of the dialect migrator, migrators not embedding Migrator use m
+func (m Migrator) typesOf(migrator gorm.Migrator) TypeInterface {
+	if reflector, ok := migrator.(TypeInterface); ok {
+		return reflector
+	}
+	return m
+}
+
+// ConstraintClausesInterface dialects implement it to append their options to constraints, e.g: DEFERRABLE (Postgres), blank if none
+type ConstraintClausesInterface interface {
+	PrimaryKeyClausesOf(fields []*schema.Field) string
+	ForeignKeyClausesOf(constraint *schema.Constraint) string
+	CheckClausesOf(chk schema.Check) string
+	UniqueClausesOf(unique schema.UniqueConstraint) string
+}
+
+// ForeignKeyIndexInterface dialects implement it if they create backing indexes of foreign keys themselves
+type ForeignKeyIndexInterface interface {
+	ForeignKeyIndexOf(constraint *schema.Constraint, foreignKeys []interface{}) (sql string, vars []interface{})
+}
+
+// buildConstraint build foreign key constraint, options of the dialect are appended, e.g: NOT ENFORCED (Postgres 18+)
+func (m Migrator) buildConstraint(constraint *schema.Constraint) (sql string, results []interface{}) {
+	var foreignKeys, references []interface{}
+	for _, field := range constraint.ForeignKeys {
+		foreignKeys = append(foreignKeys, clause.Column{Name: field.DBName})
+	}
+
+	for _, field := range constraint.References {
+		references = append(references, clause.Column{Name: field.DBName})
+	}
+
+	foreignKeySQL, foreignKeyVars := "?", []interface{}{foreignKeys}
+	if indexer, ok := m.migratorOf(m.DB).(ForeignKeyIndexInterface); ok {
+		foreignKeySQL, foreignKeyVars = indexer.ForeignKeyIndexOf(constraint, foreignKeys)
+	}
+
+	sql = "CONSTRAINT ? FOREIGN KEY " + foreignKeySQL + " REFERENCES ??"
+	if constraint.OnDelete != "" {
+		sql += " ON DELETE " + constraint.OnDelete
+	}
+
+	if constraint.OnUpdate != "" {
+		sql += " ON UPDATE  " + constraint.OnUpdate
+	}
+
+	if clauses, ok := m.migratorOf(m.DB).(ConstraintClausesInterface); ok {
+		sql += clauses.ForeignKeyClausesOf(constraint)
+	}
+
+	results = append(results, clause.Table{Name: constraint.Name})
+	results = append(results, foreignKeyVars...)
+	results = append(results, m.qualifiedTable(constraint.ReferenceSchema.Table), references)
+	return
+}
+
+// createForeignKeyIndex create backing index of foreign key not covered by other indexes if CreateForeignKeyIndexes is set
+func (m Migrator) createForeignKeyIndex(value interface{}, stmt *gorm.Statement, constraint *schema.Constraint) error {
+	if _, ok := m.migratorOf(m.DB).(ForeignKeyIndexInterface); !m.CreateForeignKeyIndexes || ok {
+		return nil
+	}
+
+	var foreignKeys []string
+	for _, field := range constraint.ForeignKeys {
+		foreignKeys = append(foreignKeys, field.DBName)
+	}
+
+	for _, idx := range sortedIndexes(m.parseIndexes(stmt)) {
+		var columns []string
+		for _, opt := range idx.Fields {
+			if opt.Field != nil {
+				columns = append(columns, opt.DBName)
+			}
+		}
+
+		if indexLeadsWith(columns, foreignKeys) {
+			return nil
+		}
+	}
+
+	if covered, err := m.HasIndexColumns(value, foreignKeys...); err != nil && !errors.Is(err, gorm.ErrNotImplemented) {
+		return err
+	} else if covered {
+		return nil
+	}
+
+	var (
+		names   []string
+		columns []interface{}
+	)
+	for _, field := range constraint.ForeignKeys {
+		names = append(names, field.Name)
+		columns = append(columns, clause.Column{Name: field.DBName})
+	}
+
+	name := constraint.IndexName
+	if name == "" {
+		name = m.DB.NamingStrategy.IndexName(stmt.Table, strings.Join(names, ""))
+	}
+
+	if m.migratorOf(m.DB).HasIndex(value, name) {
+		return nil
+	}
+	return m.execDDL("CREATE INDEX ? ON ??", clause.Column{Name: name}, m.CurrentTable(stmt), columns)
+}
+
+// buildCheckConstraint build check constraint, options of the dialect are appended
+func (m Migrator) buildCheckConstraint(chk schema.Check) (sql string, results []interface{}) {
+	sql = "CONSTRAINT ? CHECK (?)"
+	if clauses, ok := m.migratorOf(m.DB).(ConstraintClausesInterface); ok {
+		sql += clauses.CheckClausesOf(chk)
+	}
+
+	results = append(results, clause.Column{Name: chk.Name}, m.checkExpressionOf(chk))
+	return
+}
+
+// checkExpressionOf expression of the check constraint, columns of enum checks are quoted
+func (m Migrator) checkExpressionOf(chk schema.Check) clause.Expr {
+	if chk.Enum {
+		return clause.Expr{SQL: m.DB.Statement.Quote(clause.Column{Name: chk.Field.DBName}) + strings.TrimPrefix(chk.Constraint, chk.Field.DBName)}
+	}
+	return clause.Expr{SQL: chk.Constraint}
+}
+
+// CheckViolationError existing rows violate the check constraint to be added, returned when ValidateCheckConstraints is set
+type CheckViolationError struct {
+	Table      string
+	Name       string
+	Constraint string
+	Rows       int64
+}
+
+func (err CheckViolationError) Error() string {
+	return fmt.Sprintf("%d rows of %v violate check constraint %v: %v", err.Rows, err.Table, err.Name, err.Constraint)
+}
+
+// validateCheckConstraint count existing rows violating the check constraint before adding it, rows evaluating it to NULL pass the check
+func (m Migrator) validateCheckConstraint(stmt *gorm.Statement, chk schema.Check) error {
+	if !m.ValidateCheckConstraints || chk.NotEnforced {
+		return nil
+	}
+
+	var count int64
+	if err := m.DB.Raw("SELECT count(*) FROM ? WHERE NOT (?)", m.CurrentTable(stmt), m.checkExpressionOf(chk)).Row().Scan(&count); err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return CheckViolationError{Table: stmt.Table, Name: chk.Name, Constraint: chk.Constraint, Rows: count}
+	}
+	return nil
+}
+
+// buildUniqueConstraint build unique constraint, options of the dialect are appended, e.g: DEFERRABLE (Postgres)
+func (m Migrator) buildUniqueConstraint(unique schema.UniqueConstraint) (sql string, results []interface{}) {
+	sql = "CONSTRAINT ? UNIQUE ?"
+	if clauses, ok := m.migratorOf(m.DB).(ConstraintClausesInterface); ok {
+		sql += clauses.UniqueClausesOf(unique)
+	}
+
+	var columns []interface{}
+	for _, field := range unique.Fields {
+		columns = append(columns, clause.Column{Name: field.DBName})
+	}
+	results = append(results, clause.Column{Name: unique.Name}, columns)
+	return
+}
+
+func (m Migrator) CreateConstraint(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		checkConstraints := m.parseCheckConstraints(stmt)
+		if chk, ok := checkConstraints[name]; ok {
+			if err := m.validateCheckConstraint(stmt, chk); err != nil {
+				return err
+			}
+
+			sql, values := m.buildCheckConstraint(chk)
+			return m.execDDL("ALTER TABLE ? ADD "+sql, append([]interface{}{m.CurrentTable(stmt)}, values...)...)
+		}
+
+		if unique, ok := stmt.Schema.ParseUniqueConstraints()[name]; ok {
+			sql, values := m.buildUniqueConstraint(unique)
+			return m.execDDL("ALTER TABLE ? ADD "+sql, append([]interface{}{m.CurrentTable(stmt)}, values...)...)
+		}
+
+		for _, rel := range sortedRelations(stmt.Schema) {
+			if constraint := rel.ParseConstraint(); constraint != nil && constraint.Name == name {
+				sql, values := m.buildConstraint(constraint)
+				if m.addForeignKeysNotValid() {
+					// existing rows are checked later by ValidatePendingConstraints without blocking writes
+					sql += " " + m.migratorOf(m.DB).(NotValidConstraintInterface).NotValidSQL()
+				}
+
+				if err := m.execDDL("ALTER TABLE ? ADD "+sql, append([]interface{}{m.CurrentTable(stmt)}, values...)...); err != nil {
+					return err
+				}
+
+				if m.addForeignKeysNotValid() {
+					if err := m.recordPendingConstraint(stmt, constraint.Name); err != nil {
+						return err
+					}
+				}
+				return m.createForeignKeyIndex(value, stmt, constraint)
+			}
+		}
+
+		err := fmt.Errorf("failed to create constraint with name %v", name)
+		if field := stmt.Schema.LookUpField(name); field != nil {
+			for _, cc := range sortedChecks(checkConstraints) {
+				if err = m.migratorOf(m.DB).CreateIndex(value, cc.Name); err != nil {
+					return err
+				}
+			}
+
+			for _, rel := range sortedRelations(stmt.Schema) {
+				if constraint := rel.ParseConstraint(); constraint != nil && constraint.Field == field {
+					if err = m.migratorOf(m.DB).CreateIndex(value, constraint.Name); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		return err
+	})
+}
+
+// CreateConstraints create model's foreign key, check and unique constraints not exist yet
+func (m Migrator) CreateConstraints(value interface{}) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		var names []string
+		legacyNames := map[string]string{}
+		for _, rel := range sortedRelations(stmt.Schema) {
+			if constraint := rel.ParseConstraint(); constraint != nil {
+				names = append(names, constraint.Name)
+			}
+		}
+
+		for _, chk := range sortedChecks(m.parseCheckConstraints(stmt)) {
+			names = append(names, chk.Name)
+			legacyNames[chk.Name] = chk.LegacyName
+		}
+
+		for _, unique := range sortedUniqueConstraints(stmt.Schema.ParseUniqueConstraints()) {
+			names = append(names, unique.Name)
+			legacyNames[unique.Name] = unique.LegacyName
+		}
+
+		for _, name := range names {
+			if _, ok := m.liveConstraintName(m.DB, value, name, legacyNames[name]); !ok {
+				if err := m.migratorOf(m.DB).CreateConstraint(value, name); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (m Migrator) DropConstraint(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		return m.execDDL(
+			"ALTER TABLE ? DROP CONSTRAINT ?",
+			m.CurrentTable(stmt), clause.Column{Name: name},
+		)
+	})
+}
+
+// RenameConstraintInterface dialects implement it to rename constraints without RENAME CONSTRAINT, e.g: MySQL
+type RenameConstraintInterface interface {
+	RenameConstraint(value interface{}, oldName, newName string) error
+}
+
+func (m Migrator) RenameConstraint(value interface{}, oldName, newName string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		return m.execDDL(
+			"ALTER TABLE ? RENAME CONSTRAINT ? TO ?",
+			m.CurrentTable(stmt), clause.Column{Name: oldName}, clause.Column{Name: newName},
+		)
+	})
+}
+
+func (m Migrator) HasConstraint(value interface{}, name string) bool {
+	var count int64
+	m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		sql, values := m.reflectionQueries().QueryForConstraintExists(stmt, name)
+		return m.DB.Raw(sql, values...).Row().Scan(&count)
+	})
+
+	return count > 0
+}
+
+// warnConstraintNotReconciled log constraints skipped by dialects creating them with the table only, e.g: SQLite
+func (m Migrator) warnConstraintNotReconciled(stmt *gorm.Statement, name string) {
+	m.DB.Logger.Warn(m.DB.Statement.Context, "constraint %v of %v is not reconciled, %v creates constraints with the table only", name, stmt.Table, m.Dialector.Name())
+}
+
+// liveConstraintName name of the existing constraint, legacy names of embedded structs are found too
+func (m Migrator) liveConstraintName(db *gorm.DB, value interface{}, name, legacyName string) (string, bool) {
+	if m.migratorOf(db).HasConstraint(value, name) {
+		return name, true
+	} else if legacyName != "" && m.migratorOf(db).HasConstraint(value, legacyName) {
+		return legacyName, true
+	}
+	return name, false
+}
+
+func (m Migrator) QueryForConstraintExists(stmt *gorm.Statement, name string) (string, []interface{}) {
+	return "SELECT count(*) FROM INFORMATION_SCHEMA.table_constraints WHERE constraint_schema = ? AND table_name IN ? AND constraint_name IN ?",
+		[]interface{}{m.currentDatabase(), m.identifierCandidates(stmt.Table), m.identifierCandidates(name)}
+}
+
+// ConstraintInfo constraint reflected from database
+type ConstraintInfo struct {
+	Name       string
+	Type       string   // PRIMARY KEY, UNIQUE, FOREIGN KEY, CHECK
+	Definition string   // check expression
+	Columns    []string // columns of foreign keys
+	OnDelete   string
+	OnUpdate   string
+}
+
+// ConstraintsInterface dialects implement it to reflect constraints of tables
+type ConstraintsInterface interface {
+	GetConstraints(value interface{}) ([]ConstraintInfo, error)
+}
+
+func (m Migrator) GetConstraints(value interface{}) ([]ConstraintInfo, error) {
+	return nil, gorm.ErrNotImplemented
+}
+
+// constraintsOf constraint reflection of the dialect migrator, migrators not embedding Migrator use m
+func (m Migrator) constraintsOf(migrator gorm.Migrator) ConstraintsInterface {
+	if reflector, ok := migrator.(ConstraintsInterface); ok {
+		return reflector
+	}
+	return m
+}
+
+// TriggerInfo trigger reflected from database
+type TriggerInfo struct {
+	Name       string
+	Definition string // statement to recreate the trigger, e.g: CREATE TRIGGER ...
+}
+
+// TriggersInterface dialects implement it to reflect triggers of tables with statements to recreate them
+type TriggersInterface interface {
+	GetTriggers(value interface{}) ([]TriggerInfo, error)
+}
+
+// GetTriggers reflect triggers of the table with statements to recreate them
+func (m Migrator) GetTriggers(value interface{}) ([]TriggerInfo, error) {
+	return nil, gorm.ErrNotImplemented
+}
+
+// tableTriggersOf trigger reflection of the dialect migrator, migrators not embedding Migrator use m
+func (m Migrator) tableTriggersOf(migrator gorm.Migrator) TriggersInterface {
+	if reflector, ok := migrator.(TriggersInterface); ok {
+		return reflector
+	}
+	return m
+}
+
+// triggersOf scan names and definitions of triggers from the rows of query
+func (m Migrator) triggersOf(value interface{}, query func(stmt *gorm.Statement) (*sql.Rows, error)) (triggers []TriggerInfo, err error) {
+	err = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		rows, err := query(stmt)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var trigger TriggerInfo
+			if err := rows.Scan(&trigger.Name, &trigger.Definition); err != nil {
+				return err
+			}
+			triggers = append(triggers, trigger)
+		}
+		return rows.Err()
+	})
+	return
+}
+
+// preserveTriggers recreate triggers of the table lost by fc, e.g: sqlite drops triggers when recreating the table to drop or alter columns
+func (m Migrator) preserveTriggers(value interface{}, fc func() error) error {
+	triggers, err := m.tableTriggersOf(m.migratorOf(m.DB)).GetTriggers(value)
+	if errors.Is(err, gorm.ErrNotImplemented) {
+		return fc()
+	} else if err != nil {
+		return err
+	}
+
+	if err := fc(); err != nil || len(triggers) == 0 {
+		return err
+	}
+
+	liveTriggers, err := m.tableTriggersOf(m.migratorOf(m.DB)).GetTriggers(value)
+	if err != nil {
+		return err
+	}
+
+	live := map[string]bool{}
+	for _, trigger := range liveTriggers {
+		live[trigger.Name] = true
+	}
+
+	for _, trigger := range triggers {
+		if !live[trigger.Name] {
+			if err := m.DB.Exec(trigger.Definition).Error; err != nil {
+				return fmt.Errorf("failed to recreate trigger %v: %w", trigger.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// indexTypeChanged compare access method of index, indexes without type use the default btree, fulltext indexes are compared by class
+func indexTypeChanged(live IndexInfo, idx schema.Index) bool {
+	if live.Type == "" || strings.ToUpper(idx.Class) == "FULLTEXT" {
+		return false
+	}
+
+	typ := strings.ToUpper(idx.Type)
+	if typ == "" {
+		typ = "BTREE"
+	}
+	return live.Type != typ
+}
+
+// indexColumnsChanged compare live columns of index with the model, indexes on expressions are taken as unchanged
+func indexColumnsChanged(live IndexInfo, idx schema.Index) bool {
+	if len(live.Columns) != len(idx.Fields) {
+		return true
+	}
+
+	for i, opt := range idx.Fields {
+		if opt.Expression != "" || live.Columns[i] == "" {
+			return false
+		} else if opt.Field == nil || live.Columns[i] != opt.DBName {
+			return true
+		}
+	}
+	return false
+}
+
+// nullsOrderingChanged compare reflected NULLS ordering with declared, postgres defaults to NULLS FIRST for DESC and NULLS LAST otherwise
+func nullsOrderingChanged(live IndexInfo, idx schema.Index) bool {
+	for i, opt := range idx.Fields {
+		if i >= len(live.Nulls) || live.Nulls[i] == "" {
+			continue
+		}
+
+		nulls, sort := opt.Nulls, strings.ToUpper(strings.TrimSpace(opt.Sort))
+		if nulls == "" {
+			if strings.Contains(sort, "NULLS FIRST") {
+				nulls = "FIRST"
+			} else if strings.Contains(sort, "NULLS LAST") || !strings.HasPrefix(sort, "DESC") {
+				nulls = "LAST"
+			} else {
+				nulls = "FIRST"
+			}
+		}
+
+		if live.Nulls[i] != nulls {
+			return true
+		}
+	}
+	return false
+}
+
+var quotedValueRegexp = regexp.MustCompile(`'((?:[^']|'')*)'`)
+
+// enumValuesChanged compare enum values with values quoted in reflected check constraint
+func enumValuesChanged(definition string, values []string) (added, removed []string) {
+	var (
+		liveValues = map[string]bool{}
+		newValues  = map[string]bool{}
+	)
+
+	for _, matches := range quotedValueRegexp.FindAllStringSubmatch(definition, -1) {
+		liveValues[strings.Replace(matches[1], "''", "'", -1)] = true
+	}
+
+	for _, value := range values {
+		if newValues[value] = true; !liveValues[value] {
+			added = append(added, value)
+		}
+	}
+
+	for value := range liveValues {
+		if !newValues[value] {
+			removed = append(removed, value)
+		}
+	}
+	sort.Strings(removed)
+	return
+}
 
-			for _, chk := range stmt.Schema.ParseCheckConstraints() {
-				createTableSQL += "CONSTRAINT ? CHECK ?,"
-				values = append(values, clause.Column{Name: chk.Name}, clause.Expr{SQL: chk.Constraint})
-			}
+// normalizeCheckConstraint normalize check expression to compare the reflected one with the declared one
+func normalizeCheckConstraint(expr string) string {
+	expr = checkCastRegexp.ReplaceAllString(strings.ToLower(expr), "")
+	expr = strings.NewReplacer("`", "", `"`, "", " ", "", "\n", "", "\t", "").Replace(expr)
+	for wrapped := checkColumnRegexp.ReplaceAllString(expr, "$1$2"); wrapped != expr; wrapped = checkColumnRegexp.ReplaceAllString(expr, "$1$2") {
+		expr = wrapped
+	}
 
-			createTableSQL = strings.TrimSuffix(createTableSQL, ",")
+	for wrappedInParentheses(expr) {
+		expr = expr[1 : len(expr)-1]
+	}
+	return expr
+}
 
-			createTableSQL += ")"
+// casts and parentheses around casted columns added by databases to stored expressions, e.g: (name)::text
+var (
+	checkCastRegexp   = regexp.MustCompile(`::(character varying|double precision|bit varying|(timestamp|time) with(out)? time zone|[a-z_][a-z0-9_]*)(\[\])?`)
+	checkColumnRegexp = regexp.MustCompile(`(^|[^a-z0-9_])\(([a-z_][a-z0-9_.]*)\)`)
+)
 
-			if tableOption, ok := m.DB.Get("gorm:table_options"); ok {
-				createTableSQL += fmt.Sprint(tableOption)
-			}
+// wrappedInParentheses whether the whole expression is wrapped by a pair of parentheses, e.g: (a > 0), but not (a > 0) AND (b > 0)
+func wrappedInParentheses(expr string) bool {
+	if len(expr) < 2 || expr[0] != '(' || expr[len(expr)-1] != ')' {
+		return false
+	}
 
-			return tx.Exec(createTableSQL, values...).Error
-		}); err != nil {
-			return err
+	depth := 0
+	for idx, c := range expr {
+		if c == '(' {
+			depth++
+		} else if c == ')' {
+			if depth--; depth == 0 && idx < len(expr)-1 {
+				return false
+			}
 		}
 	}
-	return nil
+	return depth == 0
 }
 
-func (m Migrator) DropTable(values ...interface{}) error {
-	values = m.ReorderModels(values, false)
-	for i := len(values) - 1; i >= 0; i-- {
-		tx := m.DB.Session(&gorm.Session{})
-		if err := m.RunWithValue(values[i], func(stmt *gorm.Statement) error {
-			return tx.Exec("DROP TABLE IF EXISTS ?", clause.Table{Name: stmt.Table}).Error
-		}); err != nil {
-			return err
-		}
+// equalConstraintAction compare reflected referential action with the declared one, blank declaration means database default
+func equalConstraintAction(live, declared string) bool {
+	live, declared = strings.ToUpper(strings.TrimSpace(live)), strings.ToUpper(strings.TrimSpace(declared))
+	if declared == "" {
+		return live == "" || live == "NO ACTION" || live == "RESTRICT"
 	}
-	return nil
+	return live == declared
 }
 
-func (m Migrator) HasTable(value interface{}) bool {
-	var count int64
-
-	m.RunWithValue(value, func(stmt *gorm.Statement) error {
-		currentDatabase := m.DB.Migrator().CurrentDatabase()
-		return m.DB.Raw("SELECT count(*) FROM information_schema.tables WHERE table_schema = ? AND table_name = ? AND table_type = ?", currentDatabase, stmt.Table, "BASE TABLE").Row().Scan(&count)
-	})
+func (m Migrator) BuildIndexOptions(opts []schema.IndexOption, stmt *gorm.Statement) (results []interface{}) {
+	for _, opt := range opts {
+		str := stmt.Quote(opt.DBName)
+		if opt.Expression != "" {
+			str = opt.Expression
+		} else if opt.Length > 0 {
+			str += fmt.Sprintf("(%d)", opt.Length)
+		}
 
-	return count > 0
-}
+		if opt.Collate != "" && (opt.Field == nil || opt.DataType != schema.Bytes) {
+			str += " COLLATE " + m.collationOf(opt.Collate, opt.CollateProvider)
+		}
 
-func (m Migrator) RenameTable(oldName, newName interface{}) error {
-	var oldTable, newTable string
-	if v, ok := oldName.(string); ok {
-		oldTable = v
-	} else {
-		stmt := &gorm.Statement{DB: m.DB}
-		if err := stmt.Parse(oldName); err == nil {
-			oldTable = stmt.Table
-		} else {
-			return err
+		if opt.Sort != "" {
+			str += " " + opt.Sort
 		}
-	}
 
-	if v, ok := newName.(string); ok {
-		newTable = v
-	} else {
-		stmt := &gorm.Statement{DB: m.DB}
-		if err := stmt.Parse(newName); err == nil {
-			newTable = stmt.Table
-		} else {
-			return err
+		if opt.Nulls != "" {
+			str += " NULLS " + opt.Nulls
 		}
+		results = append(results, clause.Expr{SQL: str})
 	}
+	return
+}
 
-	return m.DB.Exec("ALTER TABLE ? RENAME TO ?", clause.Table{Name: oldTable}, clause.Table{Name: newTable}).Error
+type BuildIndexOptionsInterface interface {
+	BuildIndexOptions([]schema.IndexOption, *gorm.Statement) []interface{}
 }
 
-func (m Migrator) AddColumn(value interface{}, field string) error {
-	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
-		if field := stmt.Schema.LookUpField(field); field != nil {
-			return m.DB.Exec(
-				"ALTER TABLE ? ADD ? ?",
-				clause.Table{Name: stmt.Table}, clause.Column{Name: field.DBName}, m.FullDataTypeOf(field),
-			).Error
-		}
-		return fmt.Errorf("failed to look up field with name: %s", field)
-	})
+// indexOptionsOf index options of the dialect migrator, migrators not embedding Migrator use m
+func (m Migrator) indexOptionsOf(migrator gorm.Migrator) BuildIndexOptionsInterface {
+	if builder, ok := migrator.(BuildIndexOptionsInterface); ok {
+		return builder
+	}
+	return m
 }
 
-func (m Migrator) DropColumn(value interface{}, name string) error {
+func (m Migrator) CreateIndex(value interface{}, name string) error {
 	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
-		if field := stmt.Schema.LookUpField(name); field != nil {
-			name = field.DBName
+		if idx := m.lookIndex(stmt, name); idx != nil {
+			return m.execCreateIndex(stmt, idx, idx.Name, stmt.Table)
 		}
 
-		return m.DB.Exec(
-			"ALTER TABLE ? DROP COLUMN ?", clause.Table{Name: stmt.Table}, clause.Column{Name: name},
-		).Error
+		return fmt.Errorf("failed to create index with name %v", name)
 	})
 }
 
-func (m Migrator) AlterColumn(value interface{}, field string) error {
+// CreateIndexOn create index of model on another table only, e.g: a local index on a partition of the model's table
+func (m Migrator) CreateIndexOn(value interface{}, name string, table string) error {
 	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
-		if field := stmt.Schema.LookUpField(field); field != nil {
-			return m.DB.Exec(
-				"ALTER TABLE ? ALTER COLUMN ? TYPE ?",
-				clause.Table{Name: stmt.Table}, clause.Column{Name: field.DBName}, m.FullDataTypeOf(field),
-			).Error
+		idx := m.lookIndex(stmt, name)
+		if idx == nil {
+			return fmt.Errorf("failed to create index with name %v", name)
 		}
-		return fmt.Errorf("failed to look up field with name: %s", field)
+
+		indexName := idx.Name + "_" + table
+		if len(idx.Fields) == 1 && idx.Fields[0].Field != nil && idx.Name == m.DB.NamingStrategy.IndexName(stmt.Table, idx.Fields[0].Name) {
+			indexName = m.DB.NamingStrategy.IndexName(table, idx.Fields[0].Name)
+		}
+		return m.execCreateIndex(stmt, idx, indexName, table)
 	})
 }
 
-func (m Migrator) HasColumn(value interface{}, field string) bool {
-	var count int64
-	m.RunWithValue(value, func(stmt *gorm.Statement) error {
-		currentDatabase := m.DB.Migrator().CurrentDatabase()
-		name := field
-		if field := stmt.Schema.LookUpField(field); field != nil {
-			name = field.DBName
-		}
+// DefaultMaxIndexKeyLength byte limit of index keys when Config.MaxIndexKeyLength is zero
+const DefaultMaxIndexKeyLength = 3072
 
-		return m.DB.Raw(
-			"SELECT count(*) FROM INFORMATION_SCHEMA.columns WHERE table_schema = ? AND table_name = ? AND column_name = ?",
-			currentDatabase, stmt.Table, name,
-		).Row().Scan(&count)
-	})
+// IndexKeyTooLongError the key of the index to be created exceeds the byte limit
+type IndexKeyTooLongError struct {
+	Table     string
+	Name      string
+	Length    int
+	MaxLength int
+}
 
-	return count > 0
+func (err IndexKeyTooLongError) Error() string {
+	return fmt.Sprintf("key of index %v on %v is %d bytes, exceeds the max key length %d bytes, shorten prefix lengths of its columns, e.g: `gorm:\"index:,length:191\"`", err.Name, err.Table, err.Length, err.MaxLength)
 }
 
-func (m Migrator) RenameColumn(value interface{}, oldName, newName string) error {
-	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
-		if field := stmt.Schema.LookUpField(oldName); field != nil {
-			oldName = field.DBName
-		}
+func (m Migrator) execCreateIndex(stmt *gorm.Statement, idx *schema.Index, name, table string) error {
+	opts := m.indexOptionsOf(m.migratorOf(m.DB)).BuildIndexOptions(idx.Fields, stmt)
+	values := []interface{}{clause.Column{Name: name}, m.qualifiedTable(table), opts}
 
-		if field := stmt.Schema.LookUpField(newName); field != nil {
-			newName = field.DBName
-		}
+	createIndexSQL := "CREATE "
+	if idx.Class != "" {
+		createIndexSQL += idx.Class + " "
+	}
+	createIndexSQL += "INDEX ? ON ??"
 
-		return m.DB.Exec(
-			"ALTER TABLE ? RENAME COLUMN ? TO ?",
-			clause.Table{Name: stmt.Table}, clause.Column{Name: oldName}, clause.Column{Name: newName},
-		).Error
-	})
+	if idx.Parser != "" {
+		createIndexSQL += " WITH PARSER " + idx.Parser
+	}
+
+	if idx.Comment != "" {
+		values = append(values, idx.Comment)
+		createIndexSQL += " COMMENT ?"
+	}
+
+	if idx.Type != "" {
+		createIndexSQL += " USING " + idx.Type
+	}
+
+	if idx.Where != "" {
+		createIndexSQL += " WHERE " + idx.Where
+	}
+	return m.execDDL(createIndexSQL, values...)
 }
 
-func (m Migrator) ColumnTypes(value interface{}) (columnTypes []*sql.ColumnType, err error) {
-	err = m.RunWithValue(value, func(stmt *gorm.Statement) error {
-		rows, err := m.DB.Raw("select * from ?", clause.Table{Name: stmt.Table}).Rows()
-		if err == nil {
-			columnTypes, err = rows.ColumnTypes()
-		}
-		return err
-	})
-	return
+type FullTextIndexInterface interface {
+	CreateFullTextIndex(value interface{}, name string) error
 }
 
-func (m Migrator) CreateView(name string, option gorm.ViewOption) error {
-	return gorm.ErrNotImplemented
+// fullTextIndexesOf fulltext indexes of the dialect migrator, migrators not embedding Migrator use m
+func (m Migrator) fullTextIndexesOf(migrator gorm.Migrator) FullTextIndexInterface {
+	if creator, ok := migrator.(FullTextIndexInterface); ok {
+		return creator
+	}
+	return m
 }
 
-func (m Migrator) DropView(name string) error {
-	return gorm.ErrNotImplemented
+// createIndex create index, fulltext indexes are created with CreateFullTextIndex as their syntax varies between dialects
+func (m Migrator) createIndex(tx *gorm.DB, stmt *gorm.Statement, value interface{}, idx schema.Index) error {
+	if strings.ToUpper(idx.Class) == "FULLTEXT" {
+		return m.fullTextIndexesOf(m.migratorOf(tx)).CreateFullTextIndex(value, idx.Name)
+	}
+
+	if model := stmt.Schema.LookIndex(idx.Name); model != nil && model.Where != idx.Where {
+		// the predicate is added by the migrator config, which the dialect's migrator doesn't know
+		return m.CreateIndex(value, idx.Name)
+	}
+	return m.migratorOf(tx).CreateIndex(value, idx.Name)
 }
 
-func buildConstraint(constraint *schema.Constraint) (sql string, results []interface{}) {
-	sql = "CONSTRAINT ? FOREIGN KEY ? REFERENCES ??"
-	if constraint.OnDelete != "" {
-		sql += " ON DELETE " + constraint.OnDelete
+// PartialIndexInterface dialects implement it to report whether indexes could be created with predicates, e.g: WHERE deleted_at IS NULL
+type PartialIndexInterface interface {
+	SupportsPartialIndexes() bool
+}
+
+// parseIndexes indexes of model, unique indexes of soft deletable models are scoped to rows not deleted if SoftDeleteUniqueIndexes is set
+func (m Migrator) parseIndexes(stmt *gorm.Statement) map[string]schema.Index {
+	indexes := stmt.Schema.ParseIndexes()
+	if partial, ok := m.migratorOf(m.DB).(PartialIndexInterface); !m.SoftDeleteUniqueIndexes || (ok && !partial.SupportsPartialIndexes()) {
+		return indexes
 	}
 
-	if constraint.OnUpdate != "" {
-		sql += " ON UPDATE  " + constraint.OnUpdate
+	if condition := stmt.Schema.SoftDeleteCondition(); condition != "" {
+		for name, idx := range indexes {
+			if strings.ToUpper(idx.Class) != "UNIQUE" || strings.Contains(idx.Where, condition) {
+				continue
+			}
+
+			if idx.Where == "" {
+				idx.Where = condition
+			} else {
+				idx.Where = "(" + idx.Where + ") AND " + condition
+			}
+			indexes[name] = idx
+		}
 	}
+	return indexes
+}
 
-	var foreignKeys, references []interface{}
-	for _, field := range constraint.ForeignKeys {
-		foreignKeys = append(foreignKeys, clause.Column{Name: field.DBName})
+// sortedIndexes indexes sorted by name, so DDL generated from maps of the schema is deterministic
+func sortedIndexes(indexes map[string]schema.Index) []schema.Index {
+	results := make([]schema.Index, 0, len(indexes))
+	for _, idx := range indexes {
+		results = append(results, idx)
 	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return results
+}
 
-	for _, field := range constraint.References {
-		references = append(references, clause.Column{Name: field.DBName})
+// sortedChecks check constraints sorted by name, see sortedIndexes
+func sortedChecks(checks map[string]schema.Check) []schema.Check {
+	results := make([]schema.Check, 0, len(checks))
+	for _, chk := range checks {
+		results = append(results, chk)
 	}
-	results = append(results, clause.Table{Name: constraint.Name}, foreignKeys, clause.Table{Name: constraint.ReferenceSchema.Table}, references)
-	return
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return results
 }
 
-func (m Migrator) CreateConstraint(value interface{}, name string) error {
-	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
-		checkConstraints := stmt.Schema.ParseCheckConstraints()
-		if chk, ok := checkConstraints[name]; ok {
-			return m.DB.Exec(
-				"ALTER TABLE ? ADD CONSTRAINT ? CHECK ?",
-				clause.Table{Name: stmt.Table}, clause.Column{Name: chk.Name}, clause.Expr{SQL: chk.Constraint},
-			).Error
+// sortedUniqueConstraints unique constraints sorted by name, see sortedIndexes
+func sortedUniqueConstraints(uniques map[string]schema.UniqueConstraint) []schema.UniqueConstraint {
+	results := make([]schema.UniqueConstraint, 0, len(uniques))
+	for _, unique := range uniques {
+		results = append(results, unique)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return results
+}
+
+// sortedRelations relationships of the schema sorted by name, see sortedIndexes
+func sortedRelations(s *schema.Schema) []*schema.Relationship {
+	results := make([]*schema.Relationship, 0, len(s.Relationships.Relations))
+	for _, rel := range s.Relationships.Relations {
+		results = append(results, rel)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return results
+}
+
+// lookIndex look up index of model by name or field name, see parseIndexes
+func (m Migrator) lookIndex(stmt *gorm.Statement, name string) *schema.Index {
+	for _, idx := range sortedIndexes(m.parseIndexes(stmt)) {
+		if idx.Name == name {
+			return &idx
 		}
 
-		for _, rel := range stmt.Schema.Relationships.Relations {
-			if constraint := rel.ParseConstraint(); constraint != nil && constraint.Name == name {
-				sql, values := buildConstraint(constraint)
-				return m.DB.Exec("ALTER TABLE ? ADD "+sql, append([]interface{}{clause.Table{Name: stmt.Table}}, values...)...).Error
+		for _, opt := range idx.Fields {
+			if opt.Name == name {
+				return &idx
 			}
 		}
+	}
+	return nil
+}
 
-		err := fmt.Errorf("failed to create constraint with name %v", name)
-		if field := stmt.Schema.LookUpField(name); field != nil {
-			for _, cc := range checkConstraints {
-				if err = m.DB.Migrator().CreateIndex(value, cc.Name); err != nil {
-					return err
-				}
-			}
+// CreateFullTextIndex create fulltext index on text columns, dialects with other syntax implement FullTextIndexInterface
+func (m Migrator) CreateFullTextIndex(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		idx := stmt.Schema.LookIndex(name)
+		if idx == nil {
+			return fmt.Errorf("failed to create index with name %v", name)
+		}
 
-			for _, rel := range stmt.Schema.Relationships.Relations {
-				if constraint := rel.ParseConstraint(); constraint != nil && constraint.Field == field {
-					if err = m.DB.Migrator().CreateIndex(value, constraint.Name); err != nil {
-						return err
-					}
-				}
+		for _, opt := range idx.Fields {
+			if opt.Expression == "" && opt.DataType != schema.String {
+				return fmt.Errorf("fulltext index %v requires text columns, but %v is %v", idx.Name, opt.DBName, opt.DataType)
 			}
 		}
 
-		return err
+		return m.migratorOf(m.DB).CreateIndex(value, idx.Name)
 	})
 }
 
-func (m Migrator) DropConstraint(value interface{}, name string) error {
+func (m Migrator) DropIndex(value interface{}, name string) error {
 	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
-		return m.DB.Exec(
-			"ALTER TABLE ? DROP CONSTRAINT ?",
-			clause.Table{Name: stmt.Table}, clause.Column{Name: name},
-		).Error
+		if idx := stmt.Schema.LookIndex(name); idx != nil {
+			name = idx.Name
+		}
+		return m.execDDL("DROP INDEX ? ON ?", clause.Column{Name: name}, m.CurrentTable(stmt))
 	})
 }
 
-func (m Migrator) HasConstraint(value interface{}, name string) bool {
+// DropIndexIfExists drop index when it exists, won't return error if it has been dropped by others concurrently
+func (m Migrator) DropIndexIfExists(value interface{}, name string) error {
+	if !m.migratorOf(m.DB).HasIndex(value, name) {
+		return nil
+	}
+
+	if err := m.migratorOf(m.DB).DropIndex(value, name); err != nil && m.migratorOf(m.DB).HasIndex(value, name) {
+		return err
+	}
+	return nil
+}
+
+func (m Migrator) HasIndex(value interface{}, name string) bool {
 	var count int64
 	m.RunWithValue(value, func(stmt *gorm.Statement) error {
-		currentDatabase := m.DB.Migrator().CurrentDatabase()
-		return m.DB.Raw(
-			"SELECT count(*) FROM INFORMATION_SCHEMA.referential_constraints WHERE constraint_schema = ? AND table_name = ? AND constraint_name = ?",
-			currentDatabase, stmt.Table, name,
-		).Row().Scan(&count)
+		if idx := stmt.Schema.LookIndex(name); idx != nil {
+			name = idx.Name
+		}
+
+		sql, values := m.reflectionQueries().QueryForIndexExists(stmt, name)
+		return m.DB.Raw(sql, values...).Row().Scan(&count)
 	})
 
 	return count > 0
 }
 
-func (m Migrator) BuildIndexOptions(opts []schema.IndexOption, stmt *gorm.Statement) (results []interface{}) {
-	for _, opt := range opts {
-		str := stmt.Quote(opt.DBName)
-		if opt.Expression != "" {
-			str = opt.Expression
-		} else if opt.Length > 0 {
-			str += fmt.Sprintf("(%d)", opt.Length)
-		}
+func (m Migrator) QueryForIndexExists(stmt *gorm.Statement, name string) (string, []interface{}) {
+	return "SELECT count(*) FROM information_schema.statistics WHERE table_schema = ? AND table_name IN ? AND index_name IN ?",
+		[]interface{}{m.currentDatabase(), m.identifierCandidates(stmt.Table), m.identifierCandidates(name)}
+}
 
-		if opt.Collate != "" {
-			str += " COLLATE " + opt.Collate
-		}
+// HasIndexColumns check whether any index leads with the columns regardless of its name, e.g: index (a, b, c) has columns b, a
+func (m Migrator) HasIndexColumns(value interface{}, columns ...string) (bool, error) {
+	indexes, err := m.indexesOf(m.migratorOf(m.DB)).GetIndexes(value)
+	if err != nil {
+		return false, err
+	}
 
-		if opt.Sort != "" {
-			str += " " + opt.Sort
+	for _, idx := range indexes {
+		if indexLeadsWith(idx.Columns, columns) {
+			return true, nil
 		}
-		results = append(results, clause.Expr{SQL: str})
 	}
-	return
+	return false, nil
 }
 
-type BuildIndexOptionsInterface interface {
-	BuildIndexOptions([]schema.IndexOption, *gorm.Statement) []interface{}
+// indexLeadsWith check whether leading index columns are the columns in any order
+func indexLeadsWith(indexColumns []string, columns []string) bool {
+	if len(columns) == 0 || len(indexColumns) < len(columns) {
+		return false
+	}
+
+	leading := map[string]bool{}
+	for _, column := range indexColumns[:len(columns)] {
+		leading[column] = true
+	}
+
+	for _, column := range columns {
+		if !leading[column] {
+			return false
+		}
+		delete(leading, column)
+	}
+	return true
 }
 
-func (m Migrator) CreateIndex(value interface{}, name string) error {
-	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
-		if idx := stmt.Schema.LookIndex(name); idx != nil {
-			opts := m.DB.Migrator().(BuildIndexOptionsInterface).BuildIndexOptions(idx.Fields, stmt)
-			values := []interface{}{clause.Column{Name: idx.Name}, clause.Table{Name: stmt.Table}, opts}
+// IndexInfo index reflected from database
+type IndexInfo struct {
+	Name    string
+	Columns []string
+	Unique  bool
+	Class   string // UNIQUE | FULLTEXT | SPATIAL
+	Type    string // access method, e.g: BTREE, HASH, GIN
+	Comment string
+	Where   string   // partial index predicate
+	Nulls   []string // NULLS ordering of columns, FIRST | LAST (Postgres)
 
-			createIndexSQL := "CREATE "
-			if idx.Class != "" {
-				createIndexSQL += idx.Class + " "
-			}
-			createIndexSQL += "INDEX ? ON ??"
+	NullsNotDistinct bool // unique index treats NULLs as equal (Postgres 15+)
+}
 
-			if idx.Comment != "" {
-				values = append(values, idx.Comment)
-				createIndexSQL += " COMMENT ?"
-			}
+// IndexesInterface dialects implement it to reflect indexes of tables
+type IndexesInterface interface {
+	GetIndexes(value interface{}) ([]IndexInfo, error)
+}
 
-			if idx.Type != "" {
-				createIndexSQL += " USING " + idx.Type
-			}
+func (m Migrator) GetIndexes(value interface{}) ([]IndexInfo, error) {
+	return nil, gorm.ErrNotImplemented
+}
 
-			return m.DB.Exec(createIndexSQL, values...).Error
-		}
+// indexesOf index reflection of the dialect migrator, migrators not embedding Migrator use m
+func (m Migrator) indexesOf(migrator gorm.Migrator) IndexesInterface {
+	if reflector, ok := migrator.(IndexesInterface); ok {
+		return reflector
+	}
+	return m
+}
 
-		return fmt.Errorf("failed to create index with name %v", name)
-	})
+// PrimaryKeyPromotionInterface dialects implement it to promote unique indexes to primary key in one statement
+type PrimaryKeyPromotionInterface interface {
+	PromoteIndexToPrimaryKey(stmt *gorm.Statement, live IndexInfo) error
 }
 
-func (m Migrator) DropIndex(value interface{}, name string) error {
+// PromoteToPrimaryKey promote unique index on primary key columns of the model to primary key
+func (m Migrator) PromoteToPrimaryKey(value interface{}, index string) error {
 	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
-		if idx := stmt.Schema.LookIndex(name); idx != nil {
-			name = idx.Name
+		live, primaryKey, err := m.uniqueIndexOfPrimaryKey(value, stmt, index)
+		if err != nil {
+			return err
+		} else if primaryKey != "" {
+			return fmt.Errorf("failed to promote index %v, table %v has primary key %v already", index, stmt.Table, primaryKey)
+		} else if live == nil {
+			return fmt.Errorf("failed to promote index %v, it should be a unique index of %v on primary key columns of %v", index, stmt.Table, stmt.Schema.Name)
+		}
+
+		if promoter, ok := m.migratorOf(m.DB).(PrimaryKeyPromotionInterface); ok {
+			return promoter.PromoteIndexToPrimaryKey(stmt, *live)
 		}
 
-		return m.DB.Exec("DROP INDEX ? ON ?", clause.Column{Name: name}, clause.Table{Name: stmt.Table}).Error
+		if err := m.execDDL("ALTER TABLE ? ADD PRIMARY KEY ?", m.CurrentTable(stmt), indexColumnsOf(*live)); err != nil {
+			return err
+		}
+		return m.migratorOf(m.DB).DropIndex(value, live.Name)
 	})
 }
 
-func (m Migrator) HasIndex(value interface{}, name string) bool {
-	var count int64
-	m.RunWithValue(value, func(stmt *gorm.Statement) error {
-		currentDatabase := m.DB.Migrator().CurrentDatabase()
-		if idx := stmt.Schema.LookIndex(name); idx != nil {
-			name = idx.Name
+// indexColumnsOf columns of the live index
+func indexColumnsOf(live IndexInfo) []interface{} {
+	columns := make([]interface{}, 0, len(live.Columns))
+	for _, column := range live.Columns {
+		columns = append(columns, clause.Column{Name: column})
+	}
+	return columns
+}
+
+// uniqueIndexOfPrimaryKey find live unique index covering exactly primary key columns, also returns the live primary key
+func (m Migrator) uniqueIndexOfPrimaryKey(value interface{}, stmt *gorm.Statement, name string) (*IndexInfo, string, error) {
+	constraints, err := m.constraintsOf(m.migratorOf(m.DB)).GetConstraints(value)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, constraint := range constraints {
+		if constraint.Type == "PRIMARY KEY" {
+			return nil, constraint.Name, nil
 		}
+	}
 
-		return m.DB.Raw(
-			"SELECT count(*) FROM information_schema.statistics WHERE table_schema = ? AND table_name = ? AND index_name = ?",
-			currentDatabase, stmt.Table, name,
-		).Row().Scan(&count)
-	})
+	indexes, err := m.indexesOf(m.migratorOf(m.DB)).GetIndexes(value)
+	if err != nil {
+		return nil, "", err
+	}
 
-	return count > 0
+	primaryColumns := make([]string, 0, len(stmt.Schema.PrimaryFields))
+	for _, field := range stmt.Schema.PrimaryFields {
+		primaryColumns = append(primaryColumns, field.DBName)
+	}
+	sort.Strings(primaryColumns)
+
+	for _, idx := range indexes {
+		if (name != "" && idx.Name != name) || !idx.Unique || idx.Where != "" {
+			continue
+		}
+
+		columns := append([]string{}, idx.Columns...)
+		sort.Strings(columns)
+		if len(primaryColumns) > 0 && strings.Join(columns, ",") == strings.Join(primaryColumns, ",") {
+			return &idx, "", nil
+		}
+	}
+	return nil, "", nil
 }
 
+// RenameIndex rename index, e.g: ALTER TABLE ? RENAME INDEX ? TO ?
 func (m Migrator) RenameIndex(value interface{}, oldName, newName string) error {
 	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
-		return m.DB.Exec(
+		return m.execDDL(
 			"ALTER TABLE ? RENAME INDEX ? TO ?",
-			clause.Table{Name: stmt.Table}, clause.Column{Name: oldName}, clause.Column{Name: newName},
-		).Error
+			m.CurrentTable(stmt), clause.Column{Name: oldName}, clause.Column{Name: newName},
+		)
 	})
 }
 
@@ -541,10 +4472,8 @@ func (m Migrator) ReorderModels(values []interface{}, autoAdd bool) (results []i
 	}
 
 	var (
-		modelNames, orderedModelNames []string
-		orderedModelNamesMap          = map[string]bool{}
-		valuesMap                     = map[string]Dependency{}
-		insertIntoOrderedList         func(name string)
+		modelNames []string
+		valuesMap  = map[string]Dependency{}
 	)
 
 	parseDependence := func(value interface{}, addToList bool) {
@@ -553,7 +4482,7 @@ func (m Migrator) ReorderModels(values []interface{}, autoAdd bool) (results []i
 		}
 		dep.Parse(value)
 
-		for _, rel := range dep.Schema.Relationships.Relations {
+		for _, rel := range sortedRelations(dep.Schema) {
 			if c := rel.ParseConstraint(); c != nil && c.Schema != c.ReferenceSchema {
 				dep.Depends = append(dep.Depends, c.ReferenceSchema)
 			}
@@ -566,23 +4495,16 @@ func (m Migrator) ReorderModels(values []interface{}, autoAdd bool) (results []i
 		}
 	}
 
-	insertIntoOrderedList = func(name string) {
-		if _, ok := orderedModelNamesMap[name]; ok {
-			return // avoid loop
-		}
-
-		dep := valuesMap[name]
-		for _, d := range dep.Depends {
+	dependsOn := func(name string) (names []string) {
+		for _, d := range valuesMap[name].Depends {
 			if _, ok := valuesMap[d.Table]; ok {
-				insertIntoOrderedList(d.Table)
+				names = append(names, d.Table)
 			} else if autoAdd {
 				parseDependence(reflect.New(d.ModelType).Interface(), autoAdd)
-				insertIntoOrderedList(d.Table)
+				names = append(names, d.Table)
 			}
 		}
-
-		orderedModelNames = append(orderedModelNames, name)
-		orderedModelNamesMap[name] = true
+		return
 	}
 
 	for _, value := range values {
@@ -593,12 +4515,33 @@ func (m Migrator) ReorderModels(values []interface{}, autoAdd bool) (results []i
 		}
 	}
 
-	for _, name := range modelNames {
-		insertIntoOrderedList(name)
+	for _, name := range orderByDependencies(modelNames, dependsOn) {
+		results = append(results, valuesMap[name].Statement.Dest)
 	}
+	return
+}
 
-	for _, name := range orderedModelNames {
-		results = append(results, valuesMap[name].Statement.Dest)
+// orderByDependencies order names after names they depend on, e.g: tables after referenced tables, views after views they select from
+func orderByDependencies(names []string, dependsOn func(name string) []string) (ordered []string) {
+	var (
+		visited = map[string]bool{}
+		visit   func(name string)
+	)
+
+	visit = func(name string) {
+		if visited[name] {
+			return // avoid loop
+		}
+		visited[name] = true
+
+		for _, dep := range dependsOn(name) {
+			visit(dep)
+		}
+		ordered = append(ordered, name)
+	}
+
+	for _, name := range names {
+		visit(name)
 	}
 	return
 }
@@ -0,0 +1,97 @@
+package migrator
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultPendingConstraintsTable table records constraints added with NOT VALID when Config.PendingConstraintsTable is blank
+const DefaultPendingConstraintsTable = "pending_constraints"
+
+// pendingConstraint row of the pending constraints table, a constraint added with NOT VALID waiting for ValidatePendingConstraints
+type pendingConstraint struct {
+	Schema    string `gorm:"primarykey;size:191"`
+	Table     string `gorm:"primarykey;size:191"`
+	Name      string `gorm:"primarykey;size:191"`
+	CreatedAt time.Time
+}
+
+func (m Migrator) pendingConstraintsTable() string {
+	if m.PendingConstraintsTable != "" {
+		return m.PendingConstraintsTable
+	}
+	return DefaultPendingConstraintsTable
+}
+
+// NotValidConstraintInterface dialects implement it to add foreign keys without checking existing rows, e.g: NOT VALID
+type NotValidConstraintInterface interface {
+	NotValidSQL() string
+	ValidateConstraint(table, name string) error
+}
+
+// addForeignKeysNotValid whether foreign keys are added with NOT VALID
+func (m Migrator) addForeignKeysNotValid() bool {
+	_, ok := m.migratorOf(m.DB).(NotValidConstraintInterface)
+	return m.AddForeignKeysNotValid && ok
+}
+
+// pendingConstraints session of the pending constraints table, which isn't scoped to the schema set by WithSchema
+func (m Migrator) pendingConstraints() *gorm.DB {
+	return m.WithSchema("").(Migrator).DB.Table(m.pendingConstraintsTable())
+}
+
+// recordPendingConstraint record constraint added with NOT VALID, creates the pending constraints table if it doesn't exist
+func (m Migrator) recordPendingConstraint(stmt *gorm.Statement, name string) error {
+	if !m.migratorOf(m.DB).HasTable(m.pendingConstraintsTable()) {
+		creator := m.WithSchema("").(Migrator)
+		creator.DB = m.pendingConstraints()
+		if err := creator.CreateTable(&pendingConstraint{}); err != nil {
+			return err
+		}
+	}
+
+	record := pendingConstraint{Schema: m.schemaName(), Table: stmt.Table, Name: name, CreatedAt: time.Now()}
+	if err := m.pendingConstraints().Where(record.key()).Delete(&pendingConstraint{}).Error; err != nil {
+		return err
+	}
+	return m.pendingConstraints().Create(&record).Error
+}
+
+func (record pendingConstraint) key() map[string]interface{} {
+	return map[string]interface{}{"schema": record.Schema, "table": record.Table, "name": record.Name}
+}
+
+// ValidatePendingConstraints validate constraints added with NOT VALID and remove them from the pending constraints table
+func (m Migrator) ValidatePendingConstraints() error {
+	if !m.migratorOf(m.DB).HasTable(m.pendingConstraintsTable()) {
+		return nil
+	}
+
+	if _, ok := m.migratorOf(m.DB).(NotValidConstraintInterface); !ok {
+		return gorm.ErrNotImplemented
+	}
+
+	var records []pendingConstraint
+	if err := m.pendingConstraints().Order("created_at").Order("name").Find(&records).Error; err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		validator := m.WithSchema(record.Schema).(Migrator)
+		if !validator.HasConstraint(record.Table, record.Name) {
+			m.DB.Logger.Warn(m.DB.Statement.Context, "pending constraint %v of %v is not found, it is kept in %v", record.Name, record.Table, m.pendingConstraintsTable())
+			continue
+		}
+
+		if err := validator.migratorOf(validator.DB).(NotValidConstraintInterface).ValidateConstraint(record.Table, record.Name); err != nil {
+			return fmt.Errorf("failed to validate constraint %v of %v: %w", record.Name, record.Table, err)
+		}
+
+		if err := m.pendingConstraints().Where(record.key()).Delete(&pendingConstraint{}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
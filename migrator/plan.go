@@ -0,0 +1,118 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// lock impacts of migrate operations, from the least to the most disruptive
+const (
+	LockInstant       = "instant"        // changes metadata only, e.g: MySQL 8.0 ADD COLUMN with ALGORITHM=INSTANT
+	LockMetadataOnly  = "metadata-only"  // briefly locks the table to change metadata, or builds indexes without blocking writes (MySQL)
+	LockExclusiveLock = "exclusive-lock" // blocks writes while scanning or indexing the table, e.g: SET NOT NULL, CREATE INDEX (Postgres)
+	LockFullRewrite   = "full-rewrite"   // rewrites the table, e.g: changing column types
+)
+
+// AutoMigrateResult operations performed by AutoMigrate for each table
+type AutoMigrateResult struct {
+	Tables []TableMigrateResult
+}
+
+// TableMigrateResult operations performed on a table, empty if the table is unchanged
+type TableMigrateResult struct {
+	Table      string
+	Operations []MigrateOperation
+	SQL        []string // statements planned by PlanAutoMigrate
+	Error      error    // failure of the table rolled back to its savepoint, see SavePointPerModel
+}
+
+// MigrateOperation migrate operation, e.g: {Type: "add_column", Name: "name"}
+type MigrateOperation struct {
+	Type string // create_table, set_table_owner, add_column, alter_column, recreate_column, create_constraint, recreate_constraint, create_index, recreate_index, comment_index, alter_column_default, alter_column_nullability, alter_column_unique, reorder_column, set_column_storage, alter_column_comment, promote_primary_key, set_replica_identity, drop_constraint, drop_column, skip_<type> for skipped unsupported operations
+	Name string
+	Lock string // lock impact of the operation, e.g: instant, metadata-only, exclusive-lock, full-rewrite
+}
+
+// Count count operations with type
+func (result AutoMigrateResult) Count(typ string) (count int) {
+	for _, table := range result.Tables {
+		for _, operation := range table.Operations {
+			if operation.Type == typ {
+				count++
+			}
+		}
+	}
+	return
+}
+
+// Unchanged count tables unchanged
+func (result AutoMigrateResult) Unchanged() (count int) {
+	for _, table := range result.Tables {
+		if len(table.Operations) == 0 {
+			count++
+		}
+	}
+	return
+}
+
+// LockImpactsInterface dialects implement it to classify lock impacts of migrate operations, keyed by operation type, e.g: add_column
+type LockImpactsInterface interface {
+	LockImpacts() map[string]string
+}
+
+// LockImpactOf classify lock impact of migrate operation by the dialect, unknown operations are taken as exclusive-lock
+func (m Migrator) LockImpactOf(typ string) string {
+	if strings.HasPrefix(typ, "skip_") {
+		return ""
+	}
+
+	if impacter, ok := m.migratorOf(m.DB).(LockImpactsInterface); ok {
+		if impact, ok := impacter.LockImpacts()[typ]; ok {
+			return impact
+		}
+	}
+	return LockExclusiveLock
+}
+
+// planConnPool records statements executed by the migrator instead of executing them, queries reflecting the database run as usual
+type planConnPool struct {
+	gorm.ConnPool
+	dialector gorm.Dialector
+	sqls      []string
+}
+
+func (pool *planConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	pool.sqls = append(pool.sqls, pool.dialector.Explain(query, args...))
+	return driver.RowsAffected(0), nil
+}
+
+func (pool *planConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	return pool, nil
+}
+
+func (pool *planConnPool) Commit() error {
+	return nil
+}
+
+func (pool *planConnPool) Rollback() error {
+	return nil
+}
+
+// flush returns statements recorded since last flush
+func (pool *planConnPool) flush() (sqls []string) {
+	sqls, pool.sqls = pool.sqls, nil
+	return
+}
+
+// PlanAutoMigrate dry run auto migration, returns operations and statements of each table without executing them
+func (m Migrator) PlanAutoMigrate(values ...interface{}) (result AutoMigrateResult, err error) {
+	m.DB = m.DB.Session(&gorm.Session{Context: m.DB.Statement.Context})
+	m.DB.Statement.ConnPool = &planConnPool{ConnPool: m.DB.Statement.ConnPool, dialector: m.Dialector}
+
+	err = m.autoMigrate(&result, values...)
+	return
+}
@@ -6,11 +6,20 @@ import (
 )
 
 type Check struct {
-	Name       string
-	Constraint string // length(phone) >= 10
+	Name        string
+	Constraint  string // length(phone) >= 10
+	NoInherit   bool   // length(phone) >= 10 NO INHERIT
+	NotEnforced bool   // length(phone) >= 10 NOT ENFORCED
+	Enum        bool   // status IN ('active','inactive'), generated for fields with enum values
+	LegacyName  string // explicit name declared on an embedded struct before it was scoped to the table, e.g: name_checker
 	*Field
 }
 
+var (
+	noInheritRegexp   = regexp.MustCompile(`(?i)\s+NO\s+INHERIT\s*$`)
+	notEnforcedRegexp = regexp.MustCompile(`(?i)\s+NOT\s+ENFORCED\s*$`)
+)
+
 // ParseCheckConstraints parse schema check constraints
 func (schema *Schema) ParseCheckConstraints() map[string]Check {
 	var checks = map[string]Check{}
@@ -18,7 +27,8 @@ func (schema *Schema) ParseCheckConstraints() map[string]Check {
 		if chk := field.TagSettings["CHECK"]; chk != "" {
 			names := strings.Split(chk, ",")
 			if len(names) > 1 && regexp.MustCompile("^[A-Za-z-_]+$").MatchString(names[0]) {
-				checks[names[0]] = Check{Name: names[0], Constraint: strings.Join(names[1:], ","), Field: field}
+				name := schema.embeddedConstraintName(field, names[0], schema.namer.CheckerName)
+				checks[name] = Check{Name: name, Constraint: strings.Join(names[1:], ","), LegacyName: legacyConstraintName(name, names[0]), Field: field}
 			} else {
 				if names[0] == "" {
 					chk = strings.Join(names[1:], ",")
@@ -28,5 +38,54 @@ func (schema *Schema) ParseCheckConstraints() map[string]Check {
 			}
 		}
 	}
+
+	for _, field := range schema.Fields {
+		if len(field.EnumValues) > 0 && field.DBName != "" {
+			values := make([]string, len(field.EnumValues))
+			for idx, value := range field.EnumValues {
+				values[idx] = "'" + strings.Replace(value, "'", "''", -1) + "'"
+			}
+
+			name := schema.namer.CheckerName(schema.Table, field.DBName+"_enum")
+			checks[name] = Check{Name: name, Constraint: field.DBName + " IN (" + strings.Join(values, ",") + ")", Enum: true, Field: field}
+		}
+	}
+
+	legacyNames := map[string]int{}
+	for _, chk := range checks {
+		legacyNames[chk.LegacyName]++
+	}
+
+	for name, chk := range checks {
+		if legacyNames[chk.LegacyName] > 1 {
+			// declared by a struct embedded several times, the legacy name can't tell them apart
+			chk.LegacyName = ""
+		}
+
+		if notEnforcedRegexp.MatchString(chk.Constraint) {
+			chk.Constraint, chk.NotEnforced = notEnforcedRegexp.ReplaceAllString(chk.Constraint, ""), true
+		}
+
+		if noInheritRegexp.MatchString(chk.Constraint) {
+			chk.Constraint, chk.NoInherit = noInheritRegexp.ReplaceAllString(chk.Constraint, ""), true
+		}
+		checks[name] = chk
+	}
 	return checks
 }
+
+// embeddedConstraintName scope constraint names declared on fields of embedded structs to the table, e.g: chk_users_name_checker
+func (schema *Schema) embeddedConstraintName(field *Field, name string, namer func(table, column string) string) string {
+	if len(field.BindNames) > 1 {
+		return namer(schema.Table, field.TagSettings["EMBEDDEDPREFIX"]+name)
+	}
+	return name
+}
+
+// legacyConstraintName the explicit name if it was scoped by embeddedConstraintName, constraints created with it before are still found
+func legacyConstraintName(name, declared string) string {
+	if name != declared {
+		return declared
+	}
+	return ""
+}
@@ -12,6 +12,9 @@ type UserCheck struct {
 	Name  string `gorm:"check:name_checker,name <> 'jinzhu'"`
 	Name2 string `gorm:"check:name <> 'jinzhu'"`
 	Name3 string `gorm:"check:,name <> 'jinzhu'"`
+	Name4 string `gorm:"check:local_name_checker,name4 <> 'jinzhu' NO INHERIT"`
+	Name5 string `gorm:"check:lazy_name_checker,name5 <> 'jinzhu' NOT ENFORCED"`
+	Mood  string `gorm:"enum:happy, sad,it's ok"`
 }
 
 func TestParseCheck(t *testing.T) {
@@ -33,6 +36,21 @@ func TestParseCheck(t *testing.T) {
 			Name:       "chk_user_checks_name3",
 			Constraint: "name <> 'jinzhu'",
 		},
+		"local_name_checker": {
+			Name:       "local_name_checker",
+			Constraint: "name4 <> 'jinzhu'",
+			NoInherit:  true,
+		},
+		"lazy_name_checker": {
+			Name:        "lazy_name_checker",
+			Constraint:  "name5 <> 'jinzhu'",
+			NotEnforced: true,
+		},
+		"chk_user_checks_mood_enum": {
+			Name:       "chk_user_checks_mood_enum",
+			Constraint: "mood IN ('happy','sad','it''s ok')",
+			Enum:       true,
+		},
 	}
 
 	checks := user.ParseCheckConstraints()
@@ -43,7 +61,7 @@ func TestParseCheck(t *testing.T) {
 			t.Errorf("Failed to found check %v from parsed checks %+v", k, checks)
 		}
 
-		for _, name := range []string{"Name", "Constraint"} {
+		for _, name := range []string{"Name", "Constraint", "NoInherit", "NotEnforced", "Enum"} {
 			if reflect.ValueOf(result).FieldByName(name).Interface() != reflect.ValueOf(v).FieldByName(name).Interface() {
 				t.Errorf(
 					"check %v %v should equal, expects %v, got %v",
@@ -53,3 +71,60 @@ func TestParseCheck(t *testing.T) {
 		}
 	}
 }
+
+type CheckAudit struct {
+	Reviewer string `gorm:"check:reviewer_checker,reviewer <> ''"`
+	Score    int    `gorm:"check:score >= 0;uniqueConstraint:uni_score"`
+}
+
+type CheckPost struct {
+	ID uint
+	CheckAudit
+}
+
+type CheckComment struct {
+	ID     uint
+	Audit  CheckAudit `gorm:"embedded;embeddedPrefix:audit_"`
+	Review CheckAudit `gorm:"embedded;embeddedPrefix:review_"`
+}
+
+func TestParseEmbeddedCheck(t *testing.T) {
+	post, err := schema.Parse(&CheckPost{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse post, got error %v", err)
+	}
+
+	comment, err := schema.Parse(&CheckComment{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse comment, got error %v", err)
+	}
+
+	for s, names := range map[*schema.Schema][]string{
+		post:    {"chk_check_posts_reviewer_checker", "chk_check_posts_score"},
+		comment: {"chk_check_comments_audit_reviewer_checker", "chk_check_comments_audit_score", "chk_check_comments_review_reviewer_checker", "chk_check_comments_review_score"},
+	} {
+		checks := s.ParseCheckConstraints()
+		if len(checks) != len(names) {
+			t.Errorf("%v should have checks %v, got %+v", s.Table, names, checks)
+		}
+
+		for _, name := range names {
+			if _, ok := checks[name]; !ok {
+				t.Errorf("failed to find check %v of %v from parsed checks %+v", name, s.Table, checks)
+			}
+		}
+	}
+
+	if legacyName := post.ParseCheckConstraints()["chk_check_posts_reviewer_checker"].LegacyName; legacyName != "reviewer_checker" {
+		t.Errorf("legacy name of embedded check should be the declared name, got %v", legacyName)
+	}
+
+	if legacyName := comment.ParseCheckConstraints()["chk_check_comments_audit_reviewer_checker"].LegacyName; legacyName != "" {
+		t.Errorf("legacy name of check declared by a struct embedded several times should be empty, got %v", legacyName)
+	}
+
+	uniques := comment.ParseUniqueConstraints()
+	if _, ok := uniques["uni_check_comments_audit_uni_score"]; !ok || len(uniques) != 2 {
+		t.Errorf("unique constraints of embedded structs should be scoped to the table, got %+v", uniques)
+	}
+}
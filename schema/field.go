@@ -5,6 +5,7 @@ import (
 	"database/sql/driver"
 	"fmt"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -49,11 +50,20 @@ type Field struct {
 	AutoUpdateTime        TimeType
 	DefaultValue          string
 	DefaultValueInterface interface{}
+	DefaultOnNull         bool // default applies to explicit NULL inserts too, rendered by dialects supporting it, e.g: default:0;defaultOnNull (Oracle)
 	NotNull               bool
 	Unique                bool
 	Comment               string
+	Meta                  map[string]string // structured metadata stored in the column comment, e.g: meta:pii=true,retention=30d
+	GeneratedExpression   string
+	GeneratedStored       bool
+	Storage               string // PLAIN, EXTERNAL, EXTENDED, MAIN (Postgres)
+	CollateProvider       string // provider of the collation in the column type, e.g: type:text COLLATE de-DE;collateProvider:icu (Postgres)
+	EnumValues            []string
+	EnumCheck             bool // check enum values with check constraint instead of native enum type, e.g: enum:active,inactive;enumCheck (Postgres)
 	Size                  int
 	Precision             int
+	ArrayDimensions       int
 	FieldType             reflect.Type
 	IndirectFieldType     reflect.Type
 	StructField           reflect.StructField
@@ -66,6 +76,8 @@ type Field struct {
 	Set                   func(reflect.Value, interface{}) error
 }
 
+var generatedStorageRegexp = regexp.MustCompile(`(?i)\s+(STORED|VIRTUAL)$`)
+
 func (schema *Schema) ParseField(fieldStruct reflect.StructField) *Field {
 	field := &Field{
 		Name:              fieldStruct.Name,
@@ -151,6 +163,7 @@ func (schema *Schema) ParseField(fieldStruct reflect.StructField) *Field {
 	if v, ok := field.TagSettings["DEFAULT"]; ok {
 		field.HasDefaultValue = true
 		field.DefaultValue = v
+		_, field.DefaultOnNull = field.TagSettings["DEFAULTONNULL"]
 	}
 
 	if num, ok := field.TagSettings["SIZE"]; ok {
@@ -176,10 +189,54 @@ func (schema *Schema) ParseField(fieldStruct reflect.StructField) *Field {
 		field.Comment = val
 	}
 
+	if val, ok := field.TagSettings["META"]; ok {
+		field.Meta = map[string]string{}
+		for _, pair := range strings.Split(val, ",") {
+			if kv := strings.SplitN(pair, "=", 2); strings.TrimSpace(kv[0]) == "" {
+				continue
+			} else if len(kv) == 1 {
+				field.Meta[strings.TrimSpace(kv[0])] = "true"
+			} else {
+				field.Meta[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+			}
+		}
+	}
+
 	if val, ok := field.TagSettings["TYPE"]; ok {
 		field.DBDataType = val
 	}
 
+	if val, ok := field.TagSettings["GENERATED"]; ok {
+		field.GeneratedExpression = strings.TrimSpace(val)
+		if matches := generatedStorageRegexp.FindStringSubmatch(field.GeneratedExpression); len(matches) == 2 {
+			field.GeneratedExpression = strings.TrimSpace(strings.TrimSuffix(field.GeneratedExpression, matches[0]))
+			field.GeneratedStored = strings.ToUpper(matches[1]) == "STORED"
+		}
+	}
+
+	if val, ok := field.TagSettings["ENUM"]; ok {
+		for _, value := range strings.Split(val, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				field.EnumValues = append(field.EnumValues, value)
+			}
+		}
+		_, field.EnumCheck = field.TagSettings["ENUMCHECK"]
+	}
+
+	if val, ok := field.TagSettings["STORAGE"]; ok {
+		field.Storage = strings.ToUpper(strings.TrimSpace(val))
+	}
+
+	if val, ok := field.TagSettings["COLLATEPROVIDER"]; ok {
+		field.CollateProvider = strings.ToLower(strings.TrimSpace(val))
+	}
+
+	if val, ok := field.TagSettings["ARRAY"]; ok {
+		if field.ArrayDimensions, _ = strconv.Atoi(val); field.ArrayDimensions <= 0 {
+			field.ArrayDimensions = 1
+		}
+	}
+
 	switch reflect.Indirect(fieldValue).Kind() {
 	case reflect.Bool:
 		field.DataType = Bool
@@ -215,8 +272,10 @@ func (schema *Schema) ParseField(fieldStruct reflect.StructField) *Field {
 			field.DataType = Time
 		}
 	case reflect.Array, reflect.Slice:
-		if fieldValue.Type().Elem() == reflect.TypeOf(uint8(0)) {
+		if reflect.Indirect(fieldValue).Type().Elem() == reflect.TypeOf(uint8(0)) {
 			field.DataType = Bytes
+		} else if field.ArrayDimensions > 0 {
+			field.DataType = arrayElemDataType(reflect.Indirect(fieldValue).Type())
 		}
 	}
 
@@ -241,7 +300,12 @@ func (schema *Schema) ParseField(fieldStruct reflect.StructField) *Field {
 	}
 
 	if field.Size == 0 {
-		switch reflect.Indirect(fieldValue).Kind() {
+		sizeType := reflect.Indirect(fieldValue).Type()
+		for field.ArrayDimensions > 0 && (sizeType.Kind() == reflect.Slice || sizeType.Kind() == reflect.Array) {
+			sizeType = sizeType.Elem()
+		}
+
+		switch sizeType.Kind() {
 		case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64, reflect.Float64:
 			field.Size = 64
 		case reflect.Int8, reflect.Uint8:
@@ -286,6 +350,12 @@ func (schema *Schema) ParseField(fieldStruct reflect.StructField) *Field {
 		}
 	}
 
+	// generated columns are computed by database
+	if field.GeneratedExpression != "" {
+		field.Creatable = false
+		field.Updatable = false
+	}
+
 	if _, ok := field.TagSettings["EMBEDDED"]; ok || fieldStruct.Anonymous {
 		var err error
 		field.Creatable = false
@@ -745,3 +815,28 @@ func (field *Field) setupValuerAndSetter() {
 		}
 	}
 }
+
+// arrayElemDataType returns data type of the innermost element of array
+func arrayElemDataType(fieldType reflect.Type) DataType {
+	for fieldType.Kind() == reflect.Slice || fieldType.Kind() == reflect.Array || fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+
+	switch fieldType.Kind() {
+	case reflect.Bool:
+		return Bool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Int
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Uint
+	case reflect.Float32, reflect.Float64:
+		return Float
+	case reflect.String:
+		return String
+	case reflect.Struct:
+		if fieldType.ConvertibleTo(reflect.TypeOf(time.Time{})) {
+			return Time
+		}
+	}
+	return ""
+}
@@ -249,3 +249,46 @@ func TestParseFieldWithPermission(t *testing.T) {
 		checkSchemaField(t, user, &f, func(f *schema.Field) {})
 	}
 }
+
+type UserWithArrayFields struct {
+	Tags   []string  `gorm:"array"`
+	Matrix [][]int32 `gorm:"array:2"`
+}
+
+func TestParseFieldWithArray(t *testing.T) {
+	user, err := schema.Parse(&UserWithArrayFields{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("Failed to parse user with array fields, got error %v", err)
+	}
+
+	if field := user.LookUpField("tags"); field == nil || field.DataType != schema.String || field.ArrayDimensions != 1 {
+		t.Errorf("tags should be parsed as string array, got %+v", field)
+	}
+
+	if field := user.LookUpField("matrix"); field == nil || field.DataType != schema.Int || field.ArrayDimensions != 2 || field.Size != 32 {
+		t.Errorf("matrix should be parsed as two dimensions int32 array, got %+v", field)
+	}
+}
+
+type UserWithGeneratedFields struct {
+	ID       uint
+	Price    int
+	Quantity int
+	Total    int    `gorm:"generated:price * quantity STORED"`
+	Label    string `gorm:"generated:concat(price, 'x')"`
+}
+
+func TestParseFieldWithGenerated(t *testing.T) {
+	user, err := schema.Parse(&UserWithGeneratedFields{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("Failed to parse user with generated fields, got error %v", err)
+	}
+
+	if field := user.LookUpField("total"); field == nil || field.GeneratedExpression != "price * quantity" || !field.GeneratedStored || field.Creatable || field.Updatable || !field.Readable {
+		t.Errorf("total should be parsed as stored generated column, got %+v", field)
+	}
+
+	if field := user.LookUpField("label"); field == nil || field.GeneratedExpression != "concat(price, 'x')" || field.GeneratedStored {
+		t.Errorf("label should be parsed as virtual generated column, got %+v", field)
+	}
+}
@@ -1,25 +1,36 @@
 package schema
 
 import (
+	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
 )
 
 type Index struct {
-	Name    string
-	Class   string // UNIQUE | FULLTEXT | SPATIAL
-	Type    string // btree, hash, gist, spgist, gin, and brin
-	Where   string
-	Comment string
-	Fields  []IndexOption
+	Name     string
+	Class    string // UNIQUE | FULLTEXT | SPATIAL
+	Type     string // btree, hash, gist, spgist, gin, and brin
+	Where    string
+	Comment  string
+	Parser   string // fulltext parser, e.g: ngram (MySQL), text search config (Postgres)
+	Deferred bool   // created after the table instead of inline, e.g: `index:,deferred`
+	Manual   bool   // skipped by CreateTable and AutoMigrate, created with CreateIndex, e.g: `index:,manual`
+	Fields   []IndexOption
+
+	NullsNotDistinct bool // unique index allows at most one NULL, e.g: `uniqueIndex:,nullsNotDistinct` (Postgres 15+)
+
+	softDelete bool
 }
 
 type IndexOption struct {
 	*Field
-	Expression string
-	Sort       string // DESC, ASC
-	Collate    string
-	Length     int
+	Expression      string
+	Sort            string // DESC, ASC
+	Nulls           string // FIRST, LAST
+	Collate         string
+	CollateProvider string // collation provider, icu or libc, e.g: `index:,collate:de-DE,collateProvider:icu` (Postgres)
+	Length          int
 }
 
 // ParseIndexes parse schema indexes
@@ -27,7 +38,7 @@ func (schema *Schema) ParseIndexes() map[string]Index {
 	var indexes = map[string]Index{}
 
 	for _, field := range schema.Fields {
-		if field.TagSettings["INDEX"] != "" || field.TagSettings["UNIQUE_INDEX"] != "" {
+		if field.TagSettings["INDEX"] != "" || field.TagSettings["UNIQUE_INDEX"] != "" || field.TagSettings["UNIQUEINDEX"] != "" {
 			for _, index := range parseFieldIndexes(field) {
 				idx := indexes[index.Name]
 				idx.Name = index.Name
@@ -43,6 +54,12 @@ func (schema *Schema) ParseIndexes() map[string]Index {
 				if idx.Comment == "" {
 					idx.Comment = index.Comment
 				}
+				if idx.Parser == "" {
+					idx.Parser = index.Parser
+				}
+				idx.Deferred = idx.Deferred || index.Deferred
+				idx.Manual = idx.Manual || index.Manual
+				idx.NullsNotDistinct = idx.NullsNotDistinct || index.NullsNotDistinct
 				idx.Fields = append(idx.Fields, index.Fields...)
 				indexes[index.Name] = idx
 			}
@@ -74,43 +91,63 @@ func parseFieldIndexes(field *Field) (indexes []Index) {
 		if value != "" {
 			v := strings.Split(value, ":")
 			k := strings.TrimSpace(strings.ToUpper(v[0]))
-			if k == "INDEX" || k == "UNIQUE_INDEX" {
+			if k == "INDEX" || k == "UNIQUE_INDEX" || k == "UNIQUEINDEX" {
 				var (
-					name      string
-					tag       = strings.Join(v[1:], ":")
-					idx       = strings.Index(tag, ",")
-					settings  = ParseTagSetting(tag, ",")
-					length, _ = strconv.Atoi(settings["LENGTH"])
+					tag = strings.Join(v[1:], ":")
+					idx = strings.Index(tag, ",")
 				)
 
 				if idx == -1 {
 					idx = len(tag)
 				}
 
-				if idx != -1 {
-					name = tag[0:idx]
-				}
+				// settings follow the name, so names like `where` or `type` won't be taken as settings
+				var (
+					name      = tag[0:idx]
+					settings  = ParseTagSetting(strings.TrimPrefix(tag[idx:], ","), ",")
+					length, _ = strconv.Atoi(settings["LENGTH"])
+				)
 
 				if name == "" {
 					name = field.Schema.namer.IndexName(field.Schema.Table, field.Name)
 				}
 
-				if (k == "UNIQUE_INDEX") || settings["UNIQUE"] != "" {
+				if k == "UNIQUE_INDEX" || k == "UNIQUEINDEX" || settings["UNIQUE"] != "" {
 					settings["CLASS"] = "UNIQUE"
 				}
 
+				// only index rows not soft deleted, schemas not soft deletable are rejected by checkSoftDeleteIndexes, e.g: `uniqueIndex:,softDelete`
+				_, softDelete := settings["SOFTDELETE"]
+				if softDelete {
+					if condition := field.Schema.SoftDeleteCondition(); condition != "" {
+						if settings["WHERE"] == "" {
+							settings["WHERE"] = condition
+						} else {
+							settings["WHERE"] = "(" + settings["WHERE"] + ") AND " + condition
+						}
+					}
+				}
+
 				indexes = append(indexes, Index{
-					Name:    name,
-					Class:   settings["CLASS"],
-					Type:    settings["TYPE"],
-					Where:   settings["WHERE"],
-					Comment: settings["COMMENT"],
+					Name:     name,
+					Class:    settings["CLASS"],
+					Type:     settings["TYPE"],
+					Where:    settings["WHERE"],
+					Comment:  settings["COMMENT"],
+					Parser:   settings["PARSER"],
+					Deferred: settings["DEFERRED"] != "",
+					Manual:   settings["MANUAL"] != "",
+
+					NullsNotDistinct: settings["NULLSNOTDISTINCT"] != "",
+					softDelete:       softDelete,
 					Fields: []IndexOption{{
-						Field:      field,
-						Expression: settings["EXPRESSION"],
-						Sort:       settings["SORT"],
-						Collate:    settings["COLLATE"],
-						Length:     length,
+						Field:           field,
+						Expression:      settings["EXPRESSION"],
+						Sort:            settings["SORT"],
+						Nulls:           strings.ToUpper(settings["NULLS"]),
+						Collate:         settings["COLLATE"],
+						CollateProvider: strings.ToLower(settings["COLLATEPROVIDER"]),
+						Length:          length,
 					}},
 				})
 			}
@@ -119,3 +156,29 @@ func parseFieldIndexes(field *Field) (indexes []Index) {
 
 	return
 }
+
+// checkSoftDeleteIndexes soft delete aware indexes require a soft deletable schema, otherwise they'd filter on a missing column
+func (schema *Schema) checkSoftDeleteIndexes() error {
+	if schema.SoftDeleteCondition() != "" {
+		return nil
+	}
+
+	for _, field := range schema.Fields {
+		for _, index := range parseFieldIndexes(field) {
+			if index.softDelete {
+				return fmt.Errorf("invalid soft delete index %v on field %v, %v isn't soft deletable", index.Name, field.Name, schema)
+			}
+		}
+	}
+	return nil
+}
+
+// SoftDeleteCondition condition of rows not soft deleted, e.g: deleted_at IS NULL, returns blank if the schema isn't soft deletable
+func (schema *Schema) SoftDeleteCondition() string {
+	for _, field := range schema.Fields {
+		if _, ok := reflect.New(field.IndirectFieldType).Interface().(DeleteClausesInterface); ok && field.DBName != "" {
+			return field.DBName + " IS NULL"
+		}
+	}
+	return ""
+}
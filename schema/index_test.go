@@ -2,22 +2,31 @@ package schema_test
 
 import (
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 
+	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
 )
 
 type UserIndex struct {
 	Name         string `gorm:"index"`
 	Name2        string `gorm:"index:idx_name,unique"`
-	Name3        string `gorm:"index:,sort:desc,collate:utf8,type:btree,length:10,where:name3 != 'jinzhu'"`
+	Name3        string `gorm:"index:,sort:desc,nulls:last,collate:utf8,collateProvider:ICU,type:btree,length:10,where:name3 != 'jinzhu'"`
 	Name4        string `gorm:"unique_index"`
-	Name5        int64  `gorm:"index:,class:FULLTEXT,comment:hello \\, world,where:age > 10"`
+	Name5        int64  `gorm:"index:,class:FULLTEXT,parser:ngram,comment:hello \\, world,where:age > 10"`
 	Name6        int64  `gorm:"index:profile,comment:hello \\, world,where:age > 10"`
 	Age          int64  `gorm:"index:profile,expression:ABS(age)"`
 	OID          int64  `gorm:"index:idx_id"`
 	MemberNumber string `gorm:"index:idx_id"`
+	Email        string `gorm:"uniqueIndex:,softDelete"`
+	Code         string `gorm:"uniqueIndex:idx_code,where:code <> '',softDelete"`
+	Bio          string `gorm:"index:,deferred"`
+	Tags         string `gorm:"index:idx_tags,manual"`
+	Kind         string `gorm:"index:where"`
+	Serial       string `gorm:"uniqueIndex:,nullsNotDistinct"`
+	DeletedAt    gorm.DeletedAt
 }
 
 func TestParseIndex(t *testing.T) {
@@ -41,9 +50,11 @@ func TestParseIndex(t *testing.T) {
 			Type:  "btree",
 			Where: "name3 != 'jinzhu'",
 			Fields: []schema.IndexOption{{
-				Sort:    "desc",
-				Collate: "utf8",
-				Length:  10,
+				Sort:            "desc",
+				Nulls:           "LAST",
+				Collate:         "utf8",
+				CollateProvider: "icu",
+				Length:          10,
 			}},
 		},
 		"idx_user_indices_name4": {
@@ -54,6 +65,7 @@ func TestParseIndex(t *testing.T) {
 		"idx_user_indices_name5": {
 			Name:    "idx_user_indices_name5",
 			Class:   "FULLTEXT",
+			Parser:  "ngram",
 			Comment: "hello , world",
 			Where:   "age > 10",
 			Fields:  []schema.IndexOption{{}},
@@ -70,6 +82,38 @@ func TestParseIndex(t *testing.T) {
 			Name:   "idx_id",
 			Fields: []schema.IndexOption{{}, {}},
 		},
+		"idx_user_indices_email": {
+			Name:   "idx_user_indices_email",
+			Class:  "UNIQUE",
+			Where:  "deleted_at IS NULL",
+			Fields: []schema.IndexOption{{}},
+		},
+		"idx_code": {
+			Name:   "idx_code",
+			Class:  "UNIQUE",
+			Where:  "(code <> '') AND deleted_at IS NULL",
+			Fields: []schema.IndexOption{{}},
+		},
+		"idx_user_indices_bio": {
+			Name:     "idx_user_indices_bio",
+			Deferred: true,
+			Fields:   []schema.IndexOption{{}},
+		},
+		"idx_tags": {
+			Name:   "idx_tags",
+			Manual: true,
+			Fields: []schema.IndexOption{{}},
+		},
+		"where": {
+			Name:   "where",
+			Fields: []schema.IndexOption{{}},
+		},
+		"idx_user_indices_serial": {
+			Name:             "idx_user_indices_serial",
+			Class:            "UNIQUE",
+			NullsNotDistinct: true,
+			Fields:           []schema.IndexOption{{}},
+		},
 	}
 
 	indices := user.ParseIndexes()
@@ -80,7 +124,7 @@ func TestParseIndex(t *testing.T) {
 			t.Fatalf("Failed to found index %v from parsed indices %+v", k, indices)
 		}
 
-		for _, name := range []string{"Name", "Class", "Type", "Where", "Comment"} {
+		for _, name := range []string{"Name", "Class", "Type", "Where", "Comment", "Parser", "Deferred", "Manual", "NullsNotDistinct"} {
 			if reflect.ValueOf(result).FieldByName(name).Interface() != reflect.ValueOf(v).FieldByName(name).Interface() {
 				t.Errorf(
 					"index %v %v should equal, expects %v, got %v",
@@ -91,7 +135,7 @@ func TestParseIndex(t *testing.T) {
 
 		for idx, ef := range result.Fields {
 			rf := v.Fields[idx]
-			for _, name := range []string{"Expression", "Sort", "Collate", "Length"} {
+			for _, name := range []string{"Expression", "Sort", "Nulls", "Collate", "CollateProvider", "Length"} {
 				if reflect.ValueOf(ef).FieldByName(name).Interface() != reflect.ValueOf(rf).FieldByName(name).Interface() {
 					t.Errorf(
 						"index %v field #%v's %v should equal, expects %v, got %v", k, idx+1, name,
@@ -102,3 +146,13 @@ func TestParseIndex(t *testing.T) {
 		}
 	}
 }
+
+type UserSoftDeleteIndex struct {
+	Email string `gorm:"uniqueIndex:,softDelete"`
+}
+
+func TestParseSoftDeleteIndexWithoutDeletedAt(t *testing.T) {
+	if _, err := schema.Parse(&UserSoftDeleteIndex{}, &sync.Map{}, schema.NamingStrategy{}); err == nil || !strings.Contains(err.Error(), "isn't soft deletable") {
+		t.Errorf("soft delete index of schema not soft deletable should be rejected, got %v", err)
+	}
+}
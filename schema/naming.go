@@ -20,6 +20,19 @@ type Namer interface {
 	IndexName(table, column string) string
 }
 
+// UniqueNamer namers could implement it to name unique constraints, NamingStrategy names them otherwise
+type UniqueNamer interface {
+	UniqueName(table, column string) string
+}
+
+// UniqueNameOf unique constraint name generated by namer, falls back to NamingStrategy if namer doesn't implement UniqueNamer
+func UniqueNameOf(namer Namer, table, column string) string {
+	if uniqueNamer, ok := namer.(UniqueNamer); ok {
+		return uniqueNamer.UniqueName(table, column)
+	}
+	return NamingStrategy{}.UniqueName(table, column)
+}
+
 // NamingStrategy tables, columns naming strategy
 type NamingStrategy struct {
 	TablePrefix   string
@@ -54,6 +67,11 @@ func (ns NamingStrategy) CheckerName(table, column string) string {
 	return fmt.Sprintf("chk_%s_%s", table, column)
 }
 
+// UniqueName generate unique constraint name
+func (ns NamingStrategy) UniqueName(table, column string) string {
+	return fmt.Sprintf("uni_%s_%s", table, column)
+}
+
 // IndexName generate index name
 func (ns NamingStrategy) IndexName(table, column string) string {
 	idxName := fmt.Sprintf("idx_%v_%v", table, toDBName(column))
@@ -66,6 +66,11 @@ func (schema *Schema) parseRelation(field *Field) {
 		}
 	)
 
+	if len(relation.primaryKeys) == 0 {
+		// references could also be declared with the constraint, e.g. `constraint:OnDelete:CASCADE,References:Code`
+		relation.primaryKeys = toColumns(ParseTagSetting(field.TagSettings["CONSTRAINT"], ",")["REFERENCES"])
+	}
+
 	if relation.FieldSchema, err = Parse(fieldValue, schema.cacheStore, schema.namer); err != nil {
 		schema.err = err
 		return
@@ -311,6 +316,9 @@ func (schema *Schema) guessRelation(relation *Relationship, field *Field, guessH
 			if f := primarySchema.LookUpField(primaryKey); f != nil {
 				if len(primaryFields) < idx+1 {
 					primaryFields = append(primaryFields, f)
+				} else if len(relation.foreignKeys) == 0 && !guessHas {
+					// foreign keys were guessed, prefer the declared references
+					primaryFields[idx] = f
 				} else if f != primaryFields[idx] {
 					reguessOrErr("unsupported relations %v for %v on field %v with primary keys %v", relation.FieldSchema, schema, field.Name, relation.primaryKeys)
 					return
@@ -359,6 +367,8 @@ type Constraint struct {
 	References      []*Field
 	OnDelete        string
 	OnUpdate        string
+	NotEnforced     bool   // e.g: `constraint:OnDelete:CASCADE,NotEnforced`
+	IndexName       string // backing index of foreign keys, e.g: `constraint:OnDelete:CASCADE,Index:idx_orders_user`
 }
 
 func (rel *Relationship) ParseConstraint() *Constraint {
@@ -380,13 +390,16 @@ func (rel *Relationship) ParseConstraint() *Constraint {
 	}
 
 	constraint := Constraint{
-		Name:     name,
-		Field:    rel.Field,
-		OnUpdate: settings["ONUPDATE"],
-		OnDelete: settings["ONDELETE"],
-		Schema:   rel.Schema,
+		Name:      name,
+		Field:     rel.Field,
+		OnUpdate:  settings["ONUPDATE"],
+		OnDelete:  settings["ONDELETE"],
+		Schema:    rel.Schema,
+		IndexName: settings["INDEX"],
 	}
 
+	_, constraint.NotEnforced = settings["NOTENFORCED"]
+
 	for _, ref := range rel.References {
 		if ref.PrimaryKey != nil && !ref.OwnPrimaryKey {
 			constraint.ForeignKeys = append(constraint.ForeignKeys, ref.ForeignKey)
@@ -55,6 +55,59 @@ func TestBelongsToOverrideReferences(t *testing.T) {
 	})
 }
 
+func TestBelongsToReferencesWithGuessedForeignKey(t *testing.T) {
+	type Profile struct {
+		gorm.Model
+		Code string `gorm:"unique"`
+	}
+
+	type User struct {
+		gorm.Model
+		Profile   Profile `gorm:"References:Code"`
+		ProfileID string
+	}
+
+	checkStructRelation(t, &User{}, Relation{
+		Name: "Profile", Type: schema.BelongsTo, Schema: "User", FieldSchema: "Profile",
+		References: []Reference{{"Code", "Profile", "ProfileID", "User", "", false}},
+	})
+}
+
+func TestConstraintReferences(t *testing.T) {
+	type Profile struct {
+		gorm.Model
+		Code string `gorm:"unique"`
+	}
+
+	type User struct {
+		gorm.Model
+		Profile     Profile `gorm:"ForeignKey:ProfileCode;constraint:OnDelete:CASCADE,References:Code"`
+		ProfileCode string
+	}
+
+	s, err := schema.Parse(&User{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse user, got error %v", err)
+	}
+
+	constraint := s.Relationships.Relations["Profile"].ParseConstraint()
+	if constraint == nil {
+		t.Fatalf("failed to parse constraint")
+	}
+
+	if len(constraint.References) != 1 || constraint.References[0].DBName != "code" {
+		t.Errorf("constraint should reference profiles(code), got %+v", constraint.References)
+	}
+
+	if constraint.ForeignKeys[0].DataType != schema.String {
+		t.Errorf("foreign key should use the data type of the referenced column, got %v", constraint.ForeignKeys[0].DataType)
+	}
+
+	if constraint.OnDelete != "CASCADE" {
+		t.Errorf("constraint on delete should be CASCADE, got %v", constraint.OnDelete)
+	}
+}
+
 func TestHasOneOverrideForeignKey(t *testing.T) {
 	type Profile struct {
 		gorm.Model
@@ -203,6 +203,10 @@ func Parse(dest interface{}, cacheStore *sync.Map, namer Namer) (*Schema, error)
 		}
 	}
 
+	if err := schema.checkSoftDeleteIndexes(); err != nil {
+		return schema, err
+	}
+
 	cacheStore.Store(modelType, schema)
 
 	// parse relations for unidentified fields
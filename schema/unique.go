@@ -0,0 +1,75 @@
+package schema
+
+import (
+	"strings"
+)
+
+type UniqueConstraint struct {
+	Name              string
+	Fields            []*Field
+	Deferrable        bool   // DEFERRABLE
+	InitiallyDeferred bool   // DEFERRABLE INITIALLY DEFERRED
+	IndexTablespace   string // tablespace of the backing index, e.g: USING INDEX TABLESPACE fast_ssd (Postgres)
+	LegacyName        string // explicit name declared on an embedded struct before it was scoped to the table, see Check.LegacyName
+}
+
+// ParseUniqueConstraints parse schema unique constraints, e.g: `gorm:"uniqueConstraint:uni_items_sort,deferrable,initially_deferred,indexTablespace:fast_ssd"`
+func (schema *Schema) ParseUniqueConstraints() map[string]UniqueConstraint {
+	var uniques = map[string]UniqueConstraint{}
+	for _, field := range schema.Fields {
+		if value, ok := field.TagSettings["UNIQUECONSTRAINT"]; ok && field.DBName != "" {
+			var name, legacyName, tablespace string
+			var deferrable, initiallyDeferred bool
+			for _, option := range strings.Split(value, ",") {
+				switch option = strings.TrimSpace(option); strings.ToUpper(option) {
+				case "UNIQUECONSTRAINT":
+				case "DEFERRABLE":
+					deferrable = true
+				case "INITIALLY_DEFERRED", "INITIALLYDEFERRED":
+					deferrable, initiallyDeferred = true, true
+				default:
+					if values := strings.SplitN(option, ":", 2); len(values) == 2 && strings.ToUpper(strings.TrimSpace(values[0])) == "INDEXTABLESPACE" {
+						tablespace = strings.TrimSpace(values[1])
+					} else {
+						name = option
+					}
+				}
+			}
+
+			if name == "" {
+				name = UniqueNameOf(schema.namer, schema.Table, field.DBName)
+			} else {
+				declared := name
+				name = schema.embeddedConstraintName(field, name, func(table, column string) string {
+					return UniqueNameOf(schema.namer, table, column)
+				})
+				legacyName = legacyConstraintName(name, declared)
+			}
+
+			unique := uniques[name]
+			unique.Name = name
+			unique.Fields = append(unique.Fields, field)
+			unique.Deferrable = unique.Deferrable || deferrable
+			unique.InitiallyDeferred = unique.InitiallyDeferred || initiallyDeferred
+			if unique.IndexTablespace == "" {
+				unique.IndexTablespace = tablespace
+			}
+			unique.LegacyName = legacyName
+			uniques[name] = unique
+		}
+	}
+
+	legacyNames := map[string]int{}
+	for _, unique := range uniques {
+		legacyNames[unique.LegacyName]++
+	}
+
+	for name, unique := range uniques {
+		if legacyNames[unique.LegacyName] > 1 {
+			// declared by a struct embedded several times, the legacy name can't tell them apart
+			unique.LegacyName = ""
+			uniques[name] = unique
+		}
+	}
+	return uniques
+}
@@ -0,0 +1,83 @@
+package schema_test
+
+import (
+	"sync"
+	"testing"
+
+	"gorm.io/gorm/schema"
+)
+
+type UserUnique struct {
+	Name      string `gorm:"uniqueConstraint"`
+	ListID    uint   `gorm:"uniqueConstraint:uni_list_sort,deferrable"`
+	SortOrder int    `gorm:"uniqueConstraint:uni_list_sort"`
+	Position  int    `gorm:"uniqueConstraint:uni_position,initially_deferred,indexTablespace:fast_ssd"`
+}
+
+func TestParseUniqueConstraints(t *testing.T) {
+	user, err := schema.Parse(&UserUnique{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse user unique, got error %v", err)
+	}
+
+	results := map[string]schema.UniqueConstraint{
+		"uni_user_uniques_name": {Name: "uni_user_uniques_name", Fields: []*schema.Field{{DBName: "name"}}},
+		"uni_list_sort":         {Name: "uni_list_sort", Fields: []*schema.Field{{DBName: "list_id"}, {DBName: "sort_order"}}, Deferrable: true},
+		"uni_position":          {Name: "uni_position", Fields: []*schema.Field{{DBName: "position"}}, Deferrable: true, InitiallyDeferred: true, IndexTablespace: "fast_ssd"},
+	}
+
+	uniques := user.ParseUniqueConstraints()
+	if len(uniques) != len(results) {
+		t.Fatalf("should have %v unique constraints, but got %v", len(results), len(uniques))
+	}
+
+	for k, result := range results {
+		v, ok := uniques[k]
+		if !ok {
+			t.Fatalf("Failed to found unique constraint %v from parsed constraints %+v", k, uniques)
+		}
+
+		if v.Name != result.Name || v.Deferrable != result.Deferrable || v.InitiallyDeferred != result.InitiallyDeferred || v.IndexTablespace != result.IndexTablespace {
+			t.Errorf("unique constraint %v should equal, expects %+v, got %+v", k, result, v)
+		}
+
+		if len(v.Fields) != len(result.Fields) {
+			t.Fatalf("unique constraint %v should have %v fields, but got %v", k, len(result.Fields), len(v.Fields))
+		}
+
+		for idx, field := range result.Fields {
+			if v.Fields[idx].DBName != field.DBName {
+				t.Errorf("unique constraint %v field %v should be %v, but got %v", k, idx, field.DBName, v.Fields[idx].DBName)
+			}
+		}
+	}
+}
+
+// legacyNamer namer implemented before UniqueNamer, hides UniqueName of NamingStrategy
+type legacyNamer struct {
+	schema.Namer
+}
+
+type uniqueNamer struct {
+	schema.NamingStrategy
+}
+
+func (uniqueNamer) UniqueName(table, column string) string {
+	return "uq_" + table + "_" + column
+}
+
+func TestParseUniqueConstraintsWithNamer(t *testing.T) {
+	for namer, name := range map[schema.Namer]string{
+		legacyNamer{schema.NamingStrategy{}}: "uni_user_uniques_name",
+		uniqueNamer{}:                        "uq_user_uniques_name",
+	} {
+		user, err := schema.Parse(&UserUnique{}, &sync.Map{}, namer)
+		if err != nil {
+			t.Fatalf("failed to parse user unique, got error %v", err)
+		}
+
+		if _, ok := user.ParseUniqueConstraints()[name]; !ok {
+			t.Errorf("unique constraint should be named %v by %T, got %+v", name, namer, user.ParseUniqueConstraints())
+		}
+	}
+}
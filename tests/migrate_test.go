@@ -1,15 +1,31 @@
 package tests_test
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
 	"math/rand"
+	"reflect"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
 	. "gorm.io/gorm/utils/tests"
 )
 
+// newMigrator migrator of the dialect running with db and the options of config
+func newMigrator(db *gorm.DB, config migrator.Config) migrator.Migrator {
+	return db.Migrator().(migrator.ConfigInterface).WithConfig(config).(migrator.Migrator)
+}
+
 func TestMigrate(t *testing.T) {
 	allModels := []interface{}{&User{}, &Account{}, &Pet{}, &Company{}, &Toy{}, &Language{}}
 	rand.Seed(time.Now().UnixNano())
@@ -63,6 +79,52 @@ func TestTable(t *testing.T) {
 	}
 }
 
+func TestCreateTableLike(t *testing.T) {
+	if name := DB.Dialector.Name(); name == "sqlite" || name == "sqlserver" {
+		t.Skip("skip sqlite, sqlserver due to it doesn't support CREATE TABLE LIKE")
+	}
+
+	type LikeTableStruct struct {
+		ID   uint
+		Name string `gorm:"size:100;index"`
+	}
+
+	DB.Migrator().DropTable(&LikeTableStruct{}, "like_table_struct_archives")
+	DB.AutoMigrate(&LikeTableStruct{})
+
+	if err := DB.Migrator().(migrator.TableInterface).CreateTableLike("like_table_struct_archives", &LikeTableStruct{}); err != nil {
+		t.Fatalf("Failed to create table like, got error %v", err)
+	}
+
+	if !DB.Migrator().HasTable("like_table_struct_archives") || !DB.Table("like_table_struct_archives").Migrator().HasColumn(&LikeTableStruct{}, "name") {
+		t.Fatalf("should found table created like another table")
+	}
+}
+
+func TestCreateTableAs(t *testing.T) {
+	if DB.Dialector.Name() == "sqlserver" {
+		t.Skip("skip sqlserver due to it uses SELECT INTO")
+	}
+
+	type AsTableStruct struct {
+		ID   uint
+		Name string
+	}
+
+	DB.Migrator().DropTable(&AsTableStruct{}, "as_table_struct_snapshots")
+	DB.AutoMigrate(&AsTableStruct{})
+	DB.Create(&[]AsTableStruct{{Name: "create_table_as"}, {Name: "create_table_as_2"}})
+
+	if err := DB.Migrator().(migrator.TableInterface).CreateTableAs("as_table_struct_snapshots", DB.Table("as_table_structs").Select("id, name").Where("name = ?", "create_table_as")); err != nil {
+		t.Fatalf("Failed to create table as, got error %v", err)
+	}
+
+	var count int64
+	if err := DB.Table("as_table_struct_snapshots").Count(&count).Error; err != nil || count != 1 {
+		t.Errorf("table should be created from query results, got %v, error %v", count, err)
+	}
+}
+
 func TestIndexes(t *testing.T) {
 	type IndexStruct struct {
 		gorm.Model
@@ -113,6 +175,129 @@ func TestIndexes(t *testing.T) {
 	}
 }
 
+func TestMigrateIndexComment(t *testing.T) {
+	if name := DB.Dialector.Name(); name == "sqlite" || name == "sqlserver" {
+		t.Skip("skip sqlite, sqlserver due to it doesn't support index comments")
+	}
+
+	type IndexCommentStruct struct {
+		ID   uint
+		Name string `gorm:"size:100;index:idx_index_comment_structs_name,comment:lookup by name"`
+	}
+
+	DB.Migrator().DropTable(&IndexCommentStruct{})
+	if err := DB.AutoMigrate(&IndexCommentStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if err := DB.AutoMigrate(&IndexCommentStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate again, got error %v", err)
+	}
+
+	indexes, err := DB.Migrator().(migrator.IndexesInterface).GetIndexes(&IndexCommentStruct{})
+	if err != nil {
+		t.Fatalf("Failed to get indexes, got error %v", err)
+	}
+
+	var found bool
+	for _, idx := range indexes {
+		if idx.Name == "idx_index_comment_structs_name" {
+			found = true
+			if idx.Comment != "lookup by name" || len(idx.Columns) != 1 || idx.Columns[0] != "name" {
+				t.Errorf("index should have comment and column name, got %+v", idx)
+			}
+		}
+	}
+
+	if !found {
+		t.Errorf("Failed to find index idx_index_comment_structs_name, got %+v", indexes)
+	}
+}
+
+func TestMigrateSoftDeleteUniqueIndex(t *testing.T) {
+	if name := DB.Dialector.Name(); name == "mysql" || name == "sqlserver" {
+		t.Skip("skip mysql, sqlserver due to it doesn't support partial indexes")
+	}
+
+	type SoftDeleteUniqueStruct struct {
+		gorm.Model
+		Email string `gorm:"size:100"`
+	}
+
+	type SoftDeleteUniqueStruct2 struct {
+		gorm.Model
+		Email string `gorm:"size:100;uniqueIndex:idx_soft_delete_unique_structs_email,softDelete"`
+	}
+
+	DB.Migrator().DropTable(&SoftDeleteUniqueStruct{})
+	if err := DB.AutoMigrate(&SoftDeleteUniqueStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if err := DB.Table("soft_delete_unique_structs").AutoMigrate(&SoftDeleteUniqueStruct2{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if !DB.Table("soft_delete_unique_structs").Migrator().HasIndex(&SoftDeleteUniqueStruct2{}, "idx_soft_delete_unique_structs_email") {
+		t.Fatalf("unique index should be created for existing table")
+	}
+
+	value := SoftDeleteUniqueStruct{Email: "soft_delete_unique@example.org"}
+	DB.Create(&value)
+	if err := DB.Create(&SoftDeleteUniqueStruct{Email: value.Email}).Error; err == nil {
+		t.Errorf("should failed to create duplicated email")
+	}
+
+	DB.Delete(&value)
+	if err := DB.Create(&SoftDeleteUniqueStruct{Email: value.Email}).Error; err != nil {
+		t.Errorf("should be able to reuse email of soft deleted record, got error %v", err)
+	}
+}
+
+func TestMigrateFullTextIndex(t *testing.T) {
+	type FullTextStruct struct {
+		ID      uint
+		Title   string `gorm:"size:200;index:idx_full_text_structs_content,class:FULLTEXT"`
+		Content string `gorm:"index:idx_full_text_structs_content,class:FULLTEXT"`
+		Views   int    `gorm:"index:idx_full_text_structs_views,class:FULLTEXT"`
+	}
+
+	if err := DB.Migrator().(migrator.FullTextIndexInterface).CreateFullTextIndex(&FullTextStruct{}, "idx_full_text_structs_views"); err == nil || !strings.Contains(err.Error(), "requires text columns") {
+		t.Errorf("fulltext index on non-text column should be rejected, got %v", err)
+	}
+
+	if name := DB.Dialector.Name(); name != "mysql" && name != "postgres" {
+		t.Skip("skip fulltext index test, only mysql, postgres support fulltext indexes")
+	}
+
+	type FullTextStruct2 struct {
+		ID      uint
+		Title   string `gorm:"size:200;index:idx_full_text_structs_content,class:FULLTEXT"`
+		Content string `gorm:"index:idx_full_text_structs_content,class:FULLTEXT"`
+	}
+
+	DB.Migrator().DropTable("full_text_structs")
+	if err := DB.Table("full_text_structs").AutoMigrate(&FullTextStruct2{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	indexes, err := DB.Table("full_text_structs").Migrator().(migrator.IndexesInterface).GetIndexes(&FullTextStruct2{})
+	if err != nil {
+		t.Fatalf("Failed to get indexes, got error %v", err)
+	}
+
+	var found bool
+	for _, idx := range indexes {
+		if idx.Name == "idx_full_text_structs_content" {
+			found = idx.Class == "FULLTEXT"
+		}
+	}
+
+	if !found {
+		t.Errorf("fulltext index should be created, got %+v", indexes)
+	}
+}
+
 func TestColumns(t *testing.T) {
 	type ColumnStruct struct {
 		gorm.Model
@@ -184,6 +369,14 @@ func TestColumns(t *testing.T) {
 		t.Fatalf("Failed to found renamed column")
 	}
 
+	if err := DB.Table("column_structs").Migrator().RenameColumn(&NewColumnStruct{}, "NewName", "new_new_name"); err == nil {
+		t.Fatalf("Should fail to rename a column that doesn't exist")
+	}
+
+	if err := DB.Table("column_structs").Migrator().RenameColumn(&NewColumnStruct{}, "new_new_name", "name"); err == nil {
+		t.Fatalf("Should fail to rename a column to an existing column")
+	}
+
 	if err := DB.Table("column_structs").Migrator().DropColumn(&NewColumnStruct{}, "new_new_name"); err != nil {
 		t.Fatalf("Failed to add column, got %v", err)
 	}
@@ -192,3 +385,5492 @@ func TestColumns(t *testing.T) {
 		t.Fatalf("Found deleted column")
 	}
 }
+
+type ConstraintActionCompany struct {
+	ID   uint
+	Name string
+}
+
+type ConstraintActionUser struct {
+	ID        uint
+	CompanyID uint
+	Company   ConstraintActionCompany `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+type ConstraintActionUser2 struct {
+	ID        uint
+	CompanyID uint
+	Company   ConstraintActionCompany `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+}
+
+func (ConstraintActionUser2) TableName() string {
+	return "constraint_action_users"
+}
+
+func TestAutoMigrateWithResult(t *testing.T) {
+	type ResultStruct struct {
+		ID   uint
+		Name string
+	}
+
+	type ResultStruct2 struct {
+		ID    uint
+		Name  string
+		Email string
+	}
+
+	DB.Migrator().DropTable(&ResultStruct{})
+	result, err := DB.Migrator().(migrator.AutoMigrateInterface).AutoMigrateWithResult(&ResultStruct{})
+	if err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if len(result.Tables) != 1 || result.Tables[0].Table != "result_structs" || result.Count("create_table") != 1 {
+		t.Errorf("result should contains created table, got %+v", result)
+	}
+
+	if result, err = DB.Table("result_structs").Migrator().(migrator.AutoMigrateInterface).AutoMigrateWithResult(&ResultStruct2{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if result.Count("add_column") != 1 || result.Tables[0].Operations[0].Name != "email" || result.Unchanged() != 0 {
+		t.Errorf("result should contains added column, got %+v", result)
+	}
+
+	if result, err = DB.Table("result_structs").Migrator().(migrator.AutoMigrateInterface).AutoMigrateWithResult(&ResultStruct2{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if result.Unchanged() != 1 {
+		t.Errorf("table should be unchanged, got %+v", result)
+	}
+}
+
+type RenameIndexStruct2 struct {
+	ID       uint
+	Nickname string `gorm:"size:100;index"`
+}
+
+func (RenameIndexStruct2) TableName() string {
+	return "rename_index_structs"
+}
+
+type RenameConstraintStruct struct {
+	ID   uint
+	Age  int    `gorm:"check:chk_rename_constraint_structs_age,age > 0"`
+	Code string `gorm:"size:100;uniqueConstraint:uni_rename_constraint_structs_code"`
+}
+
+type RenameConstraintStruct2 struct {
+	ID   uint
+	Age  int    `gorm:"check:chk_rename_constraint_structs_positive_age,age > 0"`
+	Code string `gorm:"size:100;uniqueConstraint:uni_rename_constraint_structs_unique_code"`
+}
+
+func (RenameConstraintStruct2) TableName() string {
+	return "rename_constraint_structs"
+}
+
+func TestRenameConstraint(t *testing.T) {
+	if DB.Dialector.Name() != "postgres" && DB.Dialector.Name() != "mysql" {
+		t.Skip("skip dialects other than postgres and mysql, which rename constraints in place or recreate them")
+	}
+
+	DB.Migrator().DropTable(&RenameConstraintStruct{})
+	if err := DB.AutoMigrate(&RenameConstraintStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	recorder := &recordSQLLogger{Interface: DB.Logger}
+	m := DB.Session(&gorm.Session{Context: context.Background(), Logger: recorder}).Migrator()
+	for oldName, newName := range map[string]string{
+		"chk_rename_constraint_structs_age":  "chk_rename_constraint_structs_positive_age",
+		"uni_rename_constraint_structs_code": "uni_rename_constraint_structs_unique_code",
+	} {
+		recorder.sqls = nil
+		if err := m.(migrator.RenameConstraintInterface).RenameConstraint(&RenameConstraintStruct2{}, oldName, newName); err != nil {
+			t.Fatalf("failed to rename constraint %v, got error %v", oldName, err)
+		}
+
+		if m.HasConstraint(&RenameConstraintStruct2{}, oldName) || !m.HasConstraint(&RenameConstraintStruct2{}, newName) {
+			t.Errorf("constraint %v should be renamed to %v", oldName, newName)
+		}
+
+		// mysql adds the constraint with the new name before dropping the old one
+		if DB.Dialector.Name() == "mysql" && (len(recorder.sqls) < 2 || !strings.Contains(recorder.sqls[0], "ADD CONSTRAINT `"+newName+"`") || !strings.Contains(recorder.sqls[1], "DROP")) {
+			t.Errorf("constraint %v should be recreated before dropping it, got %v", oldName, recorder.sqls)
+		}
+	}
+
+	DB.Migrator().DropTable(&RenameConstraintStruct{})
+}
+
+func TestRenameIndexedColumn(t *testing.T) {
+	type RenameIndexStruct struct {
+		ID   uint
+		Name string `gorm:"size:100;index"`
+	}
+
+	DB.Migrator().DropTable(&RenameIndexStruct{})
+	if err := DB.AutoMigrate(&RenameIndexStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+	DB.Create(&RenameIndexStruct{Name: "rename_indexed_column"})
+
+	if err := DB.Migrator().RenameColumn(&RenameIndexStruct2{}, "name", "Nickname"); err != nil {
+		t.Fatalf("Failed to rename column, got error %v", err)
+	}
+
+	if !DB.Migrator().HasIndex(&RenameIndexStruct2{}, "idx_rename_index_structs_nickname") {
+		t.Errorf("index should be renamed with column")
+	}
+
+	if DB.Migrator().HasIndex(&RenameIndexStruct2{}, "idx_rename_index_structs_name") {
+		t.Errorf("index with old column name should be renamed")
+	}
+
+	var result RenameIndexStruct2
+	if err := DB.Where("nickname = ?", "rename_indexed_column").First(&result).Error; err != nil {
+		t.Errorf("should be able to query renamed indexed column, got error %v", err)
+	}
+}
+
+func TestMigrateConstraintActions(t *testing.T) {
+	if name := DB.Dialector.Name(); name == "sqlite" || name == "sqlserver" {
+		t.Skip("skip sqlite, sqlserver due to it doesn't support reflecting constraint actions")
+	}
+
+	DB.Migrator().DropTable(&ConstraintActionUser{}, &ConstraintActionCompany{})
+	if err := DB.AutoMigrate(&ConstraintActionUser{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	foreignKeyOf := func() migrator.ConstraintInfo {
+		constraints, err := DB.Migrator().(migrator.ConstraintsInterface).GetConstraints(&ConstraintActionUser2{})
+		if err != nil {
+			t.Fatalf("Failed to get constraints, got error %v", err)
+		}
+
+		for _, constraint := range constraints {
+			if constraint.Name == "fk_constraint_action_users_company" {
+				return constraint
+			}
+		}
+		t.Fatalf("constraint fk_constraint_action_users_company should be reflected, got %+v", constraints)
+		return migrator.ConstraintInfo{}
+	}
+
+	if constraint := foreignKeyOf(); constraint.OnDelete != "CASCADE" || constraint.OnUpdate != "CASCADE" {
+		t.Fatalf("constraint should be created with ON DELETE CASCADE ON UPDATE CASCADE, but got %+v", constraint)
+	}
+
+	m := newMigrator(DB, migrator.Config{RecreateChangedConstraintsWhenAutoMigrate: true})
+	if err := m.AutoMigrate(&ConstraintActionUser2{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if constraint := foreignKeyOf(); constraint.OnDelete != "SET NULL" || constraint.OnUpdate != "CASCADE" {
+		t.Errorf("constraint should be recreated with ON DELETE SET NULL ON UPDATE CASCADE, but got %+v", constraint)
+	}
+}
+
+func TestCreateConstraints(t *testing.T) {
+	if name := DB.Dialector.Name(); name == "sqlite" || name == "sqlserver" {
+		t.Skip("skip sqlite, sqlserver due to it doesn't support reflecting constraints")
+	}
+
+	type RepairCompany struct {
+		ID   uint
+		Name string
+	}
+
+	type RepairUser struct {
+		ID        uint
+		Age       int `gorm:"check:chk_repair_users_age,age > 0"`
+		CompanyID uint
+		Company   RepairCompany
+	}
+
+	DB.Migrator().DropTable(&RepairUser{}, &RepairCompany{})
+	if err := DB.AutoMigrate(&RepairUser{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	for _, name := range []string{"fk_repair_users_company", "chk_repair_users_age"} {
+		if err := DB.Migrator().DropConstraint(&RepairUser{}, name); err != nil {
+			t.Fatalf("Failed to drop constraint %v, got error %v", name, err)
+		}
+	}
+
+	if err := DB.Migrator().(migrator.ConstraintInterface).CreateConstraints(&RepairUser{}); err != nil {
+		t.Fatalf("Failed to create constraints, got error %v", err)
+	}
+
+	if err := DB.Migrator().(migrator.ConstraintInterface).CreateConstraints(&RepairUser{}); err != nil {
+		t.Fatalf("Failed to create constraints again, got error %v", err)
+	}
+
+	for _, name := range []string{"fk_repair_users_company", "chk_repair_users_age"} {
+		if !DB.Migrator().HasConstraint(&RepairUser{}, name) {
+			t.Errorf("constraint %v should be created", name)
+		}
+	}
+}
+
+func TestMigrateConstraintReferencesUniqueColumn(t *testing.T) {
+	if name := DB.Dialector.Name(); name == "sqlite" || name == "sqlserver" {
+		t.Skip("skip sqlite, sqlserver due to it doesn't support reflecting constraints")
+	}
+
+	type ReferenceCountry struct {
+		ID   uint
+		Code string `gorm:"size:8;unique"`
+	}
+
+	type ReferenceCity struct {
+		ID          uint
+		CountryCode string           `gorm:"size:8"`
+		Country     ReferenceCountry `gorm:"foreignKey:CountryCode;constraint:OnDelete:CASCADE,References:Code"`
+	}
+
+	DB.Migrator().DropTable(&ReferenceCity{}, &ReferenceCountry{})
+	if err := DB.AutoMigrate(&ReferenceCity{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if !DB.Migrator().HasConstraint(&ReferenceCity{}, "fk_reference_cities_country") {
+		t.Fatalf("Failed to find foreign key referencing unique column")
+	}
+
+	if err := DB.Create(&ReferenceCity{Country: ReferenceCountry{Code: "NL"}}).Error; err != nil {
+		t.Fatalf("Failed to create city, got error %v", err)
+	}
+
+	var city ReferenceCity
+	if err := DB.Preload("Country").First(&city).Error; err != nil || city.CountryCode != "NL" || city.Country.Code != "NL" {
+		t.Errorf("Failed to load city with referenced country, got %+v, error %v", city, err)
+	}
+}
+
+func TestMigrateNoInheritCheckConstraint(t *testing.T) {
+	type NoInheritCheck struct {
+		ID    uint
+		Price int `gorm:"check:chk_no_inherit_checks_price,price > 0 NO INHERIT"`
+	}
+
+	DB.Migrator().DropTable(&NoInheritCheck{})
+	if err := DB.AutoMigrate(&NoInheritCheck{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if DB.Dialector.Name() == "postgres" {
+		var noInherit bool
+		if err := DB.Raw("SELECT connoinherit FROM pg_constraint WHERE conname = ?", "chk_no_inherit_checks_price").Row().Scan(&noInherit); err != nil || !noInherit {
+			t.Errorf("check constraint should be created with NO INHERIT, got %v, error %v", noInherit, err)
+		}
+	}
+}
+
+func TestMigrateReflectFoldedIdentifiers(t *testing.T) {
+	if name := DB.Dialector.Name(); name == "sqlite" || name == "sqlserver" {
+		t.Skip("skip sqlite, sqlserver due to it doesn't support reflecting constraints")
+	}
+
+	type MixedCase struct {
+		ID uint
+	}
+
+	DB.Exec("DROP TABLE IF EXISTS MixedCases")
+	if err := DB.Exec("CREATE TABLE MixedCases (id int, CONSTRAINT MixedCheck CHECK (id > 0))").Error; err != nil {
+		t.Fatalf("Failed to create table, got error %v", err)
+	}
+
+	if !DB.Table("MixedCases").Migrator().HasConstraint(&MixedCase{}, "MixedCheck") {
+		t.Errorf("Failed to find constraint created with unquoted mixed case name")
+	}
+}
+
+func TestMigrateMultiColumnCheckConstraint(t *testing.T) {
+	type CheckPeriod struct {
+		ID        uint
+		StartDate time.Time `gorm:"check:chk_check_periods_dates,start_date < end_date"`
+		EndDate   time.Time
+	}
+
+	DB.Migrator().DropTable(&CheckPeriod{})
+	for i := 0; i < 2; i++ {
+		if err := DB.AutoMigrate(&CheckPeriod{}); err != nil {
+			t.Fatalf("Failed to auto migrate %v times, got error %v", i+1, err)
+		}
+	}
+
+	if name := DB.Dialector.Name(); name == "sqlite" || name == "sqlserver" {
+		t.Skip("skip sqlite, sqlserver due to it doesn't support reflecting check constraints")
+	}
+
+	if !DB.Migrator().HasConstraint(&CheckPeriod{}, "chk_check_periods_dates") {
+		t.Fatalf("Failed to find created check constraint")
+	}
+
+	m := newMigrator(DB, migrator.Config{RecreateChangedConstraintsWhenAutoMigrate: true})
+	for i := 0; i < 2; i++ {
+		if err := m.AutoMigrate(&CheckPeriod{}); err != nil {
+			t.Fatalf("Failed to auto migrate again, got error %v", err)
+		}
+	}
+
+	if !DB.Migrator().HasConstraint(&CheckPeriod{}, "chk_check_periods_dates") {
+		t.Fatalf("Failed to find check constraint after migrating again")
+	}
+}
+
+func TestMigrateDataTypeHook(t *testing.T) {
+	type DataTypeHookStruct struct {
+		ID   uint
+		Name string
+	}
+
+	m := newMigrator(DB, migrator.Config{DataTypeHook: func(field *schema.Field, dataType string) string {
+		if field.DataType == schema.String {
+			return "varchar(300)"
+		}
+		return dataType
+	}})
+
+	DB.Migrator().DropTable(&DataTypeHookStruct{})
+	if err := m.CreateTable(&DataTypeHookStruct{}); err != nil {
+		t.Fatalf("Failed to create table, got error %v", err)
+	}
+
+	columnTypes, err := DB.Migrator().ColumnTypes(&DataTypeHookStruct{})
+	if err != nil {
+		t.Fatalf("no error should returns for ColumnTypes, but got %v", err)
+	}
+
+	for _, columnType := range columnTypes {
+		if columnType.Name() == "name" && !strings.Contains(strings.ToUpper(columnType.DatabaseTypeName()), "VARCHAR") {
+			t.Errorf("column type should be rewritten by data type hook, but got %v", columnType.DatabaseTypeName())
+		}
+	}
+}
+
+func TestMigrateExplicitExoticTypes(t *testing.T) {
+	if name := DB.Dialector.Name(); name != "sqlite" && name != "postgres" {
+		t.Skip("skip exotic types test, only sqlite, postgres accept interval, inet types")
+	}
+
+	type ExoticTypeStruct struct {
+		ID       uint
+		Duration string `gorm:"type:interval day to second"`
+		Address  string `gorm:"type:inet"`
+	}
+
+	DB.Migrator().DropTable(&ExoticTypeStruct{})
+	if err := DB.Exec("CREATE TABLE exotic_type_structs (id integer PRIMARY KEY, duration interval, address inet)").Error; err != nil {
+		t.Fatalf("Failed to create table, got error %v", err)
+	}
+
+	if err := DB.AutoMigrate(&ExoticTypeStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	columnTypes, err := DB.Migrator().ColumnTypes(&ExoticTypeStruct{})
+	if err != nil {
+		t.Fatalf("no error should returns for ColumnTypes, but got %v", err)
+	}
+
+	for _, columnType := range columnTypes {
+		switch columnType.Name() {
+		case "duration":
+			if !strings.EqualFold(columnType.DatabaseTypeName(), "interval") {
+				t.Errorf("column duration shouldn't be altered, got %v", columnType.DatabaseTypeName())
+			}
+		case "address":
+			if !strings.EqualFold(columnType.DatabaseTypeName(), "inet") {
+				t.Errorf("column address shouldn't be altered, got %v", columnType.DatabaseTypeName())
+			}
+		}
+	}
+}
+
+func TestMigrateAlterColumnCollation(t *testing.T) {
+	if DB.Dialector.Name() != "postgres" {
+		t.Skip("skip collation test, only postgres alters collation with base migrator")
+	}
+
+	type CollationStruct struct {
+		ID   uint
+		Name string `gorm:"index"`
+	}
+
+	type CollationStruct2 struct {
+		ID   uint
+		Name string `gorm:"type:text COLLATE \"C\";index:idx_collation_structs_name"`
+	}
+
+	DB.Migrator().DropTable(&CollationStruct{})
+	if err := DB.AutoMigrate(&CollationStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if err := DB.Table("collation_structs").Migrator().AlterColumn(&CollationStruct2{}, "Name"); err != nil {
+		t.Fatalf("Failed to alter column collation, got error %v", err)
+	}
+
+	if !DB.Migrator().HasIndex(&CollationStruct{}, "idx_collation_structs_name") {
+		t.Errorf("index should still exist after reindexing")
+	}
+}
+
+type GeneratedColumnStruct struct {
+	ID       uint
+	Price    int
+	Quantity int
+	Total    int `gorm:"generated:price * quantity STORED;index"`
+}
+
+type GeneratedColumnStruct2 struct {
+	ID       uint
+	Price    int
+	Quantity int
+	Total    int `gorm:"generated:price * quantity + 1 STORED;index:idx_generated_column_structs_total"`
+}
+
+func (GeneratedColumnStruct2) TableName() string {
+	return "generated_column_structs"
+}
+
+type GeneratedColumnStruct3 struct {
+	ID       uint
+	Price    int
+	Quantity int
+	Total    int `gorm:"generated:price * quantity VIRTUAL"`
+}
+
+func (GeneratedColumnStruct3) TableName() string {
+	return "generated_column_structs"
+}
+
+func TestMigrateGeneratedColumn(t *testing.T) {
+	if DB.Dialector.Name() == "sqlserver" {
+		t.Skip("skip sqlserver due to it uses computed columns syntax")
+	}
+
+	DB.Migrator().DropTable(&GeneratedColumnStruct{})
+	if err := DB.AutoMigrate(&GeneratedColumnStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	value := GeneratedColumnStruct{Price: 3, Quantity: 5, Total: 1}
+	if err := DB.Create(&value).Error; err != nil {
+		t.Fatalf("Failed to create with generated column, got error %v", err)
+	}
+
+	var result GeneratedColumnStruct
+	if err := DB.First(&result, value.ID).Error; err != nil || result.Total != 15 {
+		t.Errorf("generated column should be computed by database, got %v, error %v", result.Total, err)
+	}
+
+	if stored, err := DB.Migrator().(migrator.GenerationStoredInterface).GenerationStoredOf(&GeneratedColumnStruct{}, "Total"); errors.Is(err, gorm.ErrNotImplemented) {
+		t.Skipf("skip %v, which doesn't reflect generated columns", DB.Dialector.Name())
+	} else if err != nil || !stored {
+		t.Errorf("generated column should be stored, got %v, error %v", stored, err)
+	}
+
+	if name := DB.Dialector.Name(); name == "sqlite" {
+		// sqlite can't add stored generated columns with ALTER TABLE, toggling them requires rebuilding the table
+		DB.Migrator().DropTable(&GeneratedColumnStruct3{})
+		if err := DB.AutoMigrate(&GeneratedColumnStruct3{}); err != nil {
+			t.Fatalf("Failed to auto migrate, got error %v", err)
+		}
+
+		if stored, err := DB.Migrator().(migrator.GenerationStoredInterface).GenerationStoredOf(&GeneratedColumnStruct3{}, "Total"); err != nil || stored {
+			t.Errorf("generated column should be virtual, got %v, error %v", stored, err)
+		}
+		return
+	}
+
+	m := newMigrator(DB, migrator.Config{RecreateGeneratedColumnsWhenAutoMigrate: true})
+	if name := DB.Dialector.Name(); name == "mysql" {
+		result, err := m.AutoMigrateWithResult(&GeneratedColumnStruct3{})
+		if err != nil || result.Count("recreate_column") != 1 {
+			t.Fatalf("Failed to recreate generated column as virtual, got %+v, error %v", result, err)
+		}
+
+		if stored, err := DB.Migrator().(migrator.GenerationStoredInterface).GenerationStoredOf(&GeneratedColumnStruct3{}, "Total"); err != nil || stored {
+			t.Errorf("generated column should be virtual, got %v, error %v", stored, err)
+		}
+	}
+
+	if err := m.AutoMigrate(&GeneratedColumnStruct2{}); err != nil {
+		t.Fatalf("Failed to auto migrate changed generated column, got error %v", err)
+	}
+
+	if err := DB.Table("generated_column_structs").First(&result, value.ID).Error; err != nil || result.Total != 16 {
+		t.Errorf("generated column should be recreated with new expression, got %v, error %v", result.Total, err)
+	}
+
+	if !DB.Migrator().HasIndex(&GeneratedColumnStruct2{}, "idx_generated_column_structs_total") {
+		t.Errorf("index on generated column should be recreated")
+	}
+}
+
+func TestMigrateHasColumnType(t *testing.T) {
+	type ColumnTypeStruct struct {
+		ID     uint
+		Name   string `gorm:"type:varchar(100)"`
+		Notes  string `gorm:"type:text"`
+		Active bool
+	}
+
+	DB.Migrator().DropTable(&ColumnTypeStruct{})
+	if err := DB.AutoMigrate(&ColumnTypeStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if has, err := DB.Migrator().(migrator.ColumnInterface).HasColumnType(&ColumnTypeStruct{}, "Name", "character varying(100)"); err != nil || !has {
+		t.Errorf("column name should be varchar, got %v, error %v", has, err)
+	}
+
+	if has, err := DB.Migrator().(migrator.ColumnInterface).HasColumnType(&ColumnTypeStruct{}, "name", "integer"); err != nil || has {
+		t.Errorf("column name shouldn't be integer, got %v, error %v", has, err)
+	}
+
+	if has, err := DB.Migrator().(migrator.ColumnInterface).HasColumnType(&ColumnTypeStruct{}, "missing", "varchar"); err != nil || has {
+		t.Errorf("missing column shouldn't has type, got %v, error %v", has, err)
+	}
+
+	// only names of the same data type are aliases
+	if has, err := DB.Migrator().(migrator.ColumnInterface).HasColumnType(&ColumnTypeStruct{}, "notes", "longtext"); err != nil || has {
+		t.Errorf("column notes shouldn't be longtext, got %v, error %v", has, err)
+	}
+
+	if has, err := DB.Migrator().(migrator.ColumnInterface).HasColumnType(&ColumnTypeStruct{}, "active", "tinyint"); err != nil || has != (DB.Dialector.Name() == "mysql") {
+		t.Errorf("column active should be tinyint only on mysql, got %v, error %v", has, err)
+	}
+}
+
+func TestMigrateArrayColumn(t *testing.T) {
+	if DB.Dialector.Name() != "postgres" {
+		t.Skip("skip array column test, only postgres supports array types")
+	}
+
+	type ArrayColumnStruct struct {
+		ID   uint
+		Tags []int64 `gorm:"array"`
+	}
+
+	DB.Migrator().DropTable(&ArrayColumnStruct{})
+	for i := 0; i < 2; i++ {
+		if err := DB.AutoMigrate(&ArrayColumnStruct{}); err != nil {
+			t.Fatalf("Failed to auto migrate array column, got error %v", err)
+		}
+	}
+
+	columnTypes, err := DB.Migrator().ColumnTypes(&ArrayColumnStruct{})
+	if err != nil {
+		t.Fatalf("no error should returns for ColumnTypes, but got %v", err)
+	}
+
+	for _, columnType := range columnTypes {
+		if columnType.Name() == "tags" && columnType.DatabaseTypeName() != "_INT8" {
+			t.Errorf("tags should be created as bigint array, but got %v", columnType.DatabaseTypeName())
+		}
+	}
+}
+
+func TestDropIndexIfExists(t *testing.T) {
+	type DropIndexStruct struct {
+		gorm.Model
+		Name string `gorm:"size:255;index"`
+	}
+
+	DB.Migrator().DropTable(&DropIndexStruct{})
+	DB.AutoMigrate(&DropIndexStruct{})
+
+	for i := 0; i < 2; i++ {
+		if err := DB.Migrator().(migrator.IndexInterface).DropIndexIfExists(&DropIndexStruct{}, "Name"); err != nil {
+			t.Fatalf("Failed to drop index if exists, got err %v", err)
+		}
+	}
+
+	if DB.Migrator().HasIndex(&DropIndexStruct{}, "Name") {
+		t.Fatalf("Should not find index for name after drop")
+	}
+}
+
+func TestMigrateValidate(t *testing.T) {
+	type ValidateStruct struct {
+		ID   uint
+		Name string `gorm:"size:100;index:idx_validate_structs_name"`
+	}
+
+	type ValidateStruct2 struct {
+		ID    uint
+		Name  string `gorm:"size:100;index:idx_validate_structs_name"`
+		Email string `gorm:"size:100;index:idx_validate_structs_email"`
+	}
+
+	DB.Migrator().DropTable(&ValidateStruct{})
+	if err := DB.Migrator().(migrator.AutoMigrateInterface).Validate(&ValidateStruct{}); err == nil || !strings.Contains(err.Error(), "table validate_structs is missing") {
+		t.Fatalf("should report missing table, but got %v", err)
+	}
+
+	if err := DB.AutoMigrate(&ValidateStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if err := DB.Migrator().(migrator.AutoMigrateInterface).Validate(&ValidateStruct{}); err != nil {
+		t.Fatalf("should not report discrepancies after auto migrate, but got %v", err)
+	}
+
+	err := DB.Table("validate_structs").Migrator().(migrator.AutoMigrateInterface).Validate(&ValidateStruct2{})
+	validateErr, ok := err.(migrator.ValidateError)
+	if !ok {
+		t.Fatalf("should returns ValidateError, but got %v", err)
+	}
+
+	if len(validateErr.Discrepancies) != 2 || !strings.Contains(err.Error(), "column validate_structs.email is missing") || !strings.Contains(err.Error(), "index idx_validate_structs_email on validate_structs is missing") {
+		t.Errorf("should report missing column and index, but got %v", err)
+	}
+}
+
+type ShadowColumnStruct struct {
+	ID   uint
+	Code int
+}
+
+type ShadowColumnStruct2 struct {
+	ID   uint
+	Code string `gorm:"size:100;index:idx_shadow_column_structs_code"`
+}
+
+func (ShadowColumnStruct2) TableName() string {
+	return "shadow_column_structs"
+}
+
+// shadowTriggersDialector sqlite dialector whose migrator keeps shadow columns in sync with triggers
+type shadowTriggersDialector struct {
+	gorm.Dialector
+}
+
+func (dialector shadowTriggersDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return shadowTriggersMigrator{dialector.Dialector.Migrator(db)}
+}
+
+type shadowTriggersMigrator struct {
+	gorm.Migrator
+}
+
+func (shadowTriggersMigrator) ShadowSyncTriggers(stmt *gorm.Statement, sourceOf func(column string) string, column, shadow, primaryKey string) ([]migrator.ShadowSyncTrigger, error) {
+	var triggers []migrator.ShadowSyncTrigger
+	for _, event := range []string{"INSERT", "UPDATE OF " + stmt.Quote(column)} {
+		trigger := stmt.Quote("trg_" + stmt.Table + "_" + shadow + "_" + strings.ToLower(strings.Fields(event)[0]))
+		triggers = append(triggers, migrator.ShadowSyncTrigger{
+			Create: "CREATE TRIGGER " + trigger + " AFTER " + event + " ON " + stmt.Quote(stmt.Table) + " BEGIN UPDATE " + stmt.Quote(stmt.Table) +
+				" SET " + stmt.Quote(shadow) + " = " + sourceOf("NEW."+stmt.Quote(column)) + " WHERE " + stmt.Quote(primaryKey) + " = NEW." + stmt.Quote(primaryKey) + "; END",
+			Drop: "DROP TRIGGER IF EXISTS " + trigger,
+		})
+	}
+	return triggers, nil
+}
+
+// shadowMigratorOf migrator creating shadow triggers with shadowTriggersDialector on sqlite, other dialects create them with their migrators
+func shadowMigratorOf(db *gorm.DB) migrator.Migrator {
+	if db.Dialector.Name() == "sqlite" {
+		db = db.Session(&gorm.Session{})
+		db.Dialector = shadowTriggersDialector{db.Dialector}
+	}
+	return migrator.Migrator{Config: migrator.Config{DB: db, Dialector: db.Dialector}}
+}
+
+func TestShadowAlterColumn(t *testing.T) {
+	DB.Migrator().DropTable(&ShadowColumnStruct{})
+	if err := DB.AutoMigrate(&ShadowColumnStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	for i := 1; i <= 5; i++ {
+		DB.Create(&ShadowColumnStruct{Code: i * 10})
+	}
+
+	if err := shadowMigratorOf(DB).ShadowAlterColumn(&ShadowColumnStruct2{}, "Code", 0); err == nil {
+		t.Errorf("should returns error for invalid batch size")
+	}
+
+	if err := shadowMigratorOf(DB).ShadowAlterColumn(&ShadowColumnStruct2{}, "Code", 2); err != nil {
+		t.Fatalf("Failed to migrate column with shadow column, got error %v", err)
+	}
+
+	if DB.Migrator().HasColumn(&ShadowColumnStruct2{}, "code_new") {
+		t.Errorf("shadow column should be renamed into place")
+	}
+
+	if !DB.Migrator().HasIndex(&ShadowColumnStruct2{}, "idx_shadow_column_structs_code") {
+		t.Errorf("index on migrated column should be recreated")
+	}
+
+	columnTypes, _ := DB.Migrator().ColumnTypes(&ShadowColumnStruct2{})
+	for _, columnType := range columnTypes {
+		if columnType.Name() == "code" && strings.Contains(strings.ToUpper(columnType.DatabaseTypeName()), "INT") {
+			t.Errorf("column type should be changed, but got %v", columnType.DatabaseTypeName())
+		}
+	}
+
+	var results []ShadowColumnStruct2
+	DB.Order("id").Find(&results)
+	if len(results) != 5 || results[0].Code != "10" || results[4].Code != "50" {
+		t.Errorf("column values should be backfilled, but got %+v", results)
+	}
+}
+
+type ShadowWriteStruct struct {
+	ID   uint
+	Code int
+}
+
+type ShadowWriteStruct2 struct {
+	ID   uint
+	Code string `gorm:"size:100;not null;default:none"`
+}
+
+func (ShadowWriteStruct2) TableName() string {
+	return "shadow_write_structs"
+}
+
+// backfillWriteConnPool runs write once the first batch of the backfill is executed, as the application writing meanwhile
+type backfillWriteConnPool struct {
+	gorm.ConnPool
+	write func()
+}
+
+func (pool *backfillWriteConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := pool.ConnPool.ExecContext(ctx, query, args...)
+	if write := pool.write; write != nil && strings.HasPrefix(query, "UPDATE") && strings.Contains(query, "code_new") {
+		pool.write = nil
+		write()
+	}
+	return result, err
+}
+
+func (pool *backfillWriteConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	return pool.ConnPool.(gorm.TxBeginner).BeginTx(ctx, opts)
+}
+
+func TestShadowAlterColumnWithWrites(t *testing.T) {
+	DB.Migrator().DropTable(&ShadowWriteStruct{})
+	if err := DB.AutoMigrate(&ShadowWriteStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	for i := 1; i <= 5; i++ {
+		DB.Create(&ShadowWriteStruct{Code: i * 10})
+	}
+
+	tx := DB.Session(&gorm.Session{Context: context.Background()})
+	tx.Statement.ConnPool = &backfillWriteConnPool{ConnPool: tx.Statement.ConnPool, write: func() {
+		// the row is backfilled already, the inserted one is out of the range to backfill
+		DB.Model(&ShadowWriteStruct{}).Where("id = ?", 1).Update("code", 11)
+		DB.Create(&ShadowWriteStruct{Code: 60})
+	}}
+
+	if err := shadowMigratorOf(tx).ShadowAlterColumn(&ShadowWriteStruct2{}, "Code", 5); err != nil {
+		t.Fatalf("Failed to migrate column with shadow column, got error %v", err)
+	}
+
+	var results []ShadowWriteStruct2
+	DB.Order("id").Find(&results)
+	if len(results) != 6 || results[0].Code != "11" || results[4].Code != "50" || results[5].Code != "60" {
+		t.Errorf("writes during the backfill should be kept, but got %+v", results)
+	}
+
+	if triggers, err := DB.Migrator().(migrator.TriggersInterface).GetTriggers(&ShadowWriteStruct2{}); (err != nil && !errors.Is(err, gorm.ErrNotImplemented)) || len(triggers) != 0 {
+		t.Errorf("sync triggers should be dropped, got %+v, error %v", triggers, err)
+	}
+
+	if err := DB.Exec("INSERT INTO shadow_write_structs (id, code) VALUES (100, NULL)").Error; err == nil {
+		t.Errorf("migrated column should be NOT NULL")
+	}
+
+	if err := DB.Exec("INSERT INTO shadow_write_structs (id) VALUES (101)").Error; err != nil {
+		t.Fatalf("Failed to insert row without the migrated column, got error %v", err)
+	}
+
+	var result ShadowWriteStruct2
+	if DB.First(&result, 101); result.Code != "none" {
+		t.Errorf("migrated column should keep the default, got %+v", result)
+	}
+}
+
+func TestCreateInheritedTable(t *testing.T) {
+	type InheritParentStruct struct {
+		ID   uint
+		Name string
+	}
+
+	type InheritChildStruct struct {
+		ID   uint
+		Name string
+		Age  int
+	}
+
+	DB.Migrator().DropTable(&InheritChildStruct{}, &InheritParentStruct{})
+	if err := DB.Migrator().CreateTable(&InheritParentStruct{}); err != nil {
+		t.Fatalf("Failed to create parent table, got error %v", err)
+	}
+
+	err := DB.Set("gorm:table_inherits", &InheritParentStruct{}).Migrator().CreateTable(&InheritChildStruct{})
+	if DB.Dialector.Name() != "postgres" {
+		if err != gorm.ErrNotImplemented {
+			t.Errorf("should returns ErrNotImplemented for table inheritance, but got %v", err)
+		}
+		return
+	}
+
+	if err != nil {
+		t.Fatalf("Failed to create inherited table, got error %v", err)
+	}
+
+	DB.Create(&InheritChildStruct{Name: "inherited", Age: 10})
+
+	var count int64
+	if DB.Model(&InheritParentStruct{}).Where("name = ?", "inherited").Count(&count); count != 1 {
+		t.Errorf("rows of inherited table should be visible from parent table, but got %v", count)
+	}
+}
+
+type ReorderColumnStruct struct {
+	ID   uint
+	Name string
+}
+
+type ReorderColumnStruct2 struct {
+	ID    uint
+	Email string
+	Name  string
+	Age   int
+}
+
+func (ReorderColumnStruct2) TableName() string {
+	return "reorder_column_structs"
+}
+
+func TestMigrateReorderColumns(t *testing.T) {
+	DB.Migrator().DropTable(&ReorderColumnStruct{})
+	if err := DB.AutoMigrate(&ReorderColumnStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	m := newMigrator(DB, migrator.Config{ReorderColumnsWhenAutoMigrate: true})
+	if err := m.AutoMigrate(&ReorderColumnStruct2{}); err != nil {
+		t.Fatalf("Failed to auto migrate with reordering columns, got error %v", err)
+	}
+
+	if DB.Dialector.Name() == "sqlite" {
+		return
+	}
+
+	columns, err := DB.Migrator().(migrator.ColumnOrderInterface).GetColumnOrder(&ReorderColumnStruct2{})
+	if err != nil {
+		t.Fatalf("Failed to get column order, got error %v", err)
+	}
+
+	expects := "id,email,name,age"
+	if DB.Dialector.Name() != "mysql" {
+		// columns can't be reordered, added columns are reflected after existing ones
+		expects = "id,name,email,age"
+	}
+
+	if strings.Join(columns, ",") != expects {
+		t.Errorf("columns should be ordered as %v, but got %v", expects, columns)
+	}
+}
+
+func TestBuildIndexOptionsWithNulls(t *testing.T) {
+	type NullsIndexStruct struct {
+		ID   uint
+		Name string `gorm:"index:idx_nulls_index_structs_name,sort:desc,nulls:last"`
+	}
+
+	stmt := &gorm.Statement{DB: DB}
+	if err := stmt.Parse(&NullsIndexStruct{}); err != nil {
+		t.Fatalf("failed to parse model, got error %v", err)
+	}
+
+	idx := stmt.Schema.LookIndex("idx_nulls_index_structs_name")
+	if idx == nil {
+		t.Fatalf("failed to find index")
+	}
+
+	m := newMigrator(DB, migrator.Config{})
+	opts := m.BuildIndexOptions(idx.Fields, stmt)
+	if len(opts) != 1 || !strings.HasSuffix(opts[0].(clause.Expr).SQL, " desc NULLS LAST") {
+		t.Errorf("NULLS ordering should be placed after sort, but got %v", opts)
+	}
+}
+
+type recordSQLLogger struct {
+	logger.Interface
+	sqls []string
+}
+
+func (l *recordSQLLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	sql, _ := fc()
+	l.sqls = append(l.sqls, sql)
+}
+
+// statementsOf recorded statements starting with the prefix, e.g: ALTER TABLE, reflection queries are skipped
+func (l *recordSQLLogger) statementsOf(prefix string) (sqls []string) {
+	for _, sql := range l.sqls {
+		if strings.HasPrefix(sql, prefix) {
+			sqls = append(sqls, sql)
+		}
+	}
+	return
+}
+
+type skipExecConnPool struct {
+	gorm.ConnPool
+}
+
+func (skipExecConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+
+func TestCreateNotEnforcedCheckConstraint(t *testing.T) {
+	type NotEnforcedCheck struct {
+		ID   uint
+		Name string `gorm:"check:lazy_name_checker,name <> 'jinzhu' NOT ENFORCED"`
+	}
+
+	switch DB.Dialector.Name() {
+	case "mysql", "postgres":
+	default:
+		recorder := &recordSQLLogger{Interface: DB.Logger}
+		tx := DB.Session(&gorm.Session{Context: context.Background(), Logger: recorder})
+		tx.Statement.ConnPool = skipExecConnPool{tx.Statement.ConnPool}
+
+		m := newMigrator(tx, migrator.Config{})
+		if err := m.CreateConstraint(&NotEnforcedCheck{}, "lazy_name_checker"); err != nil {
+			t.Fatalf("Failed to create check constraint, got error %v", err)
+		}
+
+		if len(recorder.sqls) != 1 || strings.Contains(recorder.sqls[0], "NOT ENFORCED") {
+			t.Errorf("NOT ENFORCED should be ignored by unsupported dialects, but got %v", recorder.sqls)
+		}
+		return
+	}
+
+	DB.Migrator().DropTable(&NotEnforcedCheck{})
+	if err := DB.Migrator().CreateTable(&NotEnforcedCheck{}); err != nil {
+		t.Fatalf("Failed to create table, got error %v", err)
+	}
+
+	if DB.Dialector.Name() == "postgres" && postgresVersionNum(t) < 180000 {
+		if err := DB.Create(&NotEnforcedCheck{Name: "jinzhu"}).Error; err == nil {
+			t.Errorf("check constraint should be created enforced by postgres before 18")
+		}
+		return
+	}
+
+	query := "SELECT conenforced FROM pg_constraint WHERE conrelid = 'not_enforced_checks'::regclass AND conname = ?"
+	if DB.Dialector.Name() == "mysql" {
+		query = "SELECT enforced = 'YES' FROM information_schema.table_constraints WHERE constraint_schema = DATABASE() AND table_name = 'not_enforced_checks' AND constraint_name = ?"
+	}
+
+	var enforced bool
+	if err := DB.Raw(query, "lazy_name_checker").Row().Scan(&enforced); err != nil {
+		t.Fatalf("check constraint should be created, got error %v", err)
+	} else if enforced {
+		t.Errorf("check constraint should be created with NOT ENFORCED")
+	}
+
+	if err := DB.Create(&NotEnforcedCheck{Name: "jinzhu"}).Error; err != nil {
+		t.Errorf("check constraint not enforced should accept violating rows, got error %v", err)
+	}
+}
+
+// postgresVersionNum server_version_num of postgres, e.g: 180000
+func postgresVersionNum(t *testing.T) (version int) {
+	if err := DB.Raw("SHOW server_version_num").Row().Scan(&version); err != nil {
+		t.Fatalf("failed to get server version, got error %v", err)
+	}
+	return
+}
+
+func TestCreateNotEnforcedForeignKey(t *testing.T) {
+	type NotEnforcedCompany struct {
+		ID   uint
+		Name string
+	}
+
+	type NotEnforcedUser struct {
+		ID        uint
+		CompanyID uint
+		Company   NotEnforcedCompany `gorm:"constraint:NotEnforced"`
+	}
+
+	DB.Migrator().DropTable(&NotEnforcedUser{}, &NotEnforcedCompany{})
+	if DB.Dialector.Name() != "postgres" {
+		m := newMigrator(DB, migrator.Config{})
+		if sql, _, err := m.BuildCreateTableSQL(&NotEnforcedUser{}); err != nil || strings.Contains(sql, "NOT ENFORCED") {
+			t.Errorf("NOT ENFORCED of foreign keys should be ignored by %v, got %v, error %v", DB.Dialector.Name(), sql, err)
+		}
+		t.Skip("skip dialects other than postgres, which creates foreign keys with NOT ENFORCED")
+	}
+
+	if err := DB.AutoMigrate(&NotEnforcedCompany{}, &NotEnforcedUser{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	// postgres before 18 rejects NOT ENFORCED, the foreign key is created enforced
+	err := DB.Create(&NotEnforcedUser{CompanyID: 42}).Error
+	if enforced := postgresVersionNum(t) < 180000; enforced != (err != nil) {
+		t.Errorf("foreign key should be enforced %v, got error %v", enforced, err)
+	}
+
+	DB.Migrator().DropTable(&NotEnforcedUser{}, &NotEnforcedCompany{})
+}
+
+func TestMigrateColumnStorage(t *testing.T) {
+	type ColumnStorageStruct struct {
+		ID      uint
+		Content string `gorm:"storage:external"`
+	}
+
+	DB.Migrator().DropTable(&ColumnStorageStruct{})
+	if err := DB.AutoMigrate(&ColumnStorageStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if DB.Dialector.Name() != "postgres" {
+		if err := DB.Migrator().(migrator.ColumnStorageInterface).SetColumnStorage(&ColumnStorageStruct{}, "Content", "MAIN"); err != gorm.ErrNotImplemented {
+			t.Errorf("should returns ErrNotImplemented for column storage, but got %v", err)
+		}
+		return
+	}
+
+	storager := DB.Migrator().(migrator.ColumnStorageInterface)
+	if storage, err := storager.ColumnStorageOf(&ColumnStorageStruct{}, "Content"); err != nil || storage != "EXTERNAL" {
+		t.Errorf("column storage should be EXTERNAL, but got %v, error %v", storage, err)
+	}
+
+	if err := DB.Migrator().(migrator.ColumnStorageInterface).SetColumnStorage(&ColumnStorageStruct{}, "Content", "MAIN"); err != nil {
+		t.Fatalf("Failed to set column storage, got error %v", err)
+	}
+
+	if err := DB.AutoMigrate(&ColumnStorageStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if storage, err := storager.ColumnStorageOf(&ColumnStorageStruct{}, "Content"); err != nil || storage != "EXTERNAL" {
+		t.Errorf("column storage should be reconciled to EXTERNAL, but got %v, error %v", storage, err)
+	}
+}
+
+func TestMigrateUserDefinedTypes(t *testing.T) {
+	if DB.Dialector.Name() != "postgres" {
+		if err := DB.Migrator().(migrator.TypeInterface).CreateType("email_address", migrator.TypeOption{Domain: true, Definition: "text"}); err != gorm.ErrNotImplemented {
+			t.Errorf("should returns ErrNotImplemented for user-defined types, but got %v", err)
+		}
+
+		if DB.Migrator().(migrator.TypeInterface).HasType("email_address") {
+			t.Errorf("should not find user-defined type")
+		}
+		return
+	}
+
+	type UserDefinedTypeStruct struct {
+		ID       uint
+		Email    string `gorm:"type:email_address"`
+		Location string `gorm:"type:geo_point"`
+	}
+
+	DB.Migrator().DropTable(&UserDefinedTypeStruct{})
+	DB.Migrator().(migrator.TypeInterface).DropType("email_address")
+	DB.Migrator().(migrator.TypeInterface).DropType("geo_point")
+
+	if err := DB.Migrator().(migrator.TypeInterface).CreateType("email_address", migrator.TypeOption{Domain: true, Definition: "text CHECK (VALUE LIKE '%@%')"}); err != nil {
+		t.Fatalf("Failed to create domain, got error %v", err)
+	}
+
+	if err := DB.Migrator().(migrator.TypeInterface).CreateType("geo_point", migrator.TypeOption{Definition: "(lat double precision, lng double precision)"}); err != nil {
+		t.Fatalf("Failed to create composite type, got error %v", err)
+	}
+
+	if !DB.Migrator().(migrator.TypeInterface).HasType("email_address") || !DB.Migrator().(migrator.TypeInterface).HasType("geo_point") {
+		t.Fatalf("Failed to find created types")
+	}
+
+	if err := DB.AutoMigrate(&UserDefinedTypeStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	result, err := newMigrator(DB, migrator.Config{MigrateColumnTypesWhenAutoMigrate: true}).AutoMigrateWithResult(&UserDefinedTypeStruct{})
+	if err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if result.Count("alter_column") != 0 {
+		t.Errorf("columns of user-defined types should not be altered, but got %+v", result)
+	}
+
+	DB.Migrator().DropTable(&UserDefinedTypeStruct{})
+	for _, name := range []string{"email_address", "geo_point"} {
+		if err := DB.Migrator().(migrator.TypeInterface).DropType(name); err != nil || DB.Migrator().(migrator.TypeInterface).HasType(name) {
+			t.Errorf("Failed to drop type %v, got error %v", name, err)
+		}
+	}
+}
+
+type SkipUnsupportedStruct struct {
+	ID   uint
+	Name string
+}
+
+type SkipUnsupportedStruct2 struct {
+	ID   uint
+	Name string `gorm:"check:skip_name_checker,name <> 'jinzhu'"`
+}
+
+func (SkipUnsupportedStruct2) TableName() string {
+	return "skip_unsupported_structs"
+}
+
+func TestMigrateSkipUnsupported(t *testing.T) {
+	DB.Migrator().DropTable(&SkipUnsupportedStruct{})
+	if err := DB.AutoMigrate(&SkipUnsupportedStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	m := newMigrator(DB, migrator.Config{CreateIndexAfterCreateTable: true, SkipUnsupported: true})
+	result, err := m.AutoMigrateWithResult(&SkipUnsupportedStruct2{})
+	if err != nil {
+		t.Fatalf("unsupported operations should be skipped, but got error %v", err)
+	}
+
+	if DB.Dialector.Name() == "sqlite" {
+		if result.Count("skip_create_constraint") != 1 || result.Count("create_constraint") != 0 {
+			t.Errorf("unsupported check constraint should be reported as skipped, but got %+v", result)
+		}
+	} else if result.Count("create_constraint") != 1 {
+		t.Errorf("check constraint should be created, but got %+v", result)
+	}
+}
+
+func TestCreateTableWithInlineIndexes(t *testing.T) {
+	type InlineIndexStruct struct {
+		ID    uint
+		Name  string `gorm:"size:100;uniqueIndex:idx_inline_index_structs_name"`
+		Email string `gorm:"size:100;index:idx_inline_index_structs_email,type:btree"`
+	}
+
+	recorder := &recordSQLLogger{Interface: DB.Logger}
+	tx := DB.Session(&gorm.Session{Context: context.Background(), Logger: recorder})
+	tx.Statement.ConnPool = skipExecConnPool{tx.Statement.ConnPool}
+
+	m := newMigrator(tx, migrator.Config{})
+	if err := m.CreateTable(&InlineIndexStruct{}); err != nil {
+		t.Fatalf("Failed to create table, got error %v", err)
+	}
+
+	if len(recorder.sqls) != 1 {
+		t.Fatalf("indexes should be created inline, but got %v", recorder.sqls)
+	}
+
+	if !regexp.MustCompile("UNIQUE INDEX .idx_inline_index_structs_name. \\(.name.\\)").MatchString(recorder.sqls[0]) {
+		t.Errorf("inline unique index should keep its class, but got %v", recorder.sqls[0])
+	}
+
+	if !regexp.MustCompile("INDEX .idx_inline_index_structs_email. USING btree \\(.email.\\)").MatchString(recorder.sqls[0]) {
+		t.Errorf("inline index should keep its type, but got %v", recorder.sqls[0])
+	}
+}
+
+func TestMigrateBinaryColumns(t *testing.T) {
+	type BinaryColumnStruct struct {
+		ID     uint
+		Small  []byte `gorm:"size:100"`
+		Medium []byte `gorm:"size:70000"`
+		Large  []byte `gorm:"size:16777216"`
+		Data   []byte
+	}
+
+	DB.Migrator().DropTable(&BinaryColumnStruct{})
+	if err := DB.AutoMigrate(&BinaryColumnStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	result, err := newMigrator(DB, migrator.Config{MigrateColumnTypesWhenAutoMigrate: true}).AutoMigrateWithResult(&BinaryColumnStruct{})
+	if err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if result.Count("alter_column") != 0 {
+		t.Errorf("binary columns should not be altered, but got %+v", result)
+	}
+
+	if DB.Dialector.Name() != "mysql" {
+		t.Skip("skip dialects other than mysql, which rejects collations of binary columns")
+	}
+
+	// mysql rejects collations of binary columns, which are dropped from the data type
+	DB.Migrator().DropTable(&BinaryColumnStruct{})
+	m := newMigrator(DB, migrator.Config{DataTypeHook: func(field *schema.Field, dataType string) string {
+		if field.DataType == schema.Bytes {
+			dataType += " COLLATE utf8mb4_bin"
+		}
+		return dataType
+	}})
+	if err := m.AutoMigrate(&BinaryColumnStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate binary columns with collation, got error %v", err)
+	}
+
+	for name, dataType := range map[string]string{"medium": "mediumblob", "large": "longblob"} {
+		var result string
+		DB.Raw("SELECT data_type FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?", "binary_column_structs", name).Row().Scan(&result)
+		if result != dataType {
+			t.Errorf("data type of %v should be %v, but got %v", name, dataType, result)
+		}
+	}
+}
+
+func TestMigratePreAndPostMigrate(t *testing.T) {
+	type MigrateHookStruct struct {
+		ID   uint
+		Name string
+	}
+
+	type MigrateHookLog struct {
+		ID    uint
+		Event string
+	}
+
+	DB.Migrator().DropTable(&MigrateHookStruct{}, &MigrateHookLog{})
+	if err := DB.AutoMigrate(&MigrateHookLog{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	m := newMigrator(DB, migrator.Config{
+		PreMigrate:  map[string][]string{"migrate_hook_structs": {"INSERT INTO migrate_hook_logs (event) VALUES ('pre')"}},
+		PostMigrate: map[string][]string{"migrate_hook_structs": {"INSERT INTO migrate_hook_logs (event) VALUES ('post')", "INSERT INTO migrate_hook_structs (name) VALUES ('post')"}},
+	})
+
+	if err := m.AutoMigrate(&MigrateHookStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	var events []string
+	DB.Model(&MigrateHookLog{}).Order("id").Pluck("event", &events)
+	if strings.Join(events, ",") != "pre,post" {
+		t.Errorf("pre and post migrate statements should be executed in order, but got %v", events)
+	}
+
+	var count int64
+	if DB.Model(&MigrateHookStruct{}).Where("name = ?", "post").Count(&count); count != 1 {
+		t.Errorf("post migrate statements should run after table migrated, but got %v", count)
+	}
+
+	m.PreMigrate = map[string][]string{"migrate_hook_structs": {"INSERT INTO not_exists_table (event) VALUES ('pre')"}}
+	if err := m.AutoMigrate(&MigrateHookStruct{}); err == nil {
+		t.Errorf("failed pre migrate statement should abort the migration")
+	}
+}
+
+type EnumColumnStruct struct {
+	ID   uint
+	Mood string `gorm:"enum:happy,sad"`
+}
+
+type EnumColumnStruct2 struct {
+	ID   uint
+	Mood string `gorm:"enum:happy,sad,ok"`
+}
+
+func (EnumColumnStruct2) TableName() string {
+	return "enum_column_structs"
+}
+
+type EnumColumnStruct3 struct {
+	ID   uint
+	Mood string `gorm:"enum:happy,ok"`
+}
+
+func (EnumColumnStruct3) TableName() string {
+	return "enum_column_structs"
+}
+
+func TestMigrateEnumColumn(t *testing.T) {
+	DB.Migrator().DropTable(&EnumColumnStruct{})
+	if DB.Dialector.Name() == "postgres" {
+		DB.Migrator().(migrator.TypeInterface).DropType("enum_column_structs_mood")
+	}
+
+	if err := DB.AutoMigrate(&EnumColumnStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if err := DB.Create(&EnumColumnStruct{Mood: "happy"}).Error; err != nil {
+		t.Errorf("should be able to create record with enum value, got error %v", err)
+	}
+
+	if err := DB.Create(&EnumColumnStruct{Mood: "angry"}).Error; err == nil {
+		t.Errorf("should not be able to create record with invalid enum value")
+	}
+
+	if DB.Dialector.Name() != "postgres" {
+		return
+	}
+
+	if !DB.Migrator().(migrator.TypeInterface).HasType("enum_column_structs_mood") {
+		t.Fatalf("native enum type should be created")
+	}
+
+	if DB.Migrator().HasConstraint(&EnumColumnStruct{}, "chk_enum_column_structs_mood_enum") {
+		t.Errorf("native enum should not be checked with check constraint")
+	}
+
+	result, err := newMigrator(DB, migrator.Config{MigrateColumnTypesWhenAutoMigrate: true}).AutoMigrateWithResult(&EnumColumnStruct2{})
+	if err != nil {
+		t.Fatalf("Failed to add enum value, got error %v", err)
+	}
+
+	if result.Count("alter_column") != 0 {
+		t.Errorf("enum column should not be altered, but got %+v", result)
+	}
+
+	if err := DB.Create(&EnumColumnStruct2{Mood: "ok"}).Error; err != nil {
+		t.Errorf("should be able to create record with added enum value, got error %v", err)
+	}
+
+	if err := DB.AutoMigrate(&EnumColumnStruct3{}); err == nil || !strings.Contains(err.Error(), "removing enum values is not supported") {
+		t.Errorf("removing enum values should returns error, but got %v", err)
+	}
+}
+
+type TimestampDefaultStruct struct {
+	ID       uint
+	Name     string    `gorm:"default:jinzhu"`
+	Active   bool      `gorm:"default:true"`
+	RecordAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+type TimestampDefaultStruct2 struct {
+	ID       uint
+	Name     string    `gorm:"default:hello"`
+	Active   bool      `gorm:"default:true"`
+	RecordAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (TimestampDefaultStruct2) TableName() string {
+	return "timestamp_default_structs"
+}
+
+type TimestampPrecisionDefaultStruct struct {
+	ID       uint
+	RecordAt time.Time `gorm:"type:timestamp(6);default:CURRENT_TIMESTAMP(6)"`
+}
+
+func TestMigrateTimestampDefault(t *testing.T) {
+	models := []interface{}{&TimestampDefaultStruct{}}
+	if DB.Dialector.Name() == "mysql" || DB.Dialector.Name() == "postgres" {
+		models = append(models, &TimestampPrecisionDefaultStruct{})
+	}
+
+	DB.Migrator().DropTable(models...)
+	if err := DB.AutoMigrate(models...); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	m := newMigrator(DB, migrator.Config{MigrateDefaultValuesWhenAutoMigrate: true})
+	for i := 0; i < 2; i++ {
+		result, err := m.AutoMigrateWithResult(models...)
+		if err != nil {
+			t.Fatalf("Failed to auto migrate, got error %v", err)
+		}
+
+		if result.Count("alter_column_default") != 0 {
+			t.Errorf("unchanged defaults should not be altered, but got %+v", result)
+		}
+	}
+
+	// the sqlite driver can't alter columns with defaults
+	if DB.Dialector.Name() == "sqlite" {
+		return
+	}
+
+	result, err := m.AutoMigrateWithResult(&TimestampDefaultStruct2{})
+	if err != nil {
+		t.Fatalf("Failed to auto migrate changed default, got error %v", err)
+	}
+
+	if result.Count("alter_column_default") != 1 {
+		t.Errorf("changed default should be altered, but got %+v", result)
+	}
+
+	if live, err := m.ColumnDefaultOf(&TimestampDefaultStruct2{}, "Name"); err != nil || !strings.Contains(live, "hello") {
+		t.Errorf("column default should be reflected from the current schema, got %v, error %v", live, err)
+	}
+}
+
+type AfterTableCreatedStruct struct {
+	ID   uint
+	Name string
+}
+
+func (AfterTableCreatedStruct) AfterTableCreated(tx *gorm.DB) error {
+	return tx.Exec("INSERT INTO after_table_created_structs (name) VALUES (?)", "created").Error
+}
+
+func TestAfterTableCreated(t *testing.T) {
+	DB.Migrator().DropTable(&AfterTableCreatedStruct{})
+	for i := 0; i < 2; i++ {
+		if err := DB.AutoMigrate(&AfterTableCreatedStruct{}); err != nil {
+			t.Fatalf("Failed to auto migrate, got error %v", err)
+		}
+	}
+
+	var count int64
+	if DB.Model(&AfterTableCreatedStruct{}).Where("name = ?", "created").Count(&count); count != 1 {
+		t.Errorf("AfterTableCreated should be called once when table created, but got %v", count)
+	}
+}
+
+func TestSetColumnDefaultSequence(t *testing.T) {
+	type SequenceDefaultStruct struct {
+		ID   uint `gorm:"primarykey;autoIncrement:false"`
+		Code int64
+		Name string
+	}
+
+	DB.Migrator().DropTable(&SequenceDefaultStruct{})
+	if err := DB.AutoMigrate(&SequenceDefaultStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if DB.Dialector.Name() != "postgres" {
+		if err := DB.Migrator().(migrator.SequenceInterface).SetColumnDefaultSequence(&SequenceDefaultStruct{}, "Code", "sequence_default_structs_code_seq"); err != gorm.ErrNotImplemented {
+			t.Errorf("should returns ErrNotImplemented for sequence default, but got %v", err)
+		}
+		return
+	}
+
+	DB.Create(&SequenceDefaultStruct{ID: 1, Code: 10, Name: "existing"})
+	if err := DB.Migrator().(migrator.SequenceInterface).SetColumnDefaultSequence(&SequenceDefaultStruct{}, "Code", "sequence_default_structs_code_seq"); err != nil {
+		t.Fatalf("Failed to set column default sequence, got error %v", err)
+	}
+
+	if err := DB.Exec("INSERT INTO sequence_default_structs (id, name) VALUES (2, 'generated')").Error; err != nil {
+		t.Fatalf("Failed to insert with sequence default, got error %v", err)
+	}
+
+	var result SequenceDefaultStruct
+	DB.First(&result, "name = ?", "generated")
+	if result.Code != 11 {
+		t.Errorf("sequence should start above existing max value, but got %v", result.Code)
+	}
+}
+
+type ColumnChangeStruct struct {
+	ID   uint
+	Age  int
+	Name string
+}
+
+type ColumnChangeStruct2 struct {
+	ID   uint
+	Age  string
+	Name string
+}
+
+func (ColumnChangeStruct2) TableName() string {
+	return "column_change_structs"
+}
+
+type ColumnChangeStruct3 struct {
+	ID   uint
+	Age  int
+	Name int
+}
+
+func (ColumnChangeStruct3) TableName() string {
+	return "column_change_structs"
+}
+
+func TestMigrateColumnChangeRisk(t *testing.T) {
+	DB.Migrator().DropTable(&ColumnChangeStruct{})
+	if err := DB.AutoMigrate(&ColumnChangeStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	var changes []migrator.ColumnChange
+	m := newMigrator(DB, migrator.Config{})
+	if err := m.AutoMigrate(&ColumnChangeStruct2{}); err != nil || len(changes) != 0 {
+		t.Errorf("column types should be left alone unless MigrateColumnTypesWhenAutoMigrate, but got %+v, error %v", changes, err)
+	}
+
+	m = newMigrator(DB, migrator.Config{MigrateColumnTypesWhenAutoMigrate: true, ColumnChangeHook: func(change migrator.ColumnChange) error {
+		changes = append(changes, change)
+		return errors.New("blocked")
+	}})
+
+	if err := m.AutoMigrate(&ColumnChangeStruct2{}); err == nil || err.Error() != "blocked" {
+		t.Errorf("column change hook should block the change, but got %v", err)
+	}
+
+	if len(changes) != 1 || changes[0].Column != "age" || changes[0].Risk != migrator.ColumnChangeLossy {
+		t.Errorf("integer to text should be a lossy change, but got %+v", changes)
+	}
+
+	if empty, err := DB.Migrator().(migrator.TableInterface).IsTableEmpty(&ColumnChangeStruct{}); err != nil || !empty {
+		t.Fatalf("table should be empty, got %v, error %v", empty, err)
+	}
+
+	DB.Create(&ColumnChangeStruct{Age: 18, Name: "change"})
+	if empty, err := DB.Migrator().(migrator.TableInterface).IsTableEmpty(&ColumnChangeStruct{}); err != nil || empty {
+		t.Fatalf("table should not be empty, got %v, error %v", empty, err)
+	}
+
+	m = newMigrator(DB, migrator.Config{MigrateColumnTypesWhenAutoMigrate: true})
+	if err := m.AutoMigrate(&ColumnChangeStruct3{}); err == nil || !strings.Contains(err.Error(), "AllowDestructiveColumnChanges") {
+		t.Errorf("destructive change of non-empty table should require opt-in, but got %v", err)
+	}
+
+	// sqlite driver fails to recreate tables when altering columns
+	if DB.Dialector.Name() != "sqlite" {
+		DB.Exec("DELETE FROM column_change_structs")
+		if err := m.AutoMigrate(&ColumnChangeStruct3{}); err != nil {
+			t.Errorf("destructive change of empty table should proceed, but got %v", err)
+		}
+	}
+}
+
+func TestGeneratedColumnOf(t *testing.T) {
+	if DB.Dialector.Name() != "sqlserver" {
+		t.Skip("only sqlserver uses computed columns syntax, others are covered by TestMigrateGeneratedColumn")
+	}
+
+	DB.Migrator().DropTable(&GeneratedColumnStruct{})
+	if err := DB.AutoMigrate(&GeneratedColumnStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate computed column, got error %v", err)
+	}
+
+	value := GeneratedColumnStruct{Price: 3, Quantity: 5}
+	if err := DB.Create(&value).Error; err != nil {
+		t.Fatalf("Failed to create with computed column, got error %v", err)
+	}
+
+	var result GeneratedColumnStruct
+	if err := DB.First(&result, value.ID).Error; err != nil || result.Total != 15 {
+		t.Errorf("computed column should be computed by database, got %v, error %v", result.Total, err)
+	}
+
+	var persisted bool
+	if err := DB.Raw("SELECT is_persisted FROM sys.computed_columns WHERE object_id = OBJECT_ID(?) AND name = ?", "generated_column_structs", "total").Row().Scan(&persisted); err != nil || !persisted {
+		t.Errorf("computed column should be persisted, got %v, error %v", persisted, err)
+	}
+}
+
+// recordExecConnPool records executed statements with their bind vars, statements are skipped unless passThrough
+type recordExecConnPool struct {
+	gorm.ConnPool
+	queries     []string
+	args        [][]interface{}
+	passThrough bool
+}
+
+func (pool *recordExecConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	pool.queries = append(pool.queries, query)
+	pool.args = append(pool.args, args)
+	if pool.passThrough {
+		return pool.ConnPool.ExecContext(ctx, query, args...)
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func TestMigrateInlineDDL(t *testing.T) {
+	type InlineDDLUser struct {
+		ID   uint
+		Name string `gorm:"index:idx_inline_ddl_users_name"`
+	}
+
+	DB.Migrator().DropTable(&InlineDDLUser{})
+	m := newMigrator(DB, migrator.Config{InlineDDL: true, CreateIndexAfterCreateTable: DB.Dialector.Name() == "sqlite"})
+	if err := m.CreateTable(&InlineDDLUser{}); err != nil {
+		t.Fatalf("failed to create table with inlined DDL, got error %v", err)
+	}
+
+	if !DB.Migrator().HasTable(&InlineDDLUser{}) || !DB.Migrator().HasIndex(&InlineDDLUser{}, "idx_inline_ddl_users_name") {
+		t.Fatalf("table and index should be created with inlined DDL")
+	}
+
+	if err := m.DropTable(&InlineDDLUser{}); err != nil || DB.Migrator().HasTable(&InlineDDLUser{}) {
+		t.Fatalf("failed to drop table with inlined DDL, got error %v", err)
+	}
+
+	tx := DB.Session(&gorm.Session{Context: context.Background()})
+	pool := &recordExecConnPool{ConnPool: tx.Statement.ConnPool}
+	tx.Statement.ConnPool = pool
+	m = newMigrator(tx, migrator.Config{InlineDDL: true})
+	if err := m.RenameTable("inline_ddl_users", "inline_ddl_accounts"); err != nil {
+		t.Fatalf("failed to rename table, got error %v", err)
+	}
+
+	if len(pool.queries) != 1 || len(pool.args[0]) != 0 || !strings.Contains(pool.queries[0], "inline_ddl_accounts") {
+		t.Fatalf("DDL should be executed fully inlined without bind vars, but got %v %v", pool.queries, pool.args)
+	}
+
+	// literals are read back as they are by the database, e.g: backslashes are escaped for mysql
+	var literal string
+	if err := DB.Raw("SELECT " + m.InlineLiteralOf("jinzhu's \\")).Row().Scan(&literal); err != nil || literal != "jinzhu's \\" {
+		t.Errorf("string literal should be quoted, but got %v, error %v", literal, err)
+	}
+
+	if literal := m.InlineLiteralOf(sql.NullInt64{Int64: 18, Valid: true}); literal != "18" {
+		t.Errorf("valuer should be inlined with its value, but got %v", literal)
+	}
+
+	if literal := m.InlineLiteralOf(sql.NullString{}); literal != "NULL" {
+		t.Errorf("null valuer should be inlined as NULL, but got %v", literal)
+	}
+}
+
+type InlineDDLAutoStruct struct {
+	ID   uint
+	Name string
+}
+
+type InlineDDLAutoStruct2 struct {
+	ID   uint
+	Name string `gorm:"index:idx_inline_ddl_auto_structs_name,comment:names of users"`
+	Age  int
+}
+
+func (InlineDDLAutoStruct2) TableName() string {
+	return "inline_ddl_auto_structs"
+}
+
+func TestAutoMigrateInlineDDL(t *testing.T) {
+	DB.Migrator().DropTable(&InlineDDLAutoStruct{})
+	if err := DB.AutoMigrate(&InlineDDLAutoStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	tx := DB.Session(&gorm.Session{Context: context.Background()})
+	pool := &recordExecConnPool{ConnPool: tx.Statement.ConnPool, passThrough: true}
+	tx.Statement.ConnPool = pool
+
+	m := newMigrator(tx, migrator.Config{InlineDDL: true})
+	if err := m.AutoMigrate(&InlineDDLAutoStruct2{}); err != nil {
+		t.Fatalf("failed to auto migrate with inlined DDL, got error %v", err)
+	}
+
+	if !DB.Migrator().HasColumn(&InlineDDLAutoStruct2{}, "Age") || !DB.Migrator().HasIndex(&InlineDDLAutoStruct2{}, "idx_inline_ddl_auto_structs_name") {
+		t.Fatalf("column and index should be added with inlined DDL")
+	}
+
+	if len(pool.queries) == 0 {
+		t.Fatalf("DDL of AutoMigrate should be recorded")
+	}
+
+	for idx, query := range pool.queries {
+		if len(pool.args[idx]) != 0 {
+			t.Errorf("DDL of AutoMigrate should be executed fully inlined without bind vars, but got %v %v", query, pool.args[idx])
+		}
+	}
+
+	DB.Migrator().DropTable(&InlineDDLAutoStruct{})
+}
+
+func TestCreateDeferrableUniqueConstraint(t *testing.T) {
+	type DeferrableUniqueItem struct {
+		ID        uint
+		ListID    uint `gorm:"uniqueConstraint:uni_deferrable_unique_items_sort,initially_deferred"`
+		SortOrder int  `gorm:"uniqueConstraint:uni_deferrable_unique_items_sort"`
+	}
+
+	DB.Migrator().DropTable(&DeferrableUniqueItem{})
+	if err := DB.Migrator().CreateTable(&DeferrableUniqueItem{}); err != nil {
+		t.Fatalf("Failed to create table with unique constraint, got error %v", err)
+	}
+
+	first := DeferrableUniqueItem{ListID: 1, SortOrder: 1}
+	DB.Create(&first)
+	if err := DB.Create(&DeferrableUniqueItem{ListID: 1, SortOrder: 1}).Error; err == nil {
+		t.Errorf("should fail to create duplicated record with unique constraint")
+	}
+
+	if err := DB.Create(&DeferrableUniqueItem{ListID: 1, SortOrder: 2}).Error; err != nil {
+		t.Errorf("should be able to create record with unique constraint, got error %v", err)
+	}
+
+	if name := DB.Dialector.Name(); name != "postgres" && name != "mysql" {
+		t.Skip("skip dialects without adding unique constraints to existing tables")
+	}
+
+	// DEFERRABLE is ignored by mysql, which would reject it
+	m := newMigrator(DB, migrator.Config{})
+	if err := m.DropConstraint(&DeferrableUniqueItem{}, "uni_deferrable_unique_items_sort"); err != nil {
+		t.Fatalf("Failed to drop unique constraint, got error %v", err)
+	}
+
+	if err := m.CreateConstraint(&DeferrableUniqueItem{}, "uni_deferrable_unique_items_sort"); err != nil {
+		t.Fatalf("Failed to create unique constraint, got error %v", err)
+	}
+
+	if DB.Dialector.Name() != "postgres" {
+		t.Skip("skip dialects other than postgres, which defers unique constraints")
+	}
+
+	var deferrable, deferred bool
+	if err := DB.Raw("SELECT condeferrable, condeferred FROM pg_constraint WHERE conrelid = 'deferrable_unique_items'::regclass AND conname = ?", "uni_deferrable_unique_items_sort").Row().Scan(&deferrable, &deferred); err != nil || !deferrable || !deferred {
+		t.Fatalf("unique constraint should be created with DEFERRABLE INITIALLY DEFERRED, got %v %v, error %v", deferrable, deferred, err)
+	}
+
+	// swapping sort orders violates the constraint until the transaction commits
+	if err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&DeferrableUniqueItem{}).Where("sort_order = ?", 2).Update("sort_order", 1).Error; err != nil {
+			return err
+		}
+		return tx.Model(&first).Update("sort_order", 2).Error
+	}); err != nil {
+		t.Errorf("deferred unique constraint should be checked at commit, got error %v", err)
+	}
+}
+
+func TestBuildCreateTableSQLWithPrimaryKeyInComment(t *testing.T) {
+	type LegacyKeyStruct struct {
+		Code string `gorm:"primaryKey;size:20;comment:primary key of the legacy system"`
+		Name string `gorm:"size:100;default:'PRIMARY KEY'"`
+	}
+
+	sql, _, err := DB.Migrator().(migrator.TableInterface).BuildCreateTableSQL(&LegacyKeyStruct{})
+	if err != nil {
+		t.Fatalf("Failed to build create table sql, got error %v", err)
+	}
+
+	if !strings.Contains(sql, "PRIMARY KEY ?") {
+		t.Errorf("comments and defaults mentioning primary key shouldn't drop the primary key clause, got %v", sql)
+	}
+}
+
+func TestBuildCreateTableSQL(t *testing.T) {
+	type BuildCreateTableStruct struct {
+		ID   uint
+		Name string `gorm:"size:100;check:name <> ''"`
+	}
+
+	DB.Migrator().DropTable(&BuildCreateTableStruct{})
+	sql, values, err := DB.Migrator().(migrator.TableInterface).BuildCreateTableSQL(&BuildCreateTableStruct{})
+	if err != nil {
+		t.Fatalf("Failed to build create table sql, got error %v", err)
+	}
+
+	if !strings.HasPrefix(sql, "CREATE TABLE ? (") || len(values) == 0 || values[0] != (clause.Table{Name: "build_create_table_structs"}) {
+		t.Errorf("should build create table sql with table name, but got %v %v", sql, values)
+	}
+
+	if !strings.Contains(sql, "CHECK (?)") {
+		t.Errorf("should build create table sql with check constraint, but got %v", sql)
+	}
+
+	if DB.Migrator().HasTable(&BuildCreateTableStruct{}) {
+		t.Fatalf("table should not be created when building create table sql")
+	}
+
+	if err := DB.Exec(sql, values...).Error; err != nil {
+		t.Fatalf("Failed to exec built create table sql, got error %v", err)
+	}
+
+	if !DB.Migrator().HasTable(&BuildCreateTableStruct{}) || !DB.Migrator().HasColumn(&BuildCreateTableStruct{}, "Name") {
+		t.Fatalf("table should be created with built create table sql")
+	}
+}
+
+func TestAlterColumnsNullability(t *testing.T) {
+	type NullabilityStruct struct {
+		ID   uint
+		Name string
+		Age  int `gorm:"not null"`
+	}
+
+	type NullabilityStruct2 struct {
+		ID   uint
+		Name string `gorm:"not null"`
+		Age  int
+	}
+
+	type NullabilityStruct3 struct {
+		ID   uint
+		Name string `gorm:"size:200;not null"`
+		Age  int
+	}
+
+	if DB.Dialector.Name() == "sqlite" {
+		t.Skip("skip sqlite due to AlterColumn of its driver loses column names when rebuilding the table")
+	}
+
+	assertNullability := func() {
+		columnTypes, err := DB.Migrator().ColumnTypes(&NullabilityStruct{})
+		if err != nil {
+			t.Fatalf("Failed to get column types, got error %v", err)
+		}
+
+		for _, columnType := range columnTypes {
+			if nullable, ok := columnType.Nullable(); ok {
+				if (columnType.Name() == "name" && nullable) || (columnType.Name() == "age" && !nullable) {
+					t.Errorf("column %v nullability should be migrated, but got nullable %v", columnType.Name(), nullable)
+				}
+			}
+		}
+	}
+
+	DB.Migrator().DropTable(&NullabilityStruct{})
+	if err := DB.AutoMigrate(&NullabilityStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	recorder := &recordSQLLogger{Interface: DB.Logger}
+	tx := DB.Session(&gorm.Session{Context: context.Background(), Logger: recorder})
+	m := newMigrator(tx.Table("nullability_structs"), migrator.Config{})
+	if err := m.AlterColumnsNullability(&NullabilityStruct2{}, "Name", "Age"); err != nil {
+		t.Fatalf("Failed to alter columns nullability, got error %v", err)
+	}
+
+	if name := DB.Dialector.Name(); (name == "postgres" || name == "mysql") && len(recorder.statementsOf("ALTER TABLE")) != 1 {
+		t.Errorf("nullability changes should be altered in one statement, but got %v", recorder.sqls)
+	}
+	assertNullability()
+
+	DB.Migrator().DropTable(&NullabilityStruct{})
+	if err := DB.AutoMigrate(&NullabilityStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	m = newMigrator(DB.Table("nullability_structs"), migrator.Config{MigrateNullabilityWhenAutoMigrate: true})
+	if _, err := m.AutoMigrateWithResult(&NullabilityStruct2{}); err != nil {
+		t.Fatalf("Failed to auto migrate nullability changes, got error %v", err)
+	}
+	assertNullability()
+
+	DB.Migrator().DropTable(&NullabilityStruct{})
+	if err := DB.AutoMigrate(&NullabilityStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	m.MigrateColumnTypesWhenAutoMigrate = true
+	result, err := m.AutoMigrateWithResult(&NullabilityStruct3{})
+	if err != nil {
+		t.Fatalf("Failed to auto migrate type and nullability changes, got error %v", err)
+	}
+
+	if result.Count("alter_column") != 1 || result.Count("alter_column_nullability") != 2 {
+		t.Errorf("nullability should be migrated with type changes, but got %+v", result)
+	}
+	assertNullability()
+}
+
+func TestAlterColumnsNullabilityWithEmptyDefault(t *testing.T) {
+	type EmptyDefaultStruct struct {
+		ID   uint
+		Name string `gorm:"size:100"`
+	}
+
+	type EmptyDefaultStruct2 struct {
+		ID   uint
+		Name string `gorm:"size:100;not null;default:''"`
+	}
+
+	if name := DB.Dialector.Name(); name != "postgres" && name != "mysql" {
+		t.Skip("skip other dialects due to they alter columns by the dialect migrator, e.g: rebuilding tables of sqlite")
+	}
+
+	DB.Migrator().DropTable(&EmptyDefaultStruct{})
+	if err := DB.AutoMigrate(&EmptyDefaultStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if err := DB.Exec("INSERT INTO empty_default_structs (id, name) VALUES (1, NULL), (2, 'jinzhu')").Error; err != nil {
+		t.Fatalf("Failed to insert rows, got error %v", err)
+	}
+
+	recorder := &recordSQLLogger{Interface: DB.Logger}
+	tx := DB.Session(&gorm.Session{Context: context.Background(), Logger: recorder})
+	m := newMigrator(tx.Table("empty_default_structs"), migrator.Config{})
+	if err := m.AlterColumnsNullability(&EmptyDefaultStruct2{}, "Name"); err != nil {
+		t.Fatalf("Failed to alter columns nullability, got error %v", err)
+	}
+
+	if len(recorder.statementsOf("UPDATE")) != 1 || len(recorder.statementsOf("ALTER TABLE")) != 1 {
+		t.Errorf("NULLs should be backfilled before setting default and NOT NULL in one statement, but got %v", recorder.sqls)
+	}
+
+	var count int64
+	if DB.Table("empty_default_structs").Where("name IS NULL").Count(&count); count != 0 {
+		t.Errorf("NULLs should be backfilled with the default, but got %v", count)
+	}
+
+	if err := DB.Exec("INSERT INTO empty_default_structs (id) VALUES (3)").Error; err != nil {
+		t.Fatalf("Failed to insert row with the default, got error %v", err)
+	}
+
+	var name sql.NullString
+	if DB.Table("empty_default_structs").Select("name").Where("id = ?", 3).Row().Scan(&name); !name.Valid || name.String != "" {
+		t.Errorf("column should be altered with empty default, but got %v", name)
+	}
+
+	if err := DB.Exec("INSERT INTO empty_default_structs (id, name) VALUES (4, NULL)").Error; err == nil {
+		t.Errorf("column should be altered with NOT NULL")
+	}
+}
+
+func TestMigrateSoftDeleteUniqueIndexes(t *testing.T) {
+	if name := DB.Dialector.Name(); name == "mysql" {
+		t.Skip("skip mysql due to it doesn't support partial indexes")
+	}
+
+	type SoftDeleteUniqueIndexStruct struct {
+		gorm.Model
+		Email string `gorm:"size:100;uniqueIndex:idx_soft_delete_unique_index_structs_email"`
+	}
+
+	DB.Migrator().DropTable(&SoftDeleteUniqueIndexStruct{})
+	m := newMigrator(DB, migrator.Config{SoftDeleteUniqueIndexes: true, CreateIndexAfterCreateTable: true})
+	if err := m.AutoMigrate(&SoftDeleteUniqueIndexStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if !DB.Migrator().HasIndex(&SoftDeleteUniqueIndexStruct{}, "idx_soft_delete_unique_index_structs_email") {
+		t.Fatalf("unique index should be created")
+	}
+
+	value := SoftDeleteUniqueIndexStruct{Email: "soft_delete_unique_index@example.org"}
+	DB.Create(&value)
+	if err := DB.Create(&SoftDeleteUniqueIndexStruct{Email: value.Email}).Error; err == nil {
+		t.Errorf("should failed to create duplicated email")
+	}
+
+	DB.Delete(&value)
+	if err := DB.Create(&SoftDeleteUniqueIndexStruct{Email: value.Email}).Error; err != nil {
+		t.Errorf("should be able to reuse email of soft deleted record, got error %v", err)
+	}
+
+	if err := m.AutoMigrate(&SoftDeleteUniqueIndexStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate again, got error %v", err)
+	}
+
+	if err := m.Validate(&SoftDeleteUniqueIndexStruct{}); err != nil {
+		t.Errorf("should validate soft delete unique indexes, got error %v", err)
+	}
+
+	// partial indexes can't be inlined into CREATE TABLE, they are created after it
+	m.CreateIndexAfterCreateTable = false
+	sql, _, err := m.BuildCreateTableSQL(&SoftDeleteUniqueIndexStruct{})
+	if err != nil {
+		t.Fatalf("Failed to build create table sql, got error %v", err)
+	}
+
+	if strings.Contains(sql, "UNIQUE INDEX") || strings.Count(sql, "INDEX") != 1 {
+		t.Errorf("create table sql should only inline the deleted_at index, got %v", sql)
+	}
+}
+
+func TestCreateTableFailingSoftDeleteUniqueIndex(t *testing.T) {
+	if name := DB.Dialector.Name(); name == "mysql" {
+		t.Skip("skip mysql due to it doesn't support partial indexes")
+	}
+
+	type FailingSoftDeleteUniqueIndexStruct struct {
+		gorm.Model
+		Email string `gorm:"size:100;uniqueIndex:idx_failing_soft_delete_unique_index_structs_email,where:missing_email IS NULL"`
+	}
+
+	DB.Migrator().DropTable(&FailingSoftDeleteUniqueIndexStruct{})
+	m := newMigrator(DB, migrator.Config{SoftDeleteUniqueIndexes: true, CreateIndexAfterCreateTable: true})
+	if err := m.CreateTable(&FailingSoftDeleteUniqueIndexStruct{}); err == nil {
+		t.Errorf("failing soft delete unique index should fail creating table")
+	}
+
+	if DB.Migrator().HasIndex(&FailingSoftDeleteUniqueIndexStruct{}, "idx_failing_soft_delete_unique_index_structs_email") {
+		t.Errorf("failing soft delete unique index shouldn't be created")
+	}
+	DB.Migrator().DropTable(&FailingSoftDeleteUniqueIndexStruct{})
+}
+
+func TestDiffModels(t *testing.T) {
+	type DiffUser struct {
+		ID    uint
+		Name  string `gorm:"size:100;index:idx_diff_users_name"`
+		Email string `gorm:"size:100;check:chk_diff_email,email <> ''"`
+	}
+
+	type DiffUserDTO struct {
+		ID    uint
+		Name  string `gorm:"size:100;index:idx_diff_users_name"`
+		Email string `gorm:"size:100;check:chk_diff_email,email <> ''"`
+	}
+
+	type DiffUserShard struct {
+		ID     uint
+		Name   string `gorm:"size:100;not null;uniqueIndex:idx_diff_users_name"`
+		Remark string
+	}
+
+	diff, err := DB.Migrator().(migrator.AutoMigrateInterface).DiffModels(&DiffUser{}, &DiffUserDTO{})
+	if err != nil {
+		t.Fatalf("Failed to diff models, got error %v", err)
+	}
+
+	if !diff.Empty() {
+		t.Errorf("models with same schema should have no differences, but got %+v", diff)
+	}
+
+	if diff, err = DB.Migrator().(migrator.AutoMigrateInterface).DiffModels(&DiffUser{}, &DiffUserShard{}); err != nil {
+		t.Fatalf("Failed to diff models, got error %v", err)
+	}
+
+	if len(diff.Columns) != 3 || !strings.HasPrefix(diff.Columns[0], "column email only exists in DiffUser") ||
+		!strings.HasPrefix(diff.Columns[1], "column name: ") || diff.Columns[2] != "column remark only exists in DiffUserShard" {
+		t.Errorf("should report differences of columns, but got %v", diff.Columns)
+	}
+
+	if len(diff.Indexes) != 1 || !strings.Contains(diff.Indexes[0], "!= UNIQUE INDEX (name)") {
+		t.Errorf("should report differences of indexes, but got %v", diff.Indexes)
+	}
+
+	if len(diff.Constraints) != 1 || diff.Constraints[0] != "constraint chk_diff_email only exists in DiffUser" {
+		t.Errorf("should report differences of constraints, but got %v", diff.Constraints)
+	}
+}
+
+func TestAutoMigrateStatementTimeout(t *testing.T) {
+	type StatementTimeoutStruct struct {
+		ID uint
+	}
+
+	type StatementTimeoutStruct2 struct {
+		ID   uint
+		Name string
+	}
+
+	DB.Migrator().DropTable(&StatementTimeoutStruct{})
+	if err := DB.AutoMigrate(&StatementTimeoutStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	recorder := &recordSQLLogger{Interface: DB.Logger}
+	tx := DB.Session(&gorm.Session{Context: context.Background(), Logger: recorder})
+	m := newMigrator(tx.Table("statement_timeout_structs"), migrator.Config{MigrateStatementTimeout: 1500 * time.Millisecond})
+	if err := m.AutoMigrate(&StatementTimeoutStruct2{}); err != nil {
+		t.Fatalf("failed to auto migrate with statement timeout, got error %v", err)
+	}
+
+	if !DB.Table("statement_timeout_structs").Migrator().HasColumn(&StatementTimeoutStruct2{}, "Name") {
+		t.Fatalf("column should be added with statement timeout")
+	}
+
+	// max_execution_time of mysql doesn't limit DDL, which falls back to the context deadline
+	var set string
+	if DB.Dialector.Name() == "postgres" {
+		set = "SET LOCAL statement_timeout = '1500ms'"
+	}
+
+	for idx, sql := range recorder.sqls {
+		if !strings.HasPrefix(sql, "ALTER TABLE") || !strings.Contains(sql, "ADD") {
+			continue
+		}
+
+		if set == "" {
+			if idx > 0 && strings.HasPrefix(recorder.sqls[idx-1], "SET") {
+				t.Errorf("dialects without statement timeouts should fall back to the context deadline, got %v", recorder.sqls)
+			}
+		} else if idx == 0 || recorder.sqls[idx-1] != set {
+			t.Errorf("column should be added with statement timeout, got %v", recorder.sqls)
+		}
+	}
+
+	DB.Migrator().DropTable(&StatementTimeoutStruct{})
+}
+
+func TestMigrateLockTimeout(t *testing.T) {
+	DB.Migrator().DropTable("lock_timeout_users", "lock_timeout_accounts")
+	m := newMigrator(DB, migrator.Config{MigrateLockTimeout: time.Second})
+	if err := m.CreateTable(&LockTimeoutUser{}); err != nil {
+		t.Fatalf("Failed to create table with lock timeout, got error %v", err)
+	}
+
+	if !DB.Migrator().HasTable(&LockTimeoutUser{}) {
+		t.Errorf("table should be created with lock timeout")
+	}
+
+	var expects []string
+	switch DB.Dialector.Name() {
+	case "postgres":
+		// lock_timeout is reset with the transaction
+		expects = []string{"SET LOCAL lock_timeout = '1500ms'", "lock_timeout_accounts"}
+	case "mysql":
+		// lock_wait_timeout is rounded up to seconds
+		expects = []string{"SET SESSION lock_wait_timeout = 2", "lock_timeout_accounts", "SET SESSION lock_wait_timeout = DEFAULT"}
+	default:
+		return
+	}
+
+	recorder := &recordSQLLogger{Interface: DB.Logger}
+	tx := DB.Session(&gorm.Session{Context: context.Background(), Logger: recorder})
+	m = newMigrator(tx, migrator.Config{MigrateLockTimeout: 1500 * time.Millisecond})
+	if err := m.RenameTable("lock_timeout_users", "lock_timeout_accounts"); err != nil {
+		t.Fatalf("Failed to rename table, got error %v", err)
+	}
+
+	if len(recorder.sqls) != len(expects) {
+		t.Errorf("DDL should be executed with lock timeout, but got %v", recorder.sqls)
+	} else {
+		for idx, expect := range expects {
+			if !strings.Contains(recorder.sqls[idx], expect) {
+				t.Errorf("DDL should be executed with lock timeout, expects %v, but got %v", expect, recorder.sqls[idx])
+			}
+		}
+	}
+
+	if !DB.Migrator().HasTable("lock_timeout_accounts") {
+		t.Errorf("table should be renamed with lock timeout")
+	}
+	DB.Migrator().DropTable("lock_timeout_accounts")
+}
+
+func TestAutoMigrateLockTimeout(t *testing.T) {
+	if DB.Dialector.Name() != "postgres" && DB.Dialector.Name() != "mysql" {
+		t.Skip("skip dialects other than postgres and mysql, which support lock timeouts")
+	}
+
+	type LockTimeoutStruct struct {
+		ID uint
+	}
+
+	type LockTimeoutStruct2 struct {
+		ID   uint
+		Name string
+	}
+
+	DB.Migrator().DropTable(&LockTimeoutStruct{})
+	if err := DB.AutoMigrate(&LockTimeoutStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	recorder := &recordSQLLogger{Interface: DB.Logger}
+	tx := DB.Session(&gorm.Session{Context: context.Background(), Logger: recorder})
+	m := newMigrator(tx.Table("lock_timeout_structs"), migrator.Config{MigrateLockTimeout: 1500 * time.Millisecond})
+	if err := m.AutoMigrate(&LockTimeoutStruct2{}); err != nil {
+		t.Fatalf("failed to auto migrate with lock timeout, got error %v", err)
+	}
+
+	set, reset := "SET LOCAL lock_timeout = '1500ms'", ""
+	if DB.Dialector.Name() == "mysql" {
+		set, reset = "SET SESSION lock_wait_timeout = 2", "SET SESSION lock_wait_timeout = DEFAULT"
+	}
+
+	var timed bool
+	for idx, sql := range recorder.sqls {
+		if strings.HasPrefix(sql, "ALTER TABLE") && strings.Contains(sql, "ADD") && idx > 0 {
+			timed = recorder.sqls[idx-1] == set && (reset == "" || idx+1 < len(recorder.sqls) && recorder.sqls[idx+1] == reset)
+		}
+	}
+
+	if !timed {
+		t.Errorf("column should be added by AutoMigrate with lock timeout, got %v", recorder.sqls)
+	}
+
+	DB.Migrator().DropTable(&LockTimeoutStruct{})
+}
+
+// busyTimeoutDialector sqlite dialector whose migrator limits lock waits of DDL with busy_timeout
+type busyTimeoutDialector struct {
+	gorm.Dialector
+}
+
+func (dialector busyTimeoutDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return busyTimeoutMigrator{dialector.Dialector.Migrator(db)}
+}
+
+type busyTimeoutMigrator struct {
+	gorm.Migrator
+}
+
+func (busyTimeoutMigrator) LockTimeoutSQL(timeout time.Duration) (string, string) {
+	return fmt.Sprintf("PRAGMA busy_timeout = %d", timeout.Milliseconds()), "PRAGMA busy_timeout = 0"
+}
+
+func TestMigrateLockTimeoutResetAfterFailedDDL(t *testing.T) {
+	db, err := OpenTestConnection()
+	if err != nil {
+		t.Fatalf("failed to connect database, got error %v", err)
+	}
+	// the only connection, queries after the DDL run on the connection of its session settings
+	db.ConnPool.(*sql.DB).SetMaxOpenConns(1)
+
+	var query, expects string
+	switch db.Dialector.Name() {
+	case "postgres":
+		query, expects = "SHOW lock_timeout", "0"
+	case "mysql":
+		query, expects = "SELECT @@SESSION.lock_wait_timeout = @@GLOBAL.lock_wait_timeout", "1"
+	case "sqlserver":
+		query, expects = "SELECT @@LOCK_TIMEOUT", "-1"
+	default:
+		db.Dialector = busyTimeoutDialector{db.Dialector}
+		query, expects = "PRAGMA busy_timeout", "0"
+	}
+
+	m := migrator.Migrator{Config: migrator.Config{DB: db, Dialector: db.Dialector, MigrateLockTimeout: 1500 * time.Millisecond}}
+	if err := m.RenameTable("lock_timeout_missing_structs", "lock_timeout_missing_accounts"); err == nil {
+		t.Fatalf("renaming missing table should fail")
+	}
+
+	var value string
+	if err := db.Raw(query).Row().Scan(&value); err != nil || value != expects {
+		t.Errorf("lock timeout should be reset after failed DDL, expects %v, got %v, error %v", expects, value, err)
+	}
+}
+
+type LockTimeoutUser struct {
+	ID   uint
+	Name string
+}
+
+func TestCreateIndexOnPartition(t *testing.T) {
+	type PartitionEvent struct {
+		ID   uint
+		Kind string `gorm:"size:50;index"`
+		Name string `gorm:"size:50;index:idx_partition_event_name"`
+	}
+
+	DB.Migrator().DropTable(&PartitionEvent{}, "partition_events_2020")
+	if err := DB.Migrator().CreateTable(&PartitionEvent{}); err != nil {
+		t.Fatalf("Failed to create table, got error %v", err)
+	}
+
+	if err := DB.Exec("CREATE TABLE partition_events_2020 (id integer, kind varchar(50), name varchar(50))").Error; err != nil {
+		t.Fatalf("Failed to create partition table, got error %v", err)
+	}
+
+	if err := DB.Migrator().(migrator.IndexInterface).CreateIndexOn(&PartitionEvent{}, "Kind", "partition_events_2020"); err != nil {
+		t.Fatalf("Failed to create index on partition, got error %v", err)
+	}
+
+	if err := DB.Migrator().(migrator.IndexInterface).CreateIndexOn(&PartitionEvent{}, "idx_partition_event_name", "partition_events_2020"); err != nil {
+		t.Fatalf("Failed to create index on partition, got error %v", err)
+	}
+
+	partition := DB.Table("partition_events_2020").Migrator()
+	if !partition.HasIndex(&PartitionEvent{}, "idx_partition_events_2020_kind") || !partition.HasIndex(&PartitionEvent{}, "idx_partition_event_name_partition_events_2020") {
+		t.Errorf("indexes should be created on partition")
+	}
+
+	if DB.Migrator().HasIndex(&PartitionEvent{}, "idx_partition_events_2020_kind") {
+		t.Errorf("index on partition should not be created on parent table")
+	}
+
+	if !DB.Migrator().HasIndex(&PartitionEvent{}, "Kind") {
+		t.Errorf("index of parent table should not be changed")
+	}
+}
+
+func TestMigrateColumnComments(t *testing.T) {
+	type ColumnCommentStruct struct {
+		ID      uint
+		Name    string `gorm:"size:100;comment:display name"`
+		Version string `gorm:"size:100;comment:gorm:schema_version=3f2a"`
+	}
+
+	m := newMigrator(DB, migrator.Config{})
+	for _, c := range []struct{ live, comment, expected string }{
+		{"", "display name", "display name"},
+		{"documented by human", "display name", "documented by human"},
+		{"", "gorm:schema_version=3f2a", "gorm:schema_version=3f2a"},
+		{"documented by human", "gorm:schema_version=3f2a", "documented by human\ngorm:schema_version=3f2a"},
+		{"documented by human\ngorm:schema_version=1b0c\nsee wiki", "gorm:schema_version=3f2a", "documented by human\ngorm:schema_version=3f2a\nsee wiki"},
+	} {
+		if merged := m.MergeColumnComment(c.live, c.comment); merged != c.expected {
+			t.Errorf("merged comment of %q and %q should be %q, but got %q", c.live, c.comment, c.expected, merged)
+		}
+	}
+
+	DB.Migrator().DropTable(&ColumnCommentStruct{})
+	if err := DB.AutoMigrate(&ColumnCommentStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	m = newMigrator(DB, migrator.Config{MigrateColumnCommentsWhenAutoMigrate: true})
+	if _, err := m.AutoMigrateWithResult(&ColumnCommentStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate column comments, got error %v", err)
+	}
+
+	if name := DB.Dialector.Name(); name != "mysql" && name != "postgres" {
+		t.Skip("skip dialects without column comments")
+	}
+
+	if comment, err := m.ColumnCommentOf(&ColumnCommentStruct{}, "Version"); err != nil || comment != "gorm:schema_version=3f2a" {
+		t.Errorf("column comment should be migrated, but got %v, error %v", comment, err)
+	}
+
+	// COMMENT ON COLUMN (Postgres), MODIFY COLUMN keeping the definition (MySQL)
+	for _, comment := range []string{"it's the display name", "documented by human\ngorm:schema_version=3f2a"} {
+		if err := m.SetColumnComment(&ColumnCommentStruct{}, "Name", comment); err != nil {
+			t.Fatalf("Failed to set column comment, got error %v", err)
+		}
+
+		if live, err := m.ColumnCommentOf(&ColumnCommentStruct{}, "Name"); err != nil || live != comment {
+			t.Errorf("column comment should be set to %q, but got %q, error %v", comment, live, err)
+		}
+	}
+}
+
+func TestColumnTypesOfView(t *testing.T) {
+	DB.Migrator().DropView("adult_user_views")
+	query := DB.Model(&User{}).Select("id, name, age").Where("age > ? AND name <> ?", 18, "it's ?")
+	if err := DB.Migrator().CreateView("adult_user_views", gorm.ViewOption{Query: query}); err != nil {
+		t.Fatalf("Failed to create view, got error %v", err)
+	}
+
+	DB.Create(&User{Name: "column_types_of_view", Age: 20})
+	var count int64
+	if err := DB.Table("adult_user_views").Where("name = ?", "column_types_of_view").Count(&count).Error; err != nil || count != 1 {
+		t.Errorf("should be able to query view, got count %v, error %v", count, err)
+	}
+
+	columnTypes, err := DB.Migrator().ColumnTypes("adult_user_views")
+	if err != nil {
+		t.Fatalf("Failed to get column types of view, got error %v", err)
+	}
+
+	var names []string
+	for _, columnType := range columnTypes {
+		names = append(names, columnType.Name())
+	}
+
+	if strings.Join(names, ",") != "id,name,age" {
+		t.Errorf("view should expose columns id, name, age, but got %v", names)
+	}
+
+	query = DB.Model(&User{}).Select("id, name").Where("age > ?", 30)
+	option := gorm.ViewOption{Query: query, Replace: true}
+	if _, ok := DB.Migrator().(migrator.ViewReplaceInterface); !ok && DB.Dialector.Name() == "sqlite" {
+		// sqlite has no CREATE OR REPLACE VIEW
+		DB.Migrator().DropView("adult_user_views")
+		option.Replace = false
+	}
+
+	if err := DB.Migrator().CreateView("adult_user_views", option); err != nil {
+		t.Fatalf("Failed to replace view, got error %v", err)
+	}
+
+	if columnTypes, err = DB.Migrator().ColumnTypes("adult_user_views"); err != nil || len(columnTypes) != 2 {
+		t.Errorf("replaced view should expose 2 columns, but got %v, error %v", len(columnTypes), err)
+	}
+
+	if err := DB.Migrator().DropView("adult_user_views"); err != nil {
+		t.Fatalf("Failed to drop view, got error %v", err)
+	}
+
+	if _, err := DB.Migrator().ColumnTypes("adult_user_views"); err == nil {
+		t.Errorf("should failed to get column types of dropped view")
+	}
+}
+
+type ForeignKeyIndexOwner struct {
+	ID   uint
+	Name string
+}
+
+type ForeignKeyIndexPet struct {
+	ID      uint
+	OwnerID uint
+	Owner   ForeignKeyIndexOwner `gorm:"constraint:OnDelete:CASCADE,Index:idx_fk_pets_owner"`
+}
+
+type ForeignKeyIndexToy struct {
+	ID      uint
+	OwnerID uint                 `gorm:"index:idx_fk_toys_owner_name"`
+	Name    string               `gorm:"size:100;index:idx_fk_toys_owner_name"`
+	Owner   ForeignKeyIndexOwner `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func TestCreateForeignKeyIndexes(t *testing.T) {
+	DB.Migrator().DropTable(&ForeignKeyIndexPet{}, &ForeignKeyIndexToy{}, &ForeignKeyIndexOwner{})
+	m := newMigrator(DB, migrator.Config{CreateForeignKeyIndexes: true, CreateIndexAfterCreateTable: true})
+	if err := m.CreateTable(&ForeignKeyIndexOwner{}, &ForeignKeyIndexPet{}, &ForeignKeyIndexToy{}); err != nil {
+		t.Fatalf("Failed to create tables, got error %v", err)
+	}
+
+	if !DB.Migrator().HasIndex(&ForeignKeyIndexPet{}, "idx_fk_pets_owner") {
+		t.Errorf("backing index of foreign key should be created with the name")
+	}
+
+	if !DB.Migrator().HasIndex(&ForeignKeyIndexToy{}, "idx_fk_toys_owner_name") {
+		t.Errorf("model index should be created")
+	}
+
+	if DB.Migrator().HasIndex(&ForeignKeyIndexToy{}, "idx_foreign_key_index_toys_owner_id") {
+		t.Errorf("backing index of foreign key covered by model index should not be created")
+	}
+
+	if DB.Dialector.Name() != "mysql" {
+		t.Skip("skip dialects other than mysql, which names backing indexes of foreign keys")
+	}
+
+	// mysql names the backing index it creates for the foreign key
+	if err := DB.Migrator().DropConstraint(&ForeignKeyIndexPet{}, "fk_foreign_key_index_pets_owner"); err != nil {
+		t.Fatalf("Failed to drop constraint, got error %v", err)
+	}
+
+	if err := DB.Migrator().DropIndex(&ForeignKeyIndexPet{}, "idx_fk_pets_owner"); err != nil {
+		t.Fatalf("Failed to drop backing index, got error %v", err)
+	}
+
+	if err := m.CreateConstraint(&ForeignKeyIndexPet{}, "fk_foreign_key_index_pets_owner"); err != nil {
+		t.Fatalf("Failed to create constraint, got error %v", err)
+	}
+
+	if !DB.Migrator().HasIndex(&ForeignKeyIndexPet{}, "idx_fk_pets_owner") {
+		t.Errorf("mysql foreign key should be created with backing index name")
+	}
+}
+
+func TestRunMigrations(t *testing.T) {
+	type MigrationUser struct {
+		ID   uint
+		Name string
+		Age  int
+	}
+
+	DB.Migrator().DropTable(&MigrationUser{}, "test_migrations")
+	defer DB.Migrator().DropTable(&MigrationUser{}, "test_migrations")
+
+	m := newMigrator(DB, migrator.Config{MigrationsTable: "test_migrations"})
+
+	var runs []string
+	steps := []migrator.MigrationStep{
+		{ID: "1_create_users", Schema: func(m gorm.Migrator) error {
+			runs = append(runs, "1_create_users")
+			return m.CreateTable(&MigrationUser{})
+		}},
+		{ID: "2_seed_users", Data: func(tx *gorm.DB) error {
+			runs = append(runs, "2_seed_users")
+			return tx.Create(&MigrationUser{Name: "migration"}).Error
+		}},
+		{ID: "3_backfill_age", Data: func(tx *gorm.DB) error {
+			runs = append(runs, "3_backfill_age")
+			return tx.Model(&MigrationUser{}).Where("name = ?", "migration").Update("age", 18).Error
+		}},
+	}
+
+	if err := m.RunMigrations(steps...); err != nil {
+		t.Fatalf("failed to run migrations, got error %v", err)
+	}
+
+	var user MigrationUser
+	if err := DB.First(&user, "name = ?", "migration").Error; err != nil || user.Age != 18 {
+		t.Errorf("data steps should run after schema steps, got %+v, error %v", user, err)
+	}
+
+	failed := errors.New("failed step")
+	steps = append(steps, migrator.MigrationStep{ID: "4_failed", Data: func(tx *gorm.DB) error {
+		runs = append(runs, "4_failed")
+		if err := tx.Create(&MigrationUser{Name: "rollback"}).Error; err != nil {
+			return err
+		}
+		return failed
+	}})
+
+	if err := m.RunMigrations(steps...); !errors.Is(err, failed) {
+		t.Errorf("should return error of failed step, got %v", err)
+	}
+
+	if !reflect.DeepEqual(runs, []string{"1_create_users", "2_seed_users", "3_backfill_age", "4_failed"}) {
+		t.Errorf("applied steps should be skipped, got %v", runs)
+	}
+
+	var count int64
+	DB.Model(&MigrationUser{}).Where("name = ?", "rollback").Count(&count)
+	if count != 0 {
+		t.Errorf("changes of failed step should be rolled back, got %v rows", count)
+	}
+
+	if ids, err := m.AppliedMigrations(); err != nil || !reflect.DeepEqual(ids, []string{"1_create_users", "2_seed_users", "3_backfill_age"}) {
+		t.Errorf("failed step should not be recorded, got %v, error %v", ids, err)
+	}
+
+	if err := m.RunMigrations(migrator.MigrationStep{ID: "5_empty"}); err == nil {
+		t.Errorf("should return error for step without changes")
+	}
+
+	if err := m.RunMigrations(steps[0], steps[0]); err == nil {
+		t.Errorf("should return error for duplicated steps")
+	}
+}
+
+// migrationLockDialector dialector whose migrator takes the migration lock with SELECT statements
+type migrationLockDialector struct {
+	gorm.Dialector
+}
+
+func (dialector migrationLockDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return migrationLockMigrator{dialector.Dialector.Migrator(db)}
+}
+
+type migrationLockMigrator struct {
+	gorm.Migrator
+}
+
+func (migrationLockMigrator) MigrationLockSQL(name string) (string, string) {
+	return "SELECT 'lock " + name + "'", "SELECT 'unlock " + name + "'"
+}
+
+func TestRunMigrationsWithLock(t *testing.T) {
+	type MigrationLockUser struct {
+		ID   uint
+		Name string
+	}
+
+	DB.Migrator().DropTable(&MigrationLockUser{}, "lock_migrations")
+	defer DB.Migrator().DropTable(&MigrationLockUser{}, "lock_migrations")
+
+	recorder := &recordSQLLogger{Interface: DB.Logger}
+	tx := DB.Session(&gorm.Session{Context: context.Background(), Logger: recorder})
+	tx.Dialector = migrationLockDialector{DB.Dialector}
+
+	m := migrator.Migrator{Config: migrator.Config{DB: tx, Dialector: tx.Dialector, MigrationsTable: "lock_migrations"}}
+	step := migrator.MigrationStep{ID: "1_create_users", Schema: func(m gorm.Migrator) error {
+		return m.CreateTable(&MigrationLockUser{})
+	}}
+
+	if err := m.RunMigrations(step); err != nil {
+		t.Fatalf("failed to run migrations, got error %v", err)
+	}
+
+	if sqls := recorder.sqls; len(sqls) < 2 || sqls[0] != "SELECT 'lock lock_migrations'" || sqls[len(sqls)-1] != "SELECT 'unlock lock_migrations'" {
+		t.Errorf("migrations should run with the migration lock, got %v", sqls)
+	}
+
+	if !DB.Migrator().HasTable(&MigrationLockUser{}) {
+		t.Errorf("migration step should be applied with the migration lock")
+	}
+}
+
+func TestCreatePartialExpressionIndex(t *testing.T) {
+	type PartialExpressionUser struct {
+		gorm.Model
+		Email string `gorm:"size:100;uniqueIndex:idx_partial_expression_email,expression:lower(email),softDelete"`
+	}
+
+	type PartialExpressionTag struct {
+		ID   uint
+		Tags string `gorm:"index:idx_partial_expression_tags,type:gin,expression:to_tsvector('simple'\\, tags),where:tags <> ''"`
+	}
+
+	DB.Migrator().DropTable(&PartialExpressionUser{})
+	if err := DB.Migrator().CreateTable(&PartialExpressionUser{}); err != nil {
+		t.Fatalf("Failed to create table, got error %v", err)
+	}
+
+	if !DB.Migrator().HasIndex(&PartialExpressionUser{}, "idx_partial_expression_email") {
+		t.Errorf("partial expression index should be created")
+	}
+
+	DB.Create(&PartialExpressionUser{Email: "Partial@Expression.io"})
+	if err := DB.Create(&PartialExpressionUser{Email: "partial@expression.io"}).Error; err == nil {
+		t.Errorf("expression index should be unique regardless of case")
+	}
+
+	DB.Where("email = ?", "Partial@Expression.io").Delete(&PartialExpressionUser{})
+	if err := DB.Create(&PartialExpressionUser{Email: "partial@expression.io"}).Error; err != nil {
+		t.Errorf("soft deleted rows should be excluded from the partial index, got error %v", err)
+	}
+
+	if DB.Dialector.Name() != "postgres" {
+		t.Skip("skip dialects other than postgres, which supports expression partial indexes")
+	}
+
+	DB.Migrator().DropTable(&PartialExpressionTag{})
+	if err := DB.Migrator().CreateTable(&PartialExpressionTag{}); err != nil {
+		t.Fatalf("Failed to create table with gin expression index, got error %v", err)
+	}
+
+	for name, expected := range map[string]*regexp.Regexp{
+		"idx_partial_expression_email": regexp.MustCompile(`\(lower\(\(?email\)?(::text)?\)\) WHERE \(deleted_at IS NULL\)$`),
+		"idx_partial_expression_tags":  regexp.MustCompile(`USING gin \(to_tsvector\('simple'::regconfig, tags\)\) WHERE \(tags <> ''::text\)$`),
+	} {
+		var definition string
+		DB.Raw("SELECT indexdef FROM pg_indexes WHERE schemaname = current_schema() AND indexname = ?", name).Row().Scan(&definition)
+		if !expected.MatchString(definition) {
+			t.Errorf("expression of index %v should be followed by the predicate, but got %v", name, definition)
+		}
+	}
+}
+
+func TestPromoteUniqueIndexToPrimaryKey(t *testing.T) {
+	type PromoteUser struct {
+		ID   uint64 `gorm:"primaryKey;autoIncrement:false"`
+		Name string `gorm:"size:100"`
+	}
+
+	DB.Migrator().DropTable(&PromoteUser{})
+	defer DB.Migrator().DropTable(&PromoteUser{})
+
+	if err := DB.Exec("CREATE TABLE promote_users (id bigint NOT NULL, name varchar(100))").Error; err != nil {
+		t.Fatalf("failed to create table, got error %v", err)
+	}
+
+	if err := DB.Exec("CREATE UNIQUE INDEX idx_promote_users_id ON promote_users (id)").Error; err != nil {
+		t.Fatalf("failed to create unique index, got error %v", err)
+	}
+
+	if DB.Dialector.Name() == "sqlite" {
+		if err := DB.Migrator().(migrator.IndexInterface).PromoteToPrimaryKey(&PromoteUser{}, "idx_promote_users_id"); !errors.Is(err, gorm.ErrNotImplemented) {
+			t.Errorf("sqlite should not support promoting indexes, got %v", err)
+		}
+		return
+	}
+
+	m := newMigrator(DB, migrator.Config{PromoteUniqueIndexesWhenAutoMigrate: true})
+	result, err := m.AutoMigrateWithResult(&PromoteUser{})
+	if err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	if result.Count("promote_primary_key") != 1 {
+		t.Errorf("unique index should be promoted to primary key, got %+v", result)
+	}
+
+	constraints, err := DB.Migrator().(migrator.ConstraintsInterface).GetConstraints(&PromoteUser{})
+	if err != nil {
+		t.Fatalf("failed to get constraints, got error %v", err)
+	}
+
+	var hasPrimaryKey bool
+	for _, constraint := range constraints {
+		hasPrimaryKey = hasPrimaryKey || constraint.Type == "PRIMARY KEY"
+	}
+
+	if !hasPrimaryKey {
+		t.Errorf("table should have primary key, got %+v", constraints)
+	}
+
+	if err := DB.Migrator().(migrator.IndexInterface).PromoteToPrimaryKey(&PromoteUser{}, "idx_promote_users_id"); err == nil || !strings.Contains(err.Error(), "primary key") {
+		t.Errorf("should not promote index of table with primary key, got %v", err)
+	}
+
+	if result, err := m.AutoMigrateWithResult(&PromoteUser{}); err != nil || result.Count("promote_primary_key") != 0 {
+		t.Errorf("promoted index should not be promoted again, got %+v, error %v", result, err)
+	}
+}
+
+func TestConstraintIndexTablespace(t *testing.T) {
+	type TablespaceItem struct {
+		ID     uint   `gorm:"primaryKey;indexTablespace:pg_default"`
+		Code   string `gorm:"size:100;uniqueConstraint:uni_tablespace_items_code,deferrable,indexTablespace:pg_default"`
+		Serial string `gorm:"size:100;uniqueConstraint"`
+	}
+
+	DB.Migrator().DropTable(&TablespaceItem{})
+	recorder := &recordSQLLogger{Interface: DB.Logger}
+	tx := DB.Session(&gorm.Session{Context: context.Background(), Logger: recorder})
+	m := newMigrator(tx, migrator.Config{})
+	if err := m.CreateTable(&TablespaceItem{}); err != nil {
+		t.Fatalf("failed to create table with index tablespace, got error %v", err)
+	}
+
+	createTableSQLs := recorder.statementsOf("CREATE TABLE")
+	if len(createTableSQLs) != 1 {
+		t.Fatalf("table should be created, but got %v", recorder.sqls)
+	}
+
+	if DB.Dialector.Name() != "postgres" {
+		if strings.Contains(createTableSQLs[0], "TABLESPACE") {
+			t.Errorf("index tablespace should be ignored by other dialects, got %v", createTableSQLs[0])
+		}
+		return
+	}
+
+	if !regexp.MustCompile(`PRIMARY KEY \(.id.\) USING INDEX TABLESPACE .pg_default.,`).MatchString(createTableSQLs[0]) {
+		t.Errorf("primary key should be created in tablespace, but got %v", createTableSQLs[0])
+	}
+
+	for _, name := range []string{"uni_tablespace_items_code", "uni_tablespace_items_serial"} {
+		if err := m.DropConstraint(&TablespaceItem{}, name); err != nil {
+			t.Fatalf("failed to drop unique constraint, got error %v", err)
+		}
+	}
+
+	// postgres rejects USING INDEX TABLESPACE after DEFERRABLE
+	recorder.sqls = nil
+	for _, name := range []string{"uni_tablespace_items_code", "uni_tablespace_items_serial"} {
+		if err := m.CreateConstraint(&TablespaceItem{}, name); err != nil {
+			t.Fatalf("failed to create unique constraint, got error %v", err)
+		}
+	}
+
+	if alterSQLs := recorder.statementsOf("ALTER TABLE"); len(alterSQLs) != 2 || !strings.Contains(alterSQLs[0], "USING INDEX TABLESPACE") || strings.Contains(alterSQLs[1], "TABLESPACE") {
+		t.Errorf("unique constraint should be created in its tablespace only, but got %v", recorder.sqls)
+	}
+}
+
+func TestHasIndexColumns(t *testing.T) {
+	type IndexColumnsStruct struct {
+		ID       uint
+		TenantID uint   `gorm:"index:idx_index_columns_tenant_name"`
+		Name     string `gorm:"size:100;index:idx_index_columns_tenant_name"`
+		Code     string `gorm:"size:100"`
+	}
+
+	DB.Migrator().DropTable(&IndexColumnsStruct{})
+	if err := DB.Migrator().CreateTable(&IndexColumnsStruct{}); err != nil {
+		t.Fatalf("Failed to create table, got error %v", err)
+	}
+
+	if _, err := DB.Migrator().(migrator.IndexInterface).HasIndexColumns(&IndexColumnsStruct{}, "tenant_id"); errors.Is(err, gorm.ErrNotImplemented) {
+		t.Skipf("skip %v, whose migrator doesn't reflect indexes", DB.Dialector.Name())
+	}
+
+	for _, columns := range [][]string{{"tenant_id"}, {"tenant_id", "name"}, {"name", "tenant_id"}} {
+		if ok, err := DB.Migrator().(migrator.IndexInterface).HasIndexColumns(&IndexColumnsStruct{}, columns...); err != nil || !ok {
+			t.Errorf("should find index leading with %v, got %v, error %v", columns, ok, err)
+		}
+	}
+
+	for _, columns := range [][]string{{"name"}, {"code"}, {"tenant_id", "code"}, {"tenant_id", "name", "code"}} {
+		if ok, err := DB.Migrator().(migrator.IndexInterface).HasIndexColumns(&IndexColumnsStruct{}, columns...); err != nil || ok {
+			t.Errorf("should not find index leading with %v, got %v, error %v", columns, ok, err)
+		}
+	}
+}
+
+func TestValidateCheckConstraints(t *testing.T) {
+	type CheckViolationStruct struct {
+		ID  uint
+		Age *int `gorm:"check:chk_check_violation_structs_age,age > 0"`
+	}
+
+	DB.Migrator().DropTable("check_violation_structs")
+	if err := DB.Exec("CREATE TABLE check_violation_structs (id integer, age integer)").Error; err != nil {
+		t.Fatalf("failed to create table, got error %v", err)
+	}
+
+	if err := DB.Exec("INSERT INTO check_violation_structs (id, age) VALUES (1, 18), (2, 0), (3, -1), (4, NULL)").Error; err != nil {
+		t.Fatalf("failed to insert rows, got error %v", err)
+	}
+
+	m := newMigrator(DB, migrator.Config{ValidateCheckConstraints: true})
+	err := m.CreateConstraint(&CheckViolationStruct{}, "chk_check_violation_structs_age")
+
+	var violation migrator.CheckViolationError
+	if !errors.As(err, &violation) || violation.Rows != 2 || violation.Name != "chk_check_violation_structs_age" {
+		t.Fatalf("should report rows violating the check constraint, got %v", err)
+	}
+
+	if err.Error() != "2 rows of check_violation_structs violate check constraint chk_check_violation_structs_age: age > 0" {
+		t.Errorf("error should describe the violation, got %v", err)
+	}
+
+	if _, err := m.AutoMigrateWithResult(&CheckViolationStruct{}); !errors.As(err, &violation) {
+		t.Errorf("auto migrate should report rows violating the check constraint, got %v", err)
+	}
+}
+
+func TestPreserveTriggersOfShadowAlterColumn(t *testing.T) {
+	type TriggerStruct struct {
+		ID   uint
+		Code int
+	}
+
+	type TriggerStruct2 struct {
+		ID   uint
+		Code string `gorm:"size:100"`
+	}
+
+	DB.Migrator().DropTable(&TriggerStruct{}, "trigger_audits")
+	if err := DB.AutoMigrate(&TriggerStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if DB.Dialector.Name() != "sqlite" {
+		t.Skip("trigger syntax in this test is sqlite only")
+	}
+
+	if _, err := DB.Migrator().(migrator.TriggersInterface).GetTriggers(&TriggerStruct{}); errors.Is(err, gorm.ErrNotImplemented) {
+		t.Skip("skip migrators not reflecting triggers, which can't be preserved")
+	}
+
+	DB.Exec("CREATE TABLE trigger_audits (struct_id integer)")
+	if err := DB.Exec("CREATE TRIGGER trg_trigger_structs_audit AFTER INSERT ON trigger_structs BEGIN INSERT INTO trigger_audits (struct_id) VALUES (NEW.id); END").Error; err != nil {
+		t.Fatalf("Failed to create trigger, got error %v", err)
+	}
+
+	DB.Create(&TriggerStruct{Code: 10})
+	if err := shadowMigratorOf(DB.Table("trigger_structs")).ShadowAlterColumn(&TriggerStruct2{}, "Code", 10); err != nil {
+		t.Fatalf("Failed to migrate column with shadow column, got error %v", err)
+	}
+
+	triggers, err := DB.Migrator().(migrator.TriggersInterface).GetTriggers(&TriggerStruct{})
+	if err != nil || len(triggers) != 1 || triggers[0].Name != "trg_trigger_structs_audit" {
+		t.Fatalf("trigger should be recreated after the table is rebuilt, got %+v, error %v", triggers, err)
+	}
+
+	DB.Table("trigger_structs").Create(&TriggerStruct2{Code: "20"})
+	var count int64
+	if DB.Table("trigger_audits").Count(&count); count != 2 {
+		t.Errorf("recreated trigger should work, got %v audits", count)
+	}
+}
+
+func TestAutoIncrementDataType(t *testing.T) {
+	type AutoIncrementStruct struct {
+		ID   uint64 `gorm:"type:bigint;primaryKey;autoIncrement"`
+		Name string
+	}
+
+	stmt := &gorm.Statement{DB: DB}
+	if err := stmt.Parse(&AutoIncrementStruct{}); err != nil {
+		t.Fatalf("failed to parse model, got error %v", err)
+	}
+	field := stmt.Schema.LookUpField("ID")
+
+	expects := map[string]string{
+		"mysql":     "bigint AUTO_INCREMENT",
+		"postgres":  "bigserial",
+		"sqlserver": "bigint IDENTITY(1,1)",
+		"sqlite":    "integer PRIMARY KEY AUTOINCREMENT",
+	}[DB.Dialector.Name()]
+
+	if _, ok := DB.Migrator().(migrator.AutoIncrementInterface); !ok && DB.Dialector.Name() != "mysql" {
+		t.Skipf("skip %v, whose migrator doesn't render auto increment columns of explicit types", DB.Dialector.Name())
+	}
+
+	if sql := DB.Migrator().(interface {
+		FullDataTypeOf(*schema.Field) clause.Expr
+	}).FullDataTypeOf(field).SQL; sql != expects {
+		t.Errorf("%v auto increment column should be %v, but got %v", DB.Dialector.Name(), expects, sql)
+	}
+
+	DB.Migrator().DropTable(&AutoIncrementStruct{})
+	if err := DB.Migrator().CreateTable(&AutoIncrementStruct{}); err != nil {
+		t.Fatalf("failed to create table with auto increment column, got error %v", err)
+	}
+
+	if err := DB.Exec("INSERT INTO auto_increment_structs (name) VALUES (?), (?)", "first", "second").Error; err != nil {
+		t.Fatalf("failed to insert rows, got error %v", err)
+	}
+
+	var results []AutoIncrementStruct
+	if DB.Order("id").Find(&results); len(results) != 2 || results[0].ID == 0 || results[1].ID <= results[0].ID {
+		t.Errorf("ids should be generated by auto increment column, got %+v", results)
+	}
+}
+
+type DecimalSizeStruct struct {
+	ID     uint
+	Amount float64 `gorm:"type:decimal(10,2)"`
+}
+
+type DecimalSizeStruct2 struct {
+	ID     uint
+	Amount float64 `gorm:"type:decimal(12,4)"`
+}
+
+func (DecimalSizeStruct2) TableName() string {
+	return "decimal_size_structs"
+}
+
+type DecimalSizeStruct3 struct {
+	ID     uint
+	Amount float64 `gorm:"type:decimal(10,4)"`
+}
+
+func (DecimalSizeStruct3) TableName() string {
+	return "decimal_size_structs"
+}
+
+func TestMigrateDecimalSize(t *testing.T) {
+	DB.Migrator().DropTable(&DecimalSizeStruct{})
+	if err := DB.AutoMigrate(&DecimalSizeStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+	DB.Create(&DecimalSizeStruct{Amount: 12345678.12})
+
+	tx := DB.Session(&gorm.Session{Context: context.Background()})
+	tx.Statement.ConnPool = skipExecConnPool{tx.Statement.ConnPool}
+
+	if result, err := newMigrator(tx, migrator.Config{MigrateColumnTypesWhenAutoMigrate: true}).AutoMigrateWithResult(&DecimalSizeStruct{}); err != nil || result.Count("alter_column") != 0 {
+		t.Errorf("unchanged decimal size should not be altered, got %+v, error %v", result, err)
+	}
+
+	var changes []migrator.ColumnChange
+	m := newMigrator(tx, migrator.Config{MigrateColumnTypesWhenAutoMigrate: true, ColumnChangeHook: func(change migrator.ColumnChange) error {
+		changes = append(changes, change)
+		return nil
+	}})
+
+	result, err := m.AutoMigrateWithResult(&DecimalSizeStruct2{})
+	if err != nil {
+		t.Fatalf("widening decimal should be migrated, got error %v", err)
+	}
+
+	if result.Count("alter_column") != 1 || len(changes) != 1 || changes[0].Risk != migrator.ColumnChangeSafe {
+		t.Errorf("widening decimal(10,2) to decimal(12,4) should be one safe alter, got %+v, changes %+v", result, changes)
+	}
+
+	changes = nil
+	if _, err := m.AutoMigrateWithResult(&DecimalSizeStruct3{}); err == nil || !strings.Contains(err.Error(), "AllowDestructiveColumnChanges") {
+		t.Errorf("narrowing integer digits of decimal should require opt-in, got %v", err)
+	}
+
+	if len(changes) != 1 || changes[0].Risk != migrator.ColumnChangeDestructive {
+		t.Errorf("decimal(10,2) to decimal(10,4) should be destructive, got %+v", changes)
+	}
+}
+
+func TestDeferredAndManualIndexes(t *testing.T) {
+	type DeferredIndexStruct struct {
+		ID      uint
+		Name    string `gorm:"size:100;index:idx_deferred_index_structs_name"`
+		Content string `gorm:"size:100;index:idx_deferred_index_structs_content,deferred"`
+		Tags    string `gorm:"size:100;index:idx_deferred_index_structs_tags,manual"`
+	}
+
+	DB.Migrator().DropTable(&DeferredIndexStruct{})
+	if DB.Dialector.Name() == "mysql" {
+		// indexes are created inline by mysql unless deferred
+		recorder := &recordSQLLogger{Interface: DB.Logger}
+		tx := DB.Session(&gorm.Session{Context: context.Background(), Logger: recorder})
+		m := newMigrator(tx, migrator.Config{})
+		if err := m.CreateTable(&DeferredIndexStruct{}); err != nil {
+			t.Fatalf("failed to create table, got error %v", err)
+		}
+
+		createTableSQLs, createIndexSQLs := recorder.statementsOf("CREATE TABLE"), recorder.statementsOf("CREATE INDEX")
+		if len(createTableSQLs) != 1 || !strings.Contains(createTableSQLs[0], "INDEX `idx_deferred_index_structs_name`") ||
+			strings.Contains(createTableSQLs[0], "idx_deferred_index_structs_content") || strings.Contains(createTableSQLs[0], "idx_deferred_index_structs_tags") ||
+			len(createIndexSQLs) != 1 || !strings.HasPrefix(createIndexSQLs[0], "CREATE INDEX `idx_deferred_index_structs_content`") {
+			t.Errorf("deferred index should be created after the table and manual index should be skipped, but got %v", recorder.sqls)
+		}
+		DB.Migrator().DropTable(&DeferredIndexStruct{})
+	}
+
+	if err := DB.AutoMigrate(&DeferredIndexStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	if err := DB.AutoMigrate(&DeferredIndexStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	if !DB.Migrator().HasIndex(&DeferredIndexStruct{}, "idx_deferred_index_structs_name") || !DB.Migrator().HasIndex(&DeferredIndexStruct{}, "idx_deferred_index_structs_content") {
+		t.Errorf("inline and deferred indexes should be created")
+	}
+
+	if DB.Migrator().HasIndex(&DeferredIndexStruct{}, "idx_deferred_index_structs_tags") {
+		t.Errorf("manual index should not be created by auto migrate")
+	}
+
+	if err := DB.Migrator().CreateIndex(&DeferredIndexStruct{}, "idx_deferred_index_structs_tags"); err != nil || !DB.Migrator().HasIndex(&DeferredIndexStruct{}, "idx_deferred_index_structs_tags") {
+		t.Errorf("manual index should be created with CreateIndex, got error %v", err)
+	}
+}
+
+func TestCreateTableFailingDeferredIndex(t *testing.T) {
+	// the index can't be created, its name is taken by the table (sqlite, postgres) and text columns can't be keys (mysql, sqlserver)
+	type FailingDeferredIndexStruct struct {
+		ID      uint
+		Content string `gorm:"type:text;index:failing_deferred_index_structs,deferred"`
+	}
+
+	DB.Migrator().DropTable(&FailingDeferredIndexStruct{})
+	m := newMigrator(DB, migrator.Config{})
+	if err := m.CreateTable(&FailingDeferredIndexStruct{}); err == nil {
+		t.Errorf("failing deferred index should fail creating table")
+	}
+	DB.Migrator().DropTable(&FailingDeferredIndexStruct{})
+}
+
+type ReservedWordStruct struct {
+	ID    uint
+	Key   string `gorm:"size:100;index:select"`
+	Order int    `gorm:"index:where"`
+	Group string `gorm:"size:100;index:from,unique"`
+}
+
+type ReservedWordConstraintStruct struct {
+	ID    uint
+	Key   string `gorm:"size:100;index:select;uniqueConstraint:table"`
+	Order int    `gorm:"index:where;check:check,\"order\" >= 0"`
+	Group string `gorm:"size:100;index:from,unique"`
+}
+
+func (ReservedWordConstraintStruct) TableName() string {
+	return "order"
+}
+
+func (ReservedWordStruct) TableName() string {
+	return "group"
+}
+
+func TestReservedWordIdentifiers(t *testing.T) {
+	DB.Migrator().DropTable(&ReservedWordStruct{})
+	if err := DB.AutoMigrate(&ReservedWordStruct{}); err != nil {
+		t.Fatalf("failed to create table with reserved words, got error %v", err)
+	}
+
+	if err := DB.AutoMigrate(&ReservedWordStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate table with reserved words, got error %v", err)
+	}
+
+	for _, name := range []string{"select", "where", "from"} {
+		if !DB.Migrator().HasIndex(&ReservedWordStruct{}, name) {
+			t.Errorf("should have index %v", name)
+		}
+	}
+
+	if name := DB.Dialector.Name(); name != "mysql" && name != "postgres" {
+		t.Skip("skip dialects without adding indexes and constraints of reserved words to existing tables")
+	}
+
+	// unquoted reserved words are rejected by the database
+	DB.Migrator().DropTable(&ReservedWordConstraintStruct{})
+	m := newMigrator(DB, migrator.Config{})
+	if err := m.CreateTable(&ReservedWordConstraintStruct{}); err != nil {
+		t.Fatalf("failed to create table with reserved words, got error %v", err)
+	}
+
+	for _, name := range []string{"select", "where", "from"} {
+		if err := m.DropIndex(&ReservedWordConstraintStruct{}, name); err != nil {
+			t.Errorf("failed to drop index %v, got error %v", name, err)
+		}
+
+		if err := m.CreateIndex(&ReservedWordConstraintStruct{}, name); err != nil || !m.HasIndex(&ReservedWordConstraintStruct{}, name) {
+			t.Errorf("failed to create index %v, got error %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"check", "table"} {
+		if err := m.DropConstraint(&ReservedWordConstraintStruct{}, name); err != nil {
+			t.Errorf("failed to drop constraint %v, got error %v", name, err)
+		}
+
+		if err := m.CreateConstraint(&ReservedWordConstraintStruct{}, name); err != nil || !m.HasConstraint(&ReservedWordConstraintStruct{}, name) {
+			t.Errorf("failed to create constraint %v, got error %v", name, err)
+		}
+	}
+	DB.Migrator().DropTable(&ReservedWordConstraintStruct{})
+}
+
+type ReplicaIdentityStruct struct {
+	ID    uint
+	Email string `gorm:"size:100;uniqueIndex:idx_replica_identity_email;not null"`
+}
+
+func (ReplicaIdentityStruct) TableReplicaIdentity() string {
+	return "using index idx_replica_identity_email"
+}
+
+func TestSetReplicaIdentity(t *testing.T) {
+	DB.Migrator().DropTable(&ReplicaIdentityStruct{})
+	m := newMigrator(DB, migrator.Config{CreateIndexAfterCreateTable: true, SkipUnsupported: true})
+	result, err := m.AutoMigrateWithResult(&ReplicaIdentityStruct{})
+	if err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	if DB.Dialector.Name() != "postgres" {
+		if err := DB.Migrator().(migrator.ReplicaIdentityInterface).SetReplicaIdentity(&ReplicaIdentityStruct{}, "FULL"); !errors.Is(err, gorm.ErrNotImplemented) {
+			t.Errorf("replica identity should not be implemented by %v, got %v", DB.Dialector.Name(), err)
+		}
+
+		if result.Count("skip_set_replica_identity") != 1 {
+			t.Errorf("unsupported replica identity should be skipped, got %+v", result)
+		}
+		return
+	}
+
+	if identity, err := m.ReplicaIdentityOf(&ReplicaIdentityStruct{}); err != nil || identity != "USING INDEX idx_replica_identity_email" {
+		t.Errorf("replica identity of the model should be set by auto migrate, got %v, error %v", identity, err)
+	}
+
+	if err := m.SetReplicaIdentity(&ReplicaIdentityStruct{}, "full"); err != nil {
+		t.Fatalf("failed to set replica identity, got error %v", err)
+	}
+
+	if identity, err := m.ReplicaIdentityOf(&ReplicaIdentityStruct{}); err != nil || identity != "FULL" {
+		t.Errorf("replica identity should be set to FULL, got %v, error %v", identity, err)
+	}
+
+	if err := m.SetReplicaIdentity(&ReplicaIdentityStruct{}, "partial"); err == nil {
+		t.Errorf("should return error for invalid replica identity")
+	}
+}
+
+func TestValidateIndexKeyLength(t *testing.T) {
+	type IndexKeyLengthStruct struct {
+		ID      uint
+		Name    string `gorm:"size:1000;index:idx_index_key_length_name"`
+		Title   string `gorm:"size:1000;index:idx_index_key_length_title,length:191"`
+		Code    string `gorm:"type:varchar(1000) CHARACTER SET latin1;index:idx_index_key_length_code"`
+		Tenant  string `gorm:"size:500;index:idx_index_key_length_tenant_email"`
+		Email   string `gorm:"size:500;index:idx_index_key_length_tenant_email"`
+		Profile string `gorm:"size:1000;index:idx_index_key_length_profile,length:700"`
+	}
+
+	if DB.Dialector.Name() != "mysql" {
+		DB.Migrator().DropTable(&IndexKeyLengthStruct{})
+		if err := DB.Exec("CREATE TABLE index_key_length_structs (id integer PRIMARY KEY, name varchar(1000))").Error; err != nil {
+			t.Fatalf("failed to create table, got error %v", err)
+		}
+
+		if err := DB.Migrator().CreateIndex(&IndexKeyLengthStruct{}, "idx_index_key_length_name"); err != nil {
+			t.Errorf("index key length should only be validated for mysql, got %v", err)
+		}
+		t.Skip("index key length is only validated for mysql")
+	}
+
+	// bytes of characters are decided by the charset of columns
+	DB.Migrator().DropTable(&IndexKeyLengthStruct{})
+	if err := DB.Exec("CREATE TABLE index_key_length_structs (id bigint unsigned AUTO_INCREMENT PRIMARY KEY, name varchar(1000), title varchar(1000), code varchar(1000) CHARACTER SET latin1, tenant varchar(500), email varchar(500), profile varchar(1000)) CHARACTER SET utf8mb4").Error; err != nil {
+		t.Fatalf("failed to create table, got error %v", err)
+	}
+
+	m := newMigrator(DB, migrator.Config{})
+	for name, length := range map[string]int{"idx_index_key_length_name": 4000, "idx_index_key_length_tenant_email": 4000} {
+		var keyErr migrator.IndexKeyTooLongError
+		if err := m.CreateIndex(&IndexKeyLengthStruct{}, name); !errors.As(err, &keyErr) || keyErr.Length != length || keyErr.MaxLength != migrator.DefaultMaxIndexKeyLength {
+			t.Errorf("index %v of utf8mb4 columns should be too long, got %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"idx_index_key_length_title", "idx_index_key_length_code", "idx_index_key_length_profile"} {
+		if err := m.CreateIndex(&IndexKeyLengthStruct{}, name); err != nil || !m.HasIndex(&IndexKeyLengthStruct{}, name) {
+			t.Errorf("index %v should fit in the max key length, got %v", name, err)
+		}
+	}
+
+	m.MaxIndexKeyLength = 767
+	if err := m.DropIndex(&IndexKeyLengthStruct{}, "idx_index_key_length_profile"); err != nil {
+		t.Fatalf("failed to drop index, got error %v", err)
+	}
+
+	if err := m.CreateIndex(&IndexKeyLengthStruct{}, "idx_index_key_length_profile"); !errors.As(err, &migrator.IndexKeyTooLongError{}) {
+		t.Errorf("index should exceed the max key length of compact row format, got %v", err)
+	}
+	DB.Migrator().DropTable(&IndexKeyLengthStruct{})
+}
+
+type CancelMigrateStruct struct {
+	ID     uint
+	cancel context.CancelFunc
+}
+
+func (s *CancelMigrateStruct) AfterTableCreated(tx *gorm.DB) error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	return nil
+}
+
+type CancelMigrateStruct2 struct {
+	ID uint
+}
+
+func TestAutoMigrateContext(t *testing.T) {
+	DB.Migrator().DropTable(&CancelMigrateStruct{}, &CancelMigrateStruct2{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := DB.Migrator().(migrator.AutoMigrateInterface).AutoMigrateContext(ctx, &CancelMigrateStruct{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("auto migrate should be canceled, got %v", err)
+	}
+
+	if DB.Migrator().HasTable(&CancelMigrateStruct{}) {
+		t.Errorf("table should not be created after canceling")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	if err := DB.Migrator().(migrator.AutoMigrateInterface).AutoMigrateContext(ctx, &CancelMigrateStruct{cancel: cancel}, &CancelMigrateStruct2{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("auto migrate should be canceled, got %v", err)
+	}
+
+	if !DB.Migrator().HasTable(&CancelMigrateStruct{}) || DB.Migrator().HasTable(&CancelMigrateStruct2{}) {
+		t.Errorf("models after canceling should not be migrated")
+	}
+
+	if err := DB.Migrator().(migrator.AutoMigrateInterface).AutoMigrateContext(context.Background(), &CancelMigrateStruct{}, &CancelMigrateStruct2{}); err != nil || !DB.Migrator().HasTable(&CancelMigrateStruct2{}) {
+		t.Errorf("failed to auto migrate, got error %v", err)
+	}
+}
+
+func TestAutoMigrateProgressHook(t *testing.T) {
+	DB.Migrator().DropTable(&CancelMigrateStruct{}, &CancelMigrateStruct2{})
+	if err := DB.AutoMigrate(&CancelMigrateStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	var progresses []migrator.MigrateProgress
+	m := newMigrator(DB, migrator.Config{ProgressHook: func(progress migrator.MigrateProgress) {
+		progresses = append(progresses, progress)
+	}})
+
+	if err := m.AutoMigrateContext(context.Background(), &CancelMigrateStruct{}, &CancelMigrateStruct2{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	if len(progresses) != 2 {
+		t.Fatalf("progress of each model should be reported, got %+v", progresses)
+	}
+
+	for idx, table := range []string{"cancel_migrate_structs", "cancel_migrate_struct2"} {
+		if progress := progresses[idx]; progress.Table != table || progress.Index != idx+1 || progress.Total != 2 || progress.Error != nil {
+			t.Errorf("progress #%v should be of %v, got %+v", idx+1, table, progress)
+		}
+	}
+
+	if ops := progresses[0].Result.Operations; len(ops) != 0 {
+		t.Errorf("existing table should be unchanged, got %+v", ops)
+	}
+
+	if ops := progresses[1].Result.Operations; len(ops) == 0 || ops[0].Type != "create_table" {
+		t.Errorf("missing table should be created, got %+v", ops)
+	}
+}
+
+func TestMigrateColumnUnique(t *testing.T) {
+	type ColumnUniqueStruct struct {
+		ID    uint
+		Email string `gorm:"size:100"`
+		Code  string `gorm:"size:100;unique"`
+	}
+
+	type ColumnUniqueStruct2 struct {
+		ID    uint
+		Email string `gorm:"size:100;unique"`
+		Code  string `gorm:"size:100"`
+	}
+
+	DB.Migrator().DropTable(&ColumnUniqueStruct{})
+	if err := DB.AutoMigrate(&ColumnUniqueStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	if err := DB.Create(&ColumnUniqueStruct{Email: "jinzhu@example.org", Code: "jinzhu"}).Error; err != nil {
+		t.Fatalf("Failed to create record, got error %v", err)
+	}
+
+	if _, err := DB.Migrator().(migrator.IndexesInterface).GetIndexes(&ColumnUniqueStruct{}); errors.Is(err, gorm.ErrNotImplemented) {
+		t.Skipf("skip %v, whose migrator doesn't reflect unique indexes", DB.Dialector.Name())
+	}
+
+	columnTypesOf := func() map[string]string {
+		types := map[string]string{}
+		columnTypes, err := DB.Migrator().ColumnTypes(&ColumnUniqueStruct{})
+		if err != nil {
+			t.Fatalf("Failed to get column types, got error %v", err)
+		}
+
+		for _, columnType := range columnTypes {
+			types[columnType.Name()] = columnType.DatabaseTypeName()
+		}
+		return types
+	}
+	types := columnTypesOf()
+
+	m := newMigrator(DB.Table("column_unique_structs"), migrator.Config{MigrateColumnTypesWhenAutoMigrate: true, MigrateUniqueWhenAutoMigrate: true, SkipUnsupported: true})
+	result, err := m.AutoMigrateWithResult(&ColumnUniqueStruct2{})
+	if err != nil {
+		t.Fatalf("Failed to auto migrate unique changes, got error %v", err)
+	}
+
+	if result.Count("alter_column_unique") != 1 || result.Count("alter_column") != 0 {
+		t.Errorf("unique of email should be migrated without altering column, got %+v", result)
+	}
+
+	if DB.Dialector.Name() == "sqlite" && result.Count("skip_alter_column_unique") != 1 {
+		t.Errorf("inline unique constraint of code should be skipped, got %+v", result)
+	}
+
+	if !reflect.DeepEqual(types, columnTypesOf()) {
+		t.Errorf("column types should be unchanged, expects %v, got %v", types, columnTypesOf())
+	}
+
+	if err := DB.Create(&ColumnUniqueStruct{Email: "jinzhu@example.org", Code: "jinzhu2"}).Error; err == nil {
+		t.Errorf("email should be unique")
+	}
+
+	if result, err := m.AutoMigrateWithResult(&ColumnUniqueStruct{}); err != nil || result.Count("alter_column_unique") != 1 {
+		t.Errorf("unique of email should be dropped, got %+v, error %v", result, err)
+	}
+
+	if err := DB.Create(&ColumnUniqueStruct{Email: "jinzhu@example.org", Code: "jinzhu3"}).Error; err != nil {
+		t.Errorf("email should not be unique, got error %v", err)
+	}
+}
+
+func TestPlanAutoMigrate(t *testing.T) {
+	type PlanStruct struct {
+		ID   uint
+		Name string `gorm:"size:100"`
+	}
+
+	type PlanStruct2 struct {
+		ID   uint
+		Name string `gorm:"size:100;index:idx_plan_structs_name"`
+		Age  int
+	}
+
+	DB.Migrator().DropTable(&PlanStruct{})
+	if err := DB.AutoMigrate(&PlanStruct{}); err != nil {
+		t.Fatalf("Failed to auto migrate, got error %v", err)
+	}
+
+	m := newMigrator(DB.Table("plan_structs"), migrator.Config{})
+	result, err := m.PlanAutoMigrate(&PlanStruct2{})
+	if err != nil {
+		t.Fatalf("Failed to plan auto migrate, got error %v", err)
+	}
+
+	if len(result.Tables) != 1 || len(result.Tables[0].Operations) != 2 || len(result.Tables[0].SQL) != 2 {
+		t.Fatalf("should plan adding column and index, got %+v", result)
+	}
+
+	for idx, op := range []migrator.MigrateOperation{{Type: "add_column", Name: "age", Lock: m.LockImpactOf("add_column")}, {Type: "create_index", Name: "idx_plan_structs_name", Lock: m.LockImpactOf("create_index")}} {
+		if result.Tables[0].Operations[idx] != op || op.Lock == "" {
+			t.Errorf("operation #%v should be %+v, got %+v", idx+1, op, result.Tables[0].Operations[idx])
+		}
+	}
+
+	if !regexp.MustCompile("ADD .age.").MatchString(result.Tables[0].SQL[0]) || !regexp.MustCompile("CREATE INDEX .idx_plan_structs_name.").MatchString(result.Tables[0].SQL[1]) {
+		t.Errorf("should plan statements with values, got %v", result.Tables[0].SQL)
+	}
+
+	if DB.Migrator().HasColumn(&PlanStruct{}, "age") || DB.Migrator().HasIndex(&PlanStruct{}, "idx_plan_structs_name") {
+		t.Errorf("planned statements should not be executed")
+	}
+
+	switch DB.Dialector.Name() {
+	case "mysql":
+		for typ, lock := range map[string]string{"add_column": migrator.LockInstant, "alter_column": migrator.LockFullRewrite, "create_index": migrator.LockMetadataOnly, "skip_create_index": "", "rename_table": migrator.LockExclusiveLock} {
+			if m.LockImpactOf(typ) != lock {
+				t.Errorf("lock impact of %v should be %v, got %v", typ, lock, m.LockImpactOf(typ))
+			}
+		}
+	case "postgres":
+		if lock := m.LockImpactOf("alter_column_nullability"); lock != migrator.LockExclusiveLock {
+			t.Errorf("SET NOT NULL should take an exclusive lock, got %v", lock)
+		}
+	}
+}
+
+func TestConvertToPartitioned(t *testing.T) {
+	type PartitionEvent struct {
+		ID        uint
+		CreatedAt time.Time
+	}
+
+	if DB.Dialector.Name() != "postgres" {
+		if err := DB.Migrator().(migrator.PartitionInterface).ConvertToPartitioned(&PartitionEvent{}, migrator.PartitionOption{By: "RANGE (created_at)"}); !errors.Is(err, gorm.ErrNotImplemented) {
+			t.Errorf("converting to partitioned table should not be implemented by %v, got %v", DB.Dialector.Name(), err)
+		}
+		return
+	}
+
+	m := newMigrator(DB, migrator.Config{})
+	if err := m.ConvertToPartitioned(&PartitionEvent{}, migrator.PartitionOption{}); err == nil {
+		t.Errorf("should return error without partition key")
+	}
+
+	partitionOf := func(partition string) (parent string) {
+		DB.Raw("SELECT p.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid JOIN pg_class p ON p.oid = i.inhparent WHERE c.relname = ? AND c.relispartition", partition).Row().Scan(&parent)
+		return
+	}
+
+	for _, option := range []migrator.PartitionOption{
+		{By: "RANGE (created_at)", Values: "FROM (MINVALUE) TO ('2021-01-01')"},
+		{By: "LIST (id)", Partition: "partition_events_eu"},
+	} {
+		DB.Migrator().DropTable("partition_events", "partition_events_legacy", "partition_events_eu")
+		if err := DB.Migrator().CreateTable(&PartitionEvent{}); err != nil {
+			t.Fatalf("failed to create table, got error %v", err)
+		}
+
+		DB.Create(&PartitionEvent{CreatedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)})
+		if err := m.ConvertToPartitioned(&PartitionEvent{}, option); err != nil {
+			t.Fatalf("failed to convert to partitioned table by %v, got error %v", option.By, err)
+		}
+
+		partition := option.Partition
+		if partition == "" {
+			partition = "partition_events_legacy"
+		}
+
+		if parent := partitionOf(partition); parent != "partition_events" {
+			t.Errorf("table should be attached to partitioned table as %v, got parent %v", partition, parent)
+		}
+
+		var count int64
+		if DB.Model(&PartitionEvent{}).Count(&count); count != 1 {
+			t.Errorf("rows should be kept in the attached partition, got %v", count)
+		}
+	}
+	DB.Migrator().DropTable("partition_events")
+}
+
+func TestCreateUniqueIndexNullsNotDistinct(t *testing.T) {
+	type NullsNotDistinctStruct struct {
+		ID     uint
+		Serial *string `gorm:"size:100;uniqueIndex:idx_nulls_not_distinct_serial,nullsNotDistinct,where:serial <> ''"`
+		Code   *string `gorm:"size:100;uniqueIndex:idx_nulls_not_distinct_code"`
+	}
+
+	// postgres rejects NULLS NOT DISTINCT after the predicate, others ignore it
+	DB.Migrator().DropTable(&NullsNotDistinctStruct{})
+	if err := DB.AutoMigrate(&NullsNotDistinctStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	indexes, err := DB.Migrator().(migrator.IndexesInterface).GetIndexes(&NullsNotDistinctStruct{})
+	if errors.Is(err, gorm.ErrNotImplemented) {
+		t.Skipf("skip %v, whose migrator doesn't reflect indexes", DB.Dialector.Name())
+	} else if err != nil || len(indexes) != 2 {
+		t.Fatalf("failed to get indexes, got %+v, error %v", indexes, err)
+	}
+
+	for _, idx := range indexes {
+		if idx.NullsNotDistinct != (idx.Name == "idx_nulls_not_distinct_serial" && DB.Dialector.Name() == "postgres") {
+			t.Errorf("NULLS NOT DISTINCT of index %v should be reflected, got %+v", idx.Name, idx)
+		}
+	}
+}
+
+func TestAddColumnWithForeignKey(t *testing.T) {
+	type AddColumnCompany struct {
+		ID uint
+	}
+
+	type AddColumnStruct struct {
+		ID        uint
+		CompanyID *uint
+		Company   AddColumnCompany `gorm:"constraint:OnDelete:CASCADE"`
+	}
+
+	name := DB.Dialector.Name()
+	if name != "sqlite" && name != "postgres" && name != "mysql" {
+		t.Skip("skip dialects adding foreign keys after columns")
+	}
+
+	if _, ok := DB.Migrator().(migrator.ColumnForeignKeyInterface); name == "sqlite" && !ok {
+		t.Skip("skip sqlite migrators not adding foreign keys with columns")
+	}
+
+	DB.Migrator().DropTable(&AddColumnStruct{}, &AddColumnCompany{})
+	if err := DB.Migrator().CreateTable(&AddColumnCompany{}); err != nil {
+		t.Fatalf("failed to create table, got error %v", err)
+	}
+
+	if err := DB.Table("add_column_structs").Migrator().CreateTable(&struct{ ID uint }{}); err != nil {
+		t.Fatalf("failed to create table, got error %v", err)
+	}
+
+	recorder := &recordSQLLogger{Interface: DB.Logger}
+	tx := DB.Session(&gorm.Session{Context: context.Background(), Logger: recorder})
+	m := newMigrator(tx, migrator.Config{AddForeignKeysWithColumn: true})
+	if err := m.AddColumn(&AddColumnStruct{}, "CompanyID"); err != nil {
+		t.Fatalf("failed to add column, got error %v", err)
+	}
+
+	if name == "sqlite" {
+		var table, onDelete string
+		if err := DB.Raw("SELECT \"table\", on_delete FROM pragma_foreign_key_list('add_column_structs') WHERE \"from\" = 'company_id'").Row().Scan(&table, &onDelete); err != nil || table != "add_column_companies" || onDelete != "CASCADE" {
+			t.Errorf("foreign key should be added with the column, got %v %v, error %v", table, onDelete, err)
+		}
+		return
+	}
+
+	if len(recorder.statementsOf("ALTER TABLE")) != 1 {
+		t.Errorf("column should be added with its foreign key in one statement, got %v", recorder.sqls)
+	}
+
+	foreignKeyOf := func() (foreignKey migrator.ConstraintInfo) {
+		constraints, err := DB.Migrator().(migrator.ConstraintsInterface).GetConstraints(&AddColumnStruct{})
+		if err != nil {
+			t.Fatalf("failed to get constraints, got error %v", err)
+		}
+
+		for _, constraint := range constraints {
+			if constraint.Name == "fk_add_column_structs_company" {
+				foreignKey = constraint
+			}
+		}
+		return
+	}
+
+	if foreignKey := foreignKeyOf(); foreignKey.Type != "FOREIGN KEY" || foreignKey.OnDelete != "CASCADE" {
+		t.Errorf("foreign key should be added with the column, got %+v", foreignKey)
+	}
+
+	if err := DB.Migrator().DropConstraint(&AddColumnStruct{}, "fk_add_column_structs_company"); err != nil {
+		t.Fatalf("failed to drop constraint, got error %v", err)
+	}
+
+	if err := DB.Migrator().DropColumn(&AddColumnStruct{}, "CompanyID"); err != nil {
+		t.Fatalf("failed to drop column, got error %v", err)
+	}
+
+	m.AddForeignKeysWithColumn = false
+	if err := m.AddColumn(&AddColumnStruct{}, "CompanyID"); err != nil || foreignKeyOf().Name != "" {
+		t.Errorf("foreign key should be left alone unless enabled, got error %v", err)
+	}
+}
+
+func TestAutoMigrateAddColumnWithForeignKey(t *testing.T) {
+	type AutoAddColumnCompany struct {
+		ID uint
+	}
+
+	type AutoAddColumnStruct struct {
+		ID        uint
+		CompanyID *uint
+		Company   AutoAddColumnCompany
+	}
+
+	if _, ok := DB.Migrator().(migrator.ColumnForeignKeyInterface); DB.Dialector.Name() == "sqlite" && !ok {
+		t.Skip("skip sqlite migrators not adding foreign keys with columns")
+	}
+
+	DB.Migrator().DropTable(&AutoAddColumnStruct{}, &AutoAddColumnCompany{})
+	if err := DB.Migrator().CreateTable(&AutoAddColumnCompany{}); err != nil {
+		t.Fatalf("failed to create table, got error %v", err)
+	}
+
+	if err := DB.Table("auto_add_column_structs").Migrator().CreateTable(&struct{ ID uint }{}); err != nil {
+		t.Fatalf("failed to create table, got error %v", err)
+	}
+
+	recorder := &recordSQLLogger{Interface: DB.Logger}
+	m := newMigrator(DB.Session(&gorm.Session{Context: context.Background(), Logger: recorder}), migrator.Config{AddForeignKeysWithColumn: true, SkipUnsupported: true})
+	if err := m.AutoMigrate(&AutoAddColumnStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	var added bool
+	for _, sql := range recorder.sqls {
+		added = added || regexp.MustCompile(`^ALTER TABLE .auto_add_column_structs. ADD .company_id. .*REFERENCES .auto_add_column_companies.`).MatchString(sql)
+	}
+
+	if !added {
+		t.Errorf("column should be added with its foreign key by AutoMigrate, got %v", recorder.sqls)
+	}
+
+	DB.Migrator().DropTable(&AutoAddColumnStruct{}, &AutoAddColumnCompany{})
+}
+
+func TestMigrateWithSchema(t *testing.T) {
+	type SchemaCompany struct {
+		ID uint
+	}
+
+	type SchemaStruct struct {
+		ID        uint
+		Name      string `gorm:"index"`
+		CompanyID uint
+		Company   SchemaCompany
+	}
+
+	switch DB.Dialector.Name() {
+	case "postgres":
+		DB.Exec("CREATE SCHEMA IF NOT EXISTS tenant_x")
+	case "mysql":
+		DB.Exec("CREATE DATABASE IF NOT EXISTS tenant_x")
+	default:
+		t.Skip("skip dialects without schemas")
+	}
+
+	// references to tables missing in the default schema fail unless qualified
+	DB.Migrator().DropTable(&SchemaStruct{}, &SchemaCompany{})
+	m := newMigrator(DB, migrator.Config{CreateIndexAfterCreateTable: true}).WithSchema("tenant_x")
+	m.DropTable(&SchemaStruct{}, &SchemaCompany{})
+	if err := m.CreateTable(&SchemaCompany{}, &SchemaStruct{}); err != nil {
+		t.Fatalf("failed to create tables in schema tenant_x, got error %v", err)
+	}
+
+	if DB.Migrator().HasTable(&SchemaStruct{}) || !m.HasTable(&SchemaStruct{}) || !m.HasConstraint(&SchemaStruct{}, "fk_schema_structs_company") {
+		t.Errorf("tables should be created in schema tenant_x with the foreign key")
+	}
+
+	indexes, err := m.(migrator.IndexesInterface).GetIndexes(&SchemaStruct{})
+	if err != nil {
+		t.Fatalf("failed to get indexes, got error %v", err)
+	}
+
+	var found bool
+	for _, idx := range indexes {
+		found = found || idx.Name == "idx_schema_structs_name"
+	}
+
+	if !found {
+		t.Errorf("reflection queries should filter by the schema, got %+v", indexes)
+	}
+
+	if indexes, err := DB.Migrator().(migrator.IndexesInterface).GetIndexes(&SchemaStruct{}); err == nil && len(indexes) != 0 {
+		t.Errorf("reflection queries should filter by current schema by default, got %+v", indexes)
+	}
+
+	if err := m.DropTable(&SchemaStruct{}, &SchemaCompany{}); err != nil {
+		t.Errorf("failed to drop tables in schema tenant_x, got error %v", err)
+	}
+}
+
+func TestMigrateWithSchemaPostgres(t *testing.T) {
+	type TenantStruct struct {
+		ID   uint
+		Name string `gorm:"index"`
+	}
+
+	if DB.Dialector.Name() != "postgres" {
+		t.Skip("the postgres driver overrides reflection of tables, columns and indexes")
+	}
+
+	DB.Exec("CREATE SCHEMA IF NOT EXISTS tenant_pg")
+	DB.Migrator().DropTable(&TenantStruct{})
+
+	m := newMigrator(DB, migrator.Config{}).WithSchema("tenant_pg")
+	m.DropTable(&TenantStruct{})
+	if err := m.AutoMigrate(&TenantStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	if DB.Migrator().HasTable(&TenantStruct{}) {
+		t.Fatalf("table should be created in schema tenant_pg only")
+	}
+
+	if !m.HasTable(&TenantStruct{}) || !m.HasColumn(&TenantStruct{}, "Name") || !m.HasIndex(&TenantStruct{}, "Name") {
+		t.Fatalf("table, column and index should be reflected in schema tenant_pg")
+	}
+
+	// migrating again reflects the table of the schema instead of creating it
+	if err := m.AutoMigrate(&TenantStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate again, got error %v", err)
+	}
+
+	if reindexer, ok := m.(migrator.ReindexInterface); ok {
+		if err := reindexer.ReindexIndex(&TenantStruct{}, "Name"); err != nil {
+			t.Fatalf("failed to reindex index in schema tenant_pg, got error %v", err)
+		}
+	}
+
+	if err := m.RenameIndex(&TenantStruct{}, "idx_tenant_structs_name", "idx_tenant_structs_name_2"); err != nil {
+		t.Fatalf("failed to rename index, got error %v", err)
+	}
+
+	if err := m.DropIndex(&TenantStruct{}, "idx_tenant_structs_name_2"); err != nil || m.HasIndex(&TenantStruct{}, "idx_tenant_structs_name_2") {
+		t.Fatalf("index should be dropped in schema tenant_pg, got error %v", err)
+	}
+
+	if err := m.DropTable(&TenantStruct{}); err != nil || m.HasTable(&TenantStruct{}) {
+		t.Fatalf("table should be dropped in schema tenant_pg, got error %v", err)
+	}
+}
+
+func TestRenameIndexDialects(t *testing.T) {
+	type RenameIndexDialectStruct struct {
+		ID   uint
+		Name string `gorm:"index"`
+	}
+
+	DB.Migrator().DropTable(&RenameIndexDialectStruct{})
+	if err := DB.AutoMigrate(&RenameIndexDialectStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	// ALTER TABLE ? RENAME INDEX (MySQL), ALTER INDEX (Postgres), sp_rename (SQL Server)
+	var m gorm.Migrator = newMigrator(DB, migrator.Config{})
+	if DB.Dialector.Name() == "sqlite" {
+		// the sqlite driver renames indexes by creating them with the new name, the old one is kept
+		m = DB.Migrator()
+	}
+
+	if err := m.RenameIndex(&RenameIndexDialectStruct{}, "idx_rename_index_dialect_structs_name", "idx_rename_index_dialect_structs_nickname"); err != nil {
+		t.Fatalf("failed to rename index on %v, got error %v", DB.Dialector.Name(), err)
+	}
+
+	if (DB.Dialector.Name() != "sqlite" && m.HasIndex(&RenameIndexDialectStruct{}, "idx_rename_index_dialect_structs_name")) || !m.HasIndex(&RenameIndexDialectStruct{}, "idx_rename_index_dialect_structs_nickname") {
+		t.Errorf("index should be renamed with the form of %v", DB.Dialector.Name())
+	}
+}
+
+func TestGetTableDDL(t *testing.T) {
+	type TableDDLStruct struct {
+		ID   uint
+		Name string `gorm:"index"`
+	}
+
+	DB.Migrator().DropTable(&TableDDLStruct{})
+	if err := DB.AutoMigrate(&TableDDLStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	ddl, err := DB.Migrator().(migrator.TableDDLInterface).GetTableDDL(&TableDDLStruct{})
+	if errors.Is(err, gorm.ErrNotImplemented) {
+		t.Skipf("skip %v, whose migrator doesn't return table DDL", DB.Dialector.Name())
+	} else if err != nil {
+		t.Fatalf("failed to get table DDL, got error %v", err)
+	}
+
+	indexPattern := `CREATE INDEX .*idx_table_ddl_structs_name`
+	if DB.Dialector.Name() == "mysql" {
+		indexPattern = `KEY .idx_table_ddl_structs_name.`
+	}
+
+	if !regexp.MustCompile(`CREATE TABLE .*table_ddl_structs`).MatchString(ddl) || !regexp.MustCompile(indexPattern).MatchString(ddl) {
+		t.Errorf("DDL should contain the table and its indexes, got %v", ddl)
+	}
+
+	if _, err := DB.Migrator().(migrator.TableDDLInterface).GetTableDDL("table_ddl_missing_structs"); err == nil {
+		t.Errorf("should return error for missing table")
+	}
+}
+
+func TestMigrateIndexType(t *testing.T) {
+	type IndexTypeStruct struct {
+		ID   uint
+		Name string `gorm:"index:idx_index_type_structs_name"`
+	}
+
+	type IndexTypeHashStruct struct {
+		ID   uint
+		Name string `gorm:"index:idx_index_type_structs_name,type:hash"`
+	}
+
+	DB.Migrator().DropTable(&IndexTypeStruct{})
+	if err := DB.AutoMigrate(&IndexTypeStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	if DB.Dialector.Name() != "postgres" {
+		t.Skip("skip dialects other than postgres, which reflects and recreates index access methods")
+	}
+
+	recorder := &recordSQLLogger{Interface: DB.Logger}
+	tx := DB.Session(&gorm.Session{Logger: recorder}).Table("index_type_structs")
+	if err := tx.AutoMigrate(&IndexTypeStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	if sqls := append(recorder.statementsOf("CREATE INDEX"), recorder.statementsOf("DROP INDEX")...); len(sqls) != 0 {
+		t.Errorf("index of default btree shouldn't be recreated, got %v", sqls)
+	}
+
+	if err := tx.AutoMigrate(&IndexTypeHashStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	var definition string
+	if err := DB.Raw("SELECT indexdef FROM pg_indexes WHERE schemaname = CURRENT_SCHEMA() AND indexname = ?", "idx_index_type_structs_name").Row().Scan(&definition); err != nil {
+		t.Fatalf("failed to reflect index, got error %v", err)
+	}
+
+	if !strings.Contains(definition, "USING hash") {
+		t.Errorf("index should be recreated with the new type, got %v", definition)
+	}
+}
+
+type EnumCheckStruct struct {
+	ID   uint
+	Mood string `gorm:"enum:happy,sad;enumCheck"`
+}
+
+type EnumCheckStruct2 struct {
+	ID   uint
+	Mood string `gorm:"enum:happy,sad,ok;enumCheck"`
+}
+
+func (EnumCheckStruct2) TableName() string {
+	return "enum_check_structs"
+}
+
+type EnumCheckStruct3 struct {
+	ID   uint
+	Mood string `gorm:"enum:happy;enumCheck"`
+}
+
+func (EnumCheckStruct3) TableName() string {
+	return "enum_check_structs"
+}
+
+func TestMigrateEnumCheck(t *testing.T) {
+	DB.Migrator().DropTable(&EnumCheckStruct{})
+	if err := DB.AutoMigrate(&EnumCheckStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	if err := DB.Create(&EnumCheckStruct{Mood: "angry"}).Error; err == nil {
+		t.Errorf("should not be able to create record with invalid enum value")
+	}
+
+	if DB.Dialector.Name() == "postgres" && DB.Migrator().(migrator.TypeInterface).HasType("enum_check_structs_mood") {
+		t.Errorf("enum values should be checked without native enum type")
+	}
+
+	if DB.Dialector.Name() != "postgres" && DB.Dialector.Name() != "mysql" {
+		t.Skip("skip dialects without recreating check constraints")
+	}
+
+	m := newMigrator(DB, migrator.Config{RecreateChangedConstraintsWhenAutoMigrate: true})
+	if result, err := m.AutoMigrateWithResult(&EnumCheckStruct{}); err != nil || result.Count("recreate_constraint") != 0 {
+		t.Errorf("enum check with same values shouldn't be recreated, got %+v, error %v", result, err)
+	}
+
+	if result, err := m.AutoMigrateWithResult(&EnumCheckStruct2{}); err != nil || result.Count("recreate_constraint") != 1 {
+		t.Errorf("enum check should be recreated with added value, got %+v, error %v", result, err)
+	}
+
+	if err := DB.Create(&EnumCheckStruct2{Mood: "ok"}).Error; err != nil {
+		t.Errorf("should be able to create record with added enum value, got error %v", err)
+	}
+
+	if _, err := m.AutoMigrateWithResult(&EnumCheckStruct3{}); err == nil || !strings.Contains(err.Error(), "RemoveEnumValuesWhenAutoMigrate") {
+		t.Errorf("removing enum values should require RemoveEnumValuesWhenAutoMigrate, got %v", err)
+	}
+
+	DB.Where("1 = 1").Delete(&EnumCheckStruct{})
+	m.RemoveEnumValuesWhenAutoMigrate = true
+	if result, err := m.AutoMigrateWithResult(&EnumCheckStruct3{}); err != nil || result.Count("recreate_constraint") != 1 {
+		t.Errorf("enum check should be recreated with removed value, got %+v, error %v", result, err)
+	}
+
+	if err := DB.Create(&EnumCheckStruct3{Mood: "sad"}).Error; err == nil {
+		t.Errorf("should not be able to create record with removed enum value")
+	}
+}
+
+func TestMigrateEnumCheckReservedColumn(t *testing.T) {
+	type EnumCheckReservedStruct struct {
+		ID    uint
+		Order string `gorm:"column:order;enum:asc,desc;enumCheck"`
+	}
+
+	DB.Migrator().DropTable(&EnumCheckReservedStruct{})
+	if err := DB.AutoMigrate(&EnumCheckReservedStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate enum check on reserved column, got error %v", err)
+	}
+
+	if err := DB.Create(&EnumCheckReservedStruct{Order: "asc"}).Error; err != nil {
+		t.Errorf("should be able to create record with valid enum value, got error %v", err)
+	}
+
+	if err := DB.Create(&EnumCheckReservedStruct{Order: "random"}).Error; err == nil {
+		t.Errorf("should not be able to create record with invalid enum value")
+	}
+}
+
+func TestAutoMigrateSavePointPerModel(t *testing.T) {
+	type SavePointStruct struct {
+		ID   uint
+		Name string
+	}
+
+	type SavePointFailedStruct struct {
+		ID   uint
+		Name string
+	}
+
+	DB.Migrator().DropTable(&SavePointStruct{}, &SavePointFailedStruct{})
+
+	var (
+		result migrator.AutoMigrateResult
+		err    error
+	)
+
+	if txErr := DB.Transaction(func(tx *gorm.DB) error {
+		m := newMigrator(tx, migrator.Config{
+			SavePointPerModel: true, CreateIndexAfterCreateTable: true,
+			PostMigrate: map[string][]string{"save_point_failed_structs": {"INSERT INTO not_exists_table (event) VALUES ('post')"}},
+		})
+		result, err = m.AutoMigrateWithResult(&SavePointFailedStruct{}, &SavePointStruct{})
+		return nil
+	}); txErr != nil {
+		t.Fatalf("failed to commit transaction, got error %v", txErr)
+	}
+
+	if DB.Dialector.Name() == "mysql" {
+		return
+	}
+
+	if err == nil || !strings.Contains(err.Error(), "save_point_failed_structs") {
+		t.Errorf("failed tables should be reported, got error %v", err)
+	}
+
+	if len(result.Tables) != 2 || result.Tables[0].Error == nil || len(result.Tables[0].Operations) != 0 || result.Tables[1].Error != nil || result.Count("create_table") != 1 {
+		t.Errorf("failure should be recorded on its table, got %+v", result)
+	}
+
+	if DB.Migrator().HasTable(&SavePointFailedStruct{}) {
+		t.Errorf("failed table should be rolled back to its savepoint")
+	}
+
+	if !DB.Migrator().HasTable(&SavePointStruct{}) {
+		t.Errorf("other tables should be migrated")
+	}
+}
+
+func TestMigrateColumnMeta(t *testing.T) {
+	type ColumnMetaStruct struct {
+		ID    uint
+		Email string `gorm:"size:100;comment:contact email;meta:retention=30d,pii"`
+	}
+
+	m := newMigrator(DB, migrator.Config{})
+	for _, c := range []struct{ live, comment, expected string }{
+		{"", "contact email\ngorm:meta:pii=true", "contact email\ngorm:meta:pii=true"},
+		{"documented by human", "contact email\ngorm:meta:pii=true", "documented by human\ngorm:meta:pii=true"},
+		{"documented by human\ngorm:meta:pii=false\ngorm:schema_version=1b0c", "gorm:meta:pii=true", "documented by human\ngorm:meta:pii=true\ngorm:schema_version=1b0c"},
+		{"gorm:meta:pii=true", "gorm:schema_version=3f2a", "gorm:meta:pii=true\ngorm:schema_version=3f2a"},
+	} {
+		if merged := m.MergeColumnComment(c.live, c.comment); merged != c.expected {
+			t.Errorf("merged comment of %q and %q should be %q, but got %q", c.live, c.comment, c.expected, merged)
+		}
+	}
+
+	if DB.Dialector.Name() != "postgres" && DB.Dialector.Name() != "mysql" {
+		t.Skip("skip dialects without column comments")
+	}
+
+	DB.Migrator().DropTable(&ColumnMetaStruct{})
+	if err := DB.Exec("CREATE TABLE column_meta_structs (id integer PRIMARY KEY)").Error; err != nil {
+		t.Fatalf("failed to create table, got error %v", err)
+	}
+
+	if err := m.AddColumn(&ColumnMetaStruct{}, "Email"); err != nil {
+		t.Fatalf("failed to add column, got error %v", err)
+	}
+
+	if comment, err := m.ColumnCommentOf(&ColumnMetaStruct{}, "Email"); err != nil || comment != "contact email\ngorm:meta:pii=true,retention=30d" {
+		t.Errorf("metadata should be stored in the column comment, got %q, error %v", comment, err)
+	}
+
+	if err := m.SetColumnComment(&ColumnMetaStruct{}, "Email", "contact email\ngorm:meta:pii=true"); err != nil {
+		t.Fatalf("failed to set column comment, got error %v", err)
+	}
+
+	if comment, err := m.ColumnCommentOf(&ColumnMetaStruct{}, "Email"); err != nil || comment != "contact email\ngorm:meta:pii=true" {
+		t.Errorf("metadata shouldn't be appended to the merged comment again, got %q, error %v", comment, err)
+	}
+}
+
+func TestMigrateDeterministicOrder(t *testing.T) {
+	type DeterministicCompany struct {
+		ID   uint
+		Name string
+	}
+
+	type DeterministicStruct struct {
+		ID        uint
+		Name      string `gorm:"index:idx_deterministic_name;check:name_checker,name <> 'jinzhu'"`
+		Code      string `gorm:"index:idx_deterministic_code;check:code_checker,code <> ''"`
+		Age       int    `gorm:"index:idx_deterministic_age;check:age_checker,age > 0"`
+		CompanyID int
+		Company   DeterministicCompany
+		ManagerID int
+		Manager   DeterministicCompany
+		PartnerID int
+		Partner   DeterministicCompany
+	}
+
+	DB.Migrator().DropTable(&DeterministicStruct{}, &DeterministicCompany{})
+	if err := DB.AutoMigrate(&DeterministicCompany{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	var expected []string
+	for i := 0; i < 10; i++ {
+		DB.Migrator().DropTable(&DeterministicStruct{})
+
+		recorder := &recordSQLLogger{Interface: DB.Logger}
+		m := newMigrator(DB.Session(&gorm.Session{Logger: recorder}), migrator.Config{CreateIndexAfterCreateTable: true})
+		if err := m.CreateTable(&DeterministicStruct{}); err != nil {
+			t.Fatalf("failed to create table, got error %v", err)
+		}
+
+		if sqls := recorder.statementsOf("CREATE"); expected == nil {
+			expected = sqls
+		} else if !reflect.DeepEqual(expected, sqls) {
+			t.Fatalf("generated DDL should be deterministic, expects %v, got %v", expected, sqls)
+		}
+	}
+
+	if len(expected) != 4 || strings.Index(expected[0], "fk_deterministic_structs_company") > strings.Index(expected[0], "fk_deterministic_structs_manager") ||
+		strings.Index(expected[0], "age_checker") > strings.Index(expected[0], "code_checker") {
+		t.Errorf("constraints should be sorted by name, got %v", expected)
+	}
+}
+
+func TestRepairSchema(t *testing.T) {
+	type RepairSchemaStruct struct {
+		ID    uint
+		Name  string `gorm:"index:idx_repair_schema_name"`
+		Email string `gorm:"uniqueIndex:idx_repair_schema_email"`
+		Code  string `gorm:"check:code_checker,code <> ''"`
+	}
+
+	DB.Migrator().DropTable(&RepairSchemaStruct{})
+	if err := DB.AutoMigrate(&RepairSchemaStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	if err := DB.Migrator().DropIndex(&RepairSchemaStruct{}, "idx_repair_schema_name"); err != nil {
+		t.Fatalf("failed to drop index, got error %v", err)
+	}
+
+	// broken by a botched migration, exists by name but on the wrong column
+	if err := DB.Migrator().DropIndex(&RepairSchemaStruct{}, "idx_repair_schema_email"); err != nil {
+		t.Fatalf("failed to drop index, got error %v", err)
+	}
+
+	if err := DB.Exec("CREATE INDEX idx_repair_schema_email ON repair_schema_structs (name)").Error; err != nil {
+		t.Fatalf("failed to create broken index, got error %v", err)
+	}
+
+	m := newMigrator(DB, migrator.Config{})
+	if err := m.RepairSchema(&RepairSchemaStruct{}); !errors.Is(err, gorm.ErrNotImplemented) {
+		t.Errorf("creating unsupported constraints should fail without SkipUnsupported, got %v", err)
+	}
+
+	m.SkipUnsupported = true
+	if err := m.RepairSchema(&RepairSchemaStruct{}); err != nil {
+		t.Fatalf("failed to repair schema, got error %v", err)
+	}
+
+	indexes, err := DB.Migrator().(migrator.IndexesInterface).GetIndexes(&RepairSchemaStruct{})
+	if errors.Is(err, gorm.ErrNotImplemented) {
+		// broken indexes can't be found without reflecting indexes
+		if !DB.Migrator().HasIndex(&RepairSchemaStruct{}, "idx_repair_schema_name") {
+			t.Errorf("missing index should be created")
+		}
+		return
+	} else if err != nil {
+		t.Fatalf("failed to get indexes, got error %v", err)
+	}
+
+	repaired := map[string]migrator.IndexInfo{}
+	for _, idx := range indexes {
+		repaired[idx.Name] = idx
+	}
+
+	if idx, ok := repaired["idx_repair_schema_name"]; !ok || !reflect.DeepEqual(idx.Columns, []string{"name"}) {
+		t.Errorf("missing index should be created, got %+v", indexes)
+	}
+
+	if idx, ok := repaired["idx_repair_schema_email"]; !ok || !idx.Unique || !reflect.DeepEqual(idx.Columns, []string{"email"}) {
+		t.Errorf("broken index should be recreated, got %+v", indexes)
+	}
+
+	if err := m.RepairSchema(&RepairSchemaStruct{}); err != nil {
+		t.Errorf("failed to repair schema again, got error %v", err)
+	}
+
+	DB.Migrator().DropTable(&RepairSchemaStruct{})
+	if err := m.RepairSchema(&RepairSchemaStruct{}); err == nil || !strings.Contains(err.Error(), "table is missing") {
+		t.Errorf("repairing missing table should fail, got %v", err)
+	}
+}
+
+func TestMigrateCollationProvider(t *testing.T) {
+	type CollationProviderStruct struct {
+		ID    uint
+		Name  string `gorm:"type:text COLLATE de-DE;collateProvider:icu;index:,collate:de-DE,collateProvider:icu"`
+		Code  string `gorm:"type:text COLLATE \"C\";collateProvider:libc;index:,collate:POSIX,collateProvider:libc"`
+		Title string `gorm:"index:,collate:und-x-icu,collateProvider:icu"`
+	}
+
+	m := newMigrator(DB, migrator.Config{CreateIndexAfterCreateTable: true})
+	if DB.Dialector.Name() != "postgres" {
+		if collation := m.CollationOf("utf8mb4_unicode_ci", "icu"); collation != "utf8mb4_unicode_ci" {
+			t.Errorf("collation provider should be ignored by other dialects, got %v", collation)
+		}
+		return
+	}
+
+	DB.Migrator().DropTable(&CollationProviderStruct{})
+	if err := m.CreateTable(&CollationProviderStruct{}); err != nil {
+		t.Fatalf("failed to create table, got error %v", err)
+	}
+
+	for column, expected := range map[string]string{"name": "de-DE-x-icu", "code": "C"} {
+		var collation string
+		if err := DB.Raw("SELECT collation_name FROM information_schema.columns WHERE table_schema = CURRENT_SCHEMA() AND table_name = ? AND column_name = ?", "collation_provider_structs", column).Row().Scan(&collation); err != nil {
+			t.Fatalf("failed to reflect column %v, got error %v", column, err)
+		}
+
+		if collation != expected {
+			t.Errorf("column %v should reference collation %v of the provider, got %v", column, expected, collation)
+		}
+	}
+
+	for name, expected := range map[string]string{
+		"idx_collation_provider_structs_name":  `COLLATE "de-DE-x-icu"`,
+		"idx_collation_provider_structs_code":  `COLLATE "POSIX"`,
+		"idx_collation_provider_structs_title": `COLLATE "und-x-icu"`,
+	} {
+		var definition string
+		if err := DB.Raw("SELECT indexdef FROM pg_indexes WHERE schemaname = CURRENT_SCHEMA() AND indexname = ?", name).Row().Scan(&definition); err != nil {
+			t.Fatalf("failed to reflect index %v, got error %v", name, err)
+		}
+
+		if !strings.Contains(definition, expected) {
+			t.Errorf("index %v should be created with %v, got %v", name, expected, definition)
+		}
+	}
+}
+
+func TestCreateTableColumnOrder(t *testing.T) {
+	type ColumnOrderStruct struct {
+		Name   string
+		Code   string `gorm:"primaryKey"`
+		Age    int
+		Region string `gorm:"primaryKey"`
+	}
+
+	for order, expected := range map[string][]string{
+		migrator.ColumnOrderAsDefined:    {"name", "code", "age", "region"},
+		migrator.ColumnOrderPKFirst:      {"code", "region", "name", "age"},
+		migrator.ColumnOrderAlphabetical: {"age", "code", "name", "region"},
+	} {
+		m := newMigrator(DB, migrator.Config{ColumnOrder: order})
+
+		DB.Migrator().DropTable(&ColumnOrderStruct{})
+		if err := m.CreateTable(&ColumnOrderStruct{}); err != nil {
+			t.Fatalf("failed to create table with column order %q, got error %v", order, err)
+		}
+
+		columnTypes, err := DB.Migrator().ColumnTypes(&ColumnOrderStruct{})
+		if err != nil {
+			t.Fatalf("failed to get column types, got error %v", err)
+		}
+
+		var columns []string
+		for _, columnType := range columnTypes {
+			columns = append(columns, columnType.Name())
+		}
+
+		if !reflect.DeepEqual(columns, expected) {
+			t.Errorf("columns of %q order should be %v, got %v", order, expected, columns)
+		}
+	}
+
+	m := newMigrator(DB, migrator.Config{ColumnOrder: "random"})
+	if _, _, err := m.BuildCreateTableSQL(&ColumnOrderStruct{}); err == nil || !strings.Contains(err.Error(), "unsupported column order") {
+		t.Errorf("unknown column order should be rejected, got %v", err)
+	}
+}
+
+func TestValidatePendingConstraints(t *testing.T) {
+	if DB.Dialector.Name() != "postgres" {
+		t.Skip("skip dialects other than postgres, which adds constraints with NOT VALID")
+	}
+
+	type NotValidCompany struct {
+		ID   uint
+		Name string
+	}
+
+	type NotValidUser struct {
+		ID        uint
+		CompanyID uint
+		Company   NotValidCompany
+	}
+
+	DB.Migrator().DropTable(&NotValidUser{}, &NotValidCompany{}, migrator.DefaultPendingConstraintsTable)
+	if err := DB.AutoMigrate(&NotValidCompany{}, &NotValidUser{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	if err := DB.Migrator().DropConstraint(&NotValidUser{}, "fk_not_valid_users_company"); err != nil {
+		t.Fatalf("failed to drop constraint, got error %v", err)
+	}
+
+	recorder := &recordSQLLogger{Interface: DB.Logger}
+	tx := DB.Session(&gorm.Session{Context: context.Background(), Logger: recorder})
+	m := newMigrator(tx, migrator.Config{AddForeignKeysNotValid: true})
+	if err := m.CreateConstraint(&NotValidUser{}, "fk_not_valid_users_company"); err != nil {
+		t.Fatalf("failed to create constraint, got error %v", err)
+	}
+
+	if len(recorder.sqls) == 0 || !strings.HasPrefix(recorder.sqls[0], "ALTER TABLE") || !strings.HasSuffix(recorder.sqls[0], "NOT VALID") {
+		t.Errorf("foreign key should be added with NOT VALID, got %v", recorder.sqls)
+	}
+
+	convalidated := func() (validated bool) {
+		if err := DB.Raw("SELECT convalidated FROM pg_constraint WHERE conname = ?", "fk_not_valid_users_company").Row().Scan(&validated); err != nil {
+			t.Fatalf("failed to reflect constraint, got error %v", err)
+		}
+		return
+	}
+
+	if convalidated() {
+		t.Errorf("foreign key added with NOT VALID shouldn't be validated")
+	}
+
+	var count int64
+	if DB.Table(migrator.DefaultPendingConstraintsTable).Count(&count); count != 1 {
+		t.Errorf("constraint added with NOT VALID should be recorded as pending, got %v", count)
+	}
+
+	if err := m.ValidatePendingConstraints(); err != nil {
+		t.Fatalf("failed to validate pending constraints, got error %v", err)
+	}
+
+	if !convalidated() {
+		t.Errorf("pending constraint should be validated")
+	}
+
+	if DB.Table(migrator.DefaultPendingConstraintsTable).Count(&count); count != 0 {
+		t.Errorf("validated constraint should be removed from pending, got %v", count)
+	}
+
+	// constraints not found are kept in pending
+	if err := m.DropConstraint(&NotValidUser{}, "fk_not_valid_users_company"); err != nil {
+		t.Fatalf("failed to drop constraint, got error %v", err)
+	}
+
+	if err := m.CreateConstraint(&NotValidUser{}, "fk_not_valid_users_company"); err != nil {
+		t.Fatalf("failed to create constraint, got error %v", err)
+	}
+
+	if err := DB.Exec("ALTER TABLE not_valid_users RENAME CONSTRAINT fk_not_valid_users_company TO fk_not_valid_users_company_renamed").Error; err != nil {
+		t.Fatalf("failed to rename constraint, got error %v", err)
+	}
+
+	if err := m.ValidatePendingConstraints(); err != nil {
+		t.Fatalf("failed to validate pending constraints, got error %v", err)
+	}
+
+	if DB.Table(migrator.DefaultPendingConstraintsTable).Count(&count); count != 1 {
+		t.Errorf("pending constraint not found should be kept, got %v", count)
+	}
+
+	DB.Migrator().DropTable(&NotValidUser{}, &NotValidCompany{}, migrator.DefaultPendingConstraintsTable)
+}
+
+func TestSetTableLogged(t *testing.T) {
+	type UnloggedStruct struct {
+		ID   uint
+		Name string
+	}
+
+	m := newMigrator(DB, migrator.Config{})
+	if DB.Dialector.Name() != "postgres" {
+		if err := m.SetTableLogged(&UnloggedStruct{}, true); !errors.Is(err, gorm.ErrNotImplemented) {
+			t.Errorf("setting table logged should be not implemented by %v, got %v", DB.Dialector.Name(), err)
+		}
+
+		m.DB = DB.Set("gorm:table_unlogged", true)
+		if _, _, err := m.BuildCreateTableSQL(&UnloggedStruct{}); !errors.Is(err, gorm.ErrNotImplemented) {
+			t.Errorf("unlogged table should be not implemented by %v, got %v", DB.Dialector.Name(), err)
+		}
+		return
+	}
+
+	persistence := func() (relpersistence string) {
+		if err := DB.Raw("SELECT relpersistence FROM pg_class WHERE oid = to_regclass(?)", "unlogged_structs").Row().Scan(&relpersistence); err != nil {
+			t.Fatalf("failed to reflect table, got error %v", err)
+		}
+		return
+	}
+
+	DB.Migrator().DropTable(&UnloggedStruct{})
+	m.DB = DB.Set("gorm:table_unlogged", true)
+	if err := m.CreateTable(&UnloggedStruct{}); err != nil {
+		t.Fatalf("failed to create table, got error %v", err)
+	}
+
+	if p := persistence(); p != "u" {
+		t.Errorf("table should be created unlogged, got %v", p)
+	}
+
+	m.DB = DB
+	if err := m.SetTableLogged(&UnloggedStruct{}, true); err != nil {
+		t.Fatalf("failed to set table logged, got error %v", err)
+	}
+
+	if p := persistence(); p != "p" {
+		t.Errorf("table should be switched to logged, got %v", p)
+	}
+
+	if err := m.SetTableLogged(&UnloggedStruct{}, false); err != nil {
+		t.Fatalf("failed to set table unlogged, got error %v", err)
+	}
+
+	if p := persistence(); p != "u" {
+		t.Errorf("table should be switched to unlogged, got %v", p)
+	}
+}
+
+func TestSetAutovacuum(t *testing.T) {
+	type BulkLoadStruct struct {
+		ID   uint
+		Name string
+	}
+
+	m := newMigrator(DB, migrator.Config{})
+	setter, ok := DB.Migrator().(migrator.StorageParametersInterface)
+	if !ok {
+		if err := m.SetAutovacuum(&BulkLoadStruct{}, false); !errors.Is(err, gorm.ErrNotImplemented) {
+			t.Errorf("autovacuum should be not implemented by %v, got %v", DB.Dialector.Name(), err)
+		}
+
+		if err := m.WithAutovacuumDisabled(&BulkLoadStruct{}, func() error {
+			t.Errorf("bulk load should not run when autovacuum can't be disabled")
+			return nil
+		}); !errors.Is(err, gorm.ErrNotImplemented) {
+			t.Errorf("autovacuum should be not implemented by %v, got %v", DB.Dialector.Name(), err)
+		}
+		return
+	}
+
+	DB.Migrator().DropTable(&BulkLoadStruct{})
+	if err := DB.AutoMigrate(&BulkLoadStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	reloptions := func() (options string) {
+		if err := DB.Raw("SELECT COALESCE(array_to_string(reloptions, ','), '') FROM pg_class WHERE oid = to_regclass(?)", "bulk_load_structs").Row().Scan(&options); err != nil {
+			t.Fatalf("failed to reflect table, got error %v", err)
+		}
+		return
+	}
+
+	if err := m.SetAutovacuum(&BulkLoadStruct{}, true); err != nil {
+		t.Fatalf("failed to enable autovacuum, got error %v", err)
+	}
+
+	if err := setter.SetTableStorageParameters(&BulkLoadStruct{}, map[string]string{"fillfactor": "70", "autovacuum_vacuum_scale_factor": "0.05"}); err != nil {
+		t.Fatalf("failed to set storage parameters, got error %v", err)
+	}
+
+	if options := reloptions(); options != "autovacuum_enabled=true,autovacuum_vacuum_scale_factor=0.05,fillfactor=70" {
+		t.Errorf("storage parameters should be set, got %v", options)
+	}
+
+	if err := setter.SetTableStorageParameters(&BulkLoadStruct{}, map[string]string{"fillfactor": "70); DROP TABLE users; --"}); err == nil {
+		t.Errorf("invalid storage parameter should be rejected")
+	}
+
+	loadErr := errors.New("load failed")
+	if err := m.WithAutovacuumDisabled(&BulkLoadStruct{}, func() error {
+		if options := reloptions(); !strings.Contains(options, "autovacuum_enabled=false") {
+			t.Errorf("autovacuum should be disabled while loading, got %v", options)
+		}
+		return loadErr
+	}); err != loadErr {
+		t.Errorf("error of bulk load should be returned, got %v", err)
+	}
+
+	if options := reloptions(); strings.Contains(options, "autovacuum_enabled") {
+		t.Errorf("autovacuum should be reset after loading, got %v", options)
+	}
+}
+
+type MemoryTableStruct struct {
+	ID   uint
+	Name string
+}
+
+func (MemoryTableStruct) TableOptions() string {
+	return " ENGINE=MEMORY"
+}
+
+type PlainTableStruct struct {
+	ID   uint
+	Name string
+}
+
+func (PlainTableStruct) TableOptions() string {
+	return ""
+}
+
+func TestCreateTableWithModelTableOptions(t *testing.T) {
+	type DefaultTableStruct struct {
+		ID   uint
+		Name string
+	}
+
+	m := newMigrator(DB.Set("gorm:table_options", " ENGINE=MyISAM"), migrator.Config{})
+	for value, expected := range map[interface{}]string{
+		&DefaultTableStruct{}: ") ENGINE=MyISAM",
+		&MemoryTableStruct{}:  ") ENGINE=MEMORY",
+		&PlainTableStruct{}:   ")",
+	} {
+		sql, _, err := m.BuildCreateTableSQL(value)
+		if err != nil {
+			t.Fatalf("failed to build create table sql, got error %v", err)
+		}
+
+		if !strings.HasSuffix(sql, expected) {
+			t.Errorf("create table sql of %T should end with %q, got %v", value, expected, sql)
+		}
+	}
+
+	if DB.Dialector.Name() != "mysql" {
+		t.Skip("skip dialects other than mysql, which creates tables with engines")
+	}
+
+	for value, expected := range map[interface{}]string{
+		&DefaultTableStruct{}: "MyISAM",
+		&MemoryTableStruct{}:  "MEMORY",
+		&PlainTableStruct{}:   "InnoDB",
+	} {
+		DB.Migrator().DropTable(value)
+		if err := m.CreateTable(value); err != nil {
+			t.Fatalf("failed to create table, got error %v", err)
+		}
+
+		var engine string
+		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			return DB.Raw("SELECT engine FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?", stmt.Table).Row().Scan(&engine)
+		}); err != nil {
+			t.Fatalf("failed to reflect table, got error %v", err)
+		}
+
+		if engine != expected {
+			t.Errorf("table of %T should be created with engine %v, got %v", value, expected, engine)
+		}
+	}
+}
+
+func TestSyncColumnSequence(t *testing.T) {
+	type SequenceSyncStruct struct {
+		ID   uint
+		Name string
+	}
+
+	if DB.Dialector.Name() != "postgres" {
+		if _, err := DB.Migrator().(migrator.SequenceInterface).GetSequenceValue("sequence_sync_structs_id_seq"); err != gorm.ErrNotImplemented {
+			t.Errorf("should returns ErrNotImplemented for sequence value, but got %v", err)
+		}
+
+		if err := DB.Migrator().(migrator.SequenceInterface).SyncColumnSequence(&SequenceSyncStruct{}, "ID"); err != gorm.ErrNotImplemented {
+			t.Errorf("should returns ErrNotImplemented for syncing sequence, but got %v", err)
+		}
+		return
+	}
+
+	DB.Migrator().DropTable(&SequenceSyncStruct{})
+	if err := DB.AutoMigrate(&SequenceSyncStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	m := DB.Migrator().(migrator.SequenceInterface)
+	if sequence, err := m.ColumnSequenceOf(&SequenceSyncStruct{}, "ID"); err != nil || sequence != "public.sequence_sync_structs_id_seq" {
+		t.Errorf("sequence owned by the column should be reflected, got %v, %v", sequence, err)
+	}
+
+	// rows restored with their keys don't advance the sequence
+	if err := DB.Create(&SequenceSyncStruct{ID: 41, Name: "restored"}).Error; err != nil {
+		t.Fatalf("failed to create record, got error %v", err)
+	}
+
+	if err := m.SyncColumnSequence(&SequenceSyncStruct{}, "ID"); err != nil {
+		t.Fatalf("failed to sync column sequence, got error %v", err)
+	}
+
+	if value, err := m.GetSequenceValue("sequence_sync_structs_id_seq"); err != nil || value != 42 {
+		t.Errorf("sequence should be restarted above max value of the column, got %v, %v", value, err)
+	}
+
+	created := SequenceSyncStruct{Name: "created"}
+	if err := DB.Create(&created).Error; err != nil || created.ID != 42 {
+		t.Errorf("record should be created with the next value of the sequence, got %v, error %v", created.ID, err)
+	}
+
+	if err := m.SyncColumnSequence(&SequenceSyncStruct{}, "Name"); err == nil || !strings.Contains(err.Error(), "owns no sequence") {
+		t.Errorf("syncing column without sequence should fail, got %v", err)
+	}
+}
+
+func TestAlterColumnWithDependentViews(t *testing.T) {
+	type ViewDependencyStruct struct {
+		ID   uint
+		Name string `gorm:"size:200"`
+	}
+
+	if DB.Dialector.Name() != "postgres" {
+		t.Skip("skip dialects other than postgres, which rejects altering columns used by views")
+	}
+
+	DB.Exec("DROP VIEW IF EXISTS top_views")
+	DB.Exec("DROP MATERIALIZED VIEW IF EXISTS mid_views")
+	DB.Exec("DROP VIEW IF EXISTS base_views")
+	DB.Migrator().DropTable(&ViewDependencyStruct{})
+	for _, sql := range []string{
+		"CREATE TABLE view_dependency_structs (id bigserial PRIMARY KEY, name text)",
+		"CREATE VIEW base_views AS SELECT id, name FROM view_dependency_structs",
+		"CREATE MATERIALIZED VIEW mid_views AS SELECT id FROM base_views",
+		"CREATE VIEW top_views AS SELECT m.id FROM mid_views m JOIN view_dependency_structs s ON s.id = m.id",
+	} {
+		if err := DB.Exec(sql).Error; err != nil {
+			t.Fatalf("failed to create %v, got error %v", sql, err)
+		}
+	}
+
+	m := newMigrator(DB, migrator.Config{})
+	if err := m.AlterColumn(&ViewDependencyStruct{}, "Name"); err != nil {
+		t.Fatalf("failed to alter column, got error %v", err)
+	}
+
+	var dataType string
+	if err := DB.Raw("SELECT data_type FROM information_schema.columns WHERE table_schema = CURRENT_SCHEMA() AND table_name = ? AND column_name = ?", "view_dependency_structs", "name").Row().Scan(&dataType); err != nil || dataType != "character varying" {
+		t.Errorf("column should be altered, got %v, error %v", dataType, err)
+	}
+
+	for name, expected := range map[string]string{"base_views": "v", "mid_views": "m", "top_views": "v"} {
+		var kind string
+		if err := DB.Raw("SELECT relkind FROM pg_class WHERE oid = to_regclass(?)", name).Row().Scan(&kind); err != nil || kind != expected {
+			t.Errorf("dependent view %v should be recreated with kind %v, got %v, error %v", name, expected, kind, err)
+		}
+	}
+
+	DB.Exec("DROP VIEW IF EXISTS top_views")
+	DB.Exec("DROP MATERIALIZED VIEW IF EXISTS mid_views")
+	DB.Exec("DROP VIEW IF EXISTS base_views")
+	DB.Migrator().DropTable(&ViewDependencyStruct{})
+}
+
+func TestHasDefault(t *testing.T) {
+	type HasDefaultStruct struct {
+		ID     uint
+		Name   string
+		Status string `gorm:"default:active"`
+	}
+
+	DB.Migrator().DropTable(&HasDefaultStruct{})
+	if err := DB.AutoMigrate(&HasDefaultStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	if has, err := DB.Migrator().(migrator.ColumnInterface).HasDefault(&HasDefaultStruct{}, "Status"); errors.Is(err, gorm.ErrNotImplemented) {
+		t.Skipf("skip %v, whose migrator doesn't reflect column defaults", DB.Dialector.Name())
+	} else if err != nil || !has {
+		t.Errorf("column with default should have default, got %v, %v", has, err)
+	}
+
+	if has, err := DB.Migrator().(migrator.ColumnInterface).HasDefault(&HasDefaultStruct{}, "name"); err != nil || has {
+		t.Errorf("column without default shouldn't have default, got %v, %v", has, err)
+	}
+
+	if _, err := DB.Migrator().(migrator.ColumnInterface).HasDefault(&HasDefaultStruct{}, "missing"); err == nil || !strings.Contains(err.Error(), "column not found") {
+		t.Errorf("checking default of missing column should fail, got %v", err)
+	}
+}
+
+func TestMigrateWithOnlineSchemaChangeHook(t *testing.T) {
+	type OnlineSchemaChangeStruct struct {
+		ID   uint
+		Name string `gorm:"index"`
+		Age  int
+	}
+
+	if DB.Dialector.Name() != "mysql" {
+		t.Skip("skip dialects other than mysql, which delegates alter table statements to online schema change tools")
+	}
+
+	DB.Migrator().DropTable(&OnlineSchemaChangeStruct{})
+	if err := DB.Exec("CREATE TABLE online_schema_change_structs (id bigint unsigned AUTO_INCREMENT PRIMARY KEY, name longtext)").Error; err != nil {
+		t.Fatalf("failed to create table, got error %v", err)
+	}
+
+	var changes []migrator.OnlineSchemaChange
+	m := newMigrator(DB, migrator.Config{OnlineSchemaChangeHook: func(change migrator.OnlineSchemaChange) error {
+		changes = append(changes, change)
+		return nil
+	}})
+
+	if err := m.AddColumn(&OnlineSchemaChangeStruct{}, "Age"); err != nil {
+		t.Fatalf("failed to add column, got error %v", err)
+	}
+
+	if err := m.WithSchema("tenant_x").AddColumn(&OnlineSchemaChangeStruct{}, "Age"); err != nil {
+		t.Fatalf("failed to add column, got error %v", err)
+	}
+
+	if err := m.CreateIndex(&OnlineSchemaChangeStruct{}, "Name"); err != nil {
+		t.Fatalf("failed to create index, got error %v", err)
+	}
+
+	if len(changes) != 2 || changes[0].Table != "online_schema_change_structs" || changes[0].Alter != "ADD `age` integer" ||
+		changes[1].Database != "tenant_x" || changes[1].Table != "online_schema_change_structs" || changes[1].SQL != "ALTER TABLE `tenant_x`.`online_schema_change_structs` ADD `age` integer" {
+		t.Errorf("alter table statements should be delegated to the hook, got %+v", changes)
+	}
+
+	if DB.Migrator().HasColumn(&OnlineSchemaChangeStruct{}, "Age") {
+		t.Errorf("column delegated to the hook shouldn't be added")
+	}
+
+	if !DB.Migrator().HasIndex(&OnlineSchemaChangeStruct{}, "Name") {
+		t.Errorf("other statements should be executed directly")
+	}
+
+	m.OnlineSchemaChangeHook = func(change migrator.OnlineSchemaChange) error {
+		return errors.New("gh-ost failed")
+	}
+
+	if err := m.AddColumn(&OnlineSchemaChangeStruct{}, "Age"); err == nil || err.Error() != "gh-ost failed" {
+		t.Errorf("error of the hook should be returned, got %v", err)
+	}
+}
+
+func TestAutoMigrateWithOnlineSchemaChangeHook(t *testing.T) {
+	if DB.Dialector.Name() != "mysql" {
+		t.Skip("skip dialects other than mysql, which delegates alter table statements to online schema change tools")
+	}
+
+	type OnlineSchemaChangeAutoStruct struct {
+		ID   uint
+		Name string `gorm:"size:50"`
+	}
+
+	type OnlineSchemaChangeAutoStruct2 struct {
+		ID   uint
+		Name string `gorm:"size:100"`
+		Age  int
+	}
+
+	DB.Migrator().DropTable(&OnlineSchemaChangeAutoStruct{})
+	if err := DB.AutoMigrate(&OnlineSchemaChangeAutoStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	var changes []migrator.OnlineSchemaChange
+	m := newMigrator(DB.Table("online_schema_change_auto_structs"), migrator.Config{OnlineSchemaChangeHook: func(change migrator.OnlineSchemaChange) error {
+		changes = append(changes, change)
+		return nil
+	}})
+
+	if err := m.AutoMigrate(&OnlineSchemaChangeAutoStruct2{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	var added, altered bool
+	for _, change := range changes {
+		added = added || strings.HasPrefix(change.Alter, "ADD `age`")
+		altered = altered || strings.HasPrefix(change.Alter, "MODIFY COLUMN `name` varchar(100)")
+	}
+
+	if !added || !altered {
+		t.Errorf("alter table statements of AutoMigrate should be delegated to the hook, got %+v", changes)
+	}
+
+	if DB.Table("online_schema_change_auto_structs").Migrator().HasColumn(&OnlineSchemaChangeAutoStruct2{}, "Age") {
+		t.Errorf("column delegated to the hook shouldn't be added by AutoMigrate")
+	}
+
+	DB.Migrator().DropTable(&OnlineSchemaChangeAutoStruct{})
+}
+
+func TestAutoMigrateWithMaintenanceWorkers(t *testing.T) {
+	if DB.Dialector.Name() != "postgres" {
+		t.Skip("skip dialects other than postgres, which builds indexes with parallel maintenance workers")
+	}
+
+	type MaintenanceWorkersAutoStruct struct {
+		ID   uint
+		Name string
+	}
+
+	type MaintenanceWorkersAutoStruct2 struct {
+		ID   uint
+		Name string `gorm:"index:idx_maintenance_workers_auto_structs_name"`
+	}
+
+	DB.Migrator().DropTable(&MaintenanceWorkersAutoStruct{})
+	if err := DB.AutoMigrate(&MaintenanceWorkersAutoStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	recorder := &recordSQLLogger{Interface: DB.Logger}
+	tx := DB.Session(&gorm.Session{Context: context.Background(), Logger: recorder})
+	m := newMigrator(tx.Table("maintenance_workers_auto_structs"), migrator.Config{IndexMaintenanceWorkers: 4})
+	if err := m.AutoMigrate(&MaintenanceWorkersAutoStruct2{}); err != nil {
+		t.Fatalf("failed to auto migrate with maintenance workers, got error %v", err)
+	}
+
+	var parallel bool
+	for idx, sql := range recorder.sqls {
+		if strings.HasPrefix(sql, "CREATE INDEX") && idx > 0 && idx+1 < len(recorder.sqls) {
+			parallel = recorder.sqls[idx-1] == "SET max_parallel_maintenance_workers = 4" && recorder.sqls[idx+1] == "RESET max_parallel_maintenance_workers"
+		}
+	}
+
+	if !parallel {
+		t.Errorf("index should be created by AutoMigrate with parallel maintenance workers, got %v", recorder.sqls)
+	}
+
+	if !DB.Table("maintenance_workers_auto_structs").Migrator().HasIndex(&MaintenanceWorkersAutoStruct2{}, "idx_maintenance_workers_auto_structs_name") {
+		t.Errorf("index should be created by AutoMigrate")
+	}
+
+	DB.Migrator().DropTable(&MaintenanceWorkersAutoStruct{})
+}
+
+func TestCreateIndexWithMaintenanceWorkers(t *testing.T) {
+	type MaintenanceWorkersStruct struct {
+		ID   uint
+		Name string `gorm:"index"`
+	}
+
+	DB.Migrator().DropTable(&MaintenanceWorkersStruct{})
+	if err := DB.Exec("CREATE TABLE maintenance_workers_structs (id integer PRIMARY KEY, name varchar(100))").Error; err != nil {
+		t.Fatalf("failed to create table, got error %v", err)
+	}
+
+	recorder := &recordSQLLogger{Interface: DB.Logger}
+	m := newMigrator(DB.Session(&gorm.Session{Logger: recorder}), migrator.Config{IndexMaintenanceWorkers: 4})
+	if DB.Dialector.Name() == "postgres" {
+		m.MigrateLockTimeout = time.Second
+	}
+
+	if err := m.CreateIndex(&MaintenanceWorkersStruct{}, "Name"); err != nil {
+		t.Fatalf("failed to create index, got error %v", err)
+	}
+
+	if !DB.Migrator().HasIndex(&MaintenanceWorkersStruct{}, "Name") {
+		t.Errorf("index should be created")
+	}
+
+	sqls := append(recorder.statementsOf("SET"), recorder.statementsOf("RESET")...)
+	if DB.Dialector.Name() != "postgres" {
+		if len(sqls) != 0 {
+			t.Errorf("parallel maintenance workers should be ignored by %v, got %v", DB.Dialector.Name(), sqls)
+		}
+		return
+	}
+
+	if len(recorder.sqls) != 3 || recorder.sqls[0] != "SET LOCAL max_parallel_maintenance_workers = 4" || recorder.sqls[1] != "SET LOCAL lock_timeout = '1000ms'" ||
+		!strings.HasPrefix(recorder.sqls[2], "CREATE INDEX") {
+		t.Errorf("index should be created with parallel maintenance workers, got %v", recorder.sqls)
+	}
+}
+
+func TestAutoMigrateDropObsoleteForeignKeys(t *testing.T) {
+	type ObsoleteFKManager struct {
+		ID   uint
+		Name string
+	}
+
+	// Company relationship is removed, CompanyID is a plain column now
+	type ObsoleteFKUser struct {
+		ID        uint
+		CompanyID uint
+		ManagerID uint
+		Manager   ObsoleteFKManager
+	}
+
+	if DB.Dialector.Name() != "postgres" && DB.Dialector.Name() != "mysql" {
+		t.Skip("skip dialects without dropping foreign keys")
+	}
+
+	DB.Migrator().DropTable(&ObsoleteFKUser{}, &ObsoleteFKManager{})
+	if err := DB.AutoMigrate(&ObsoleteFKUser{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	// foreign keys of unknown columns, e.g: managed by other services, are never dropped
+	legacyType := "bigint"
+	if DB.Dialector.Name() == "mysql" {
+		legacyType = "bigint unsigned"
+	}
+
+	for _, sql := range []string{
+		"ALTER TABLE obsolete_fk_users ADD CONSTRAINT fk_obsolete_fk_users_company FOREIGN KEY (company_id) REFERENCES obsolete_fk_managers(id)",
+		"ALTER TABLE obsolete_fk_users ADD legacy_account_id " + legacyType,
+		"ALTER TABLE obsolete_fk_users ADD CONSTRAINT fk_legacy_accounts FOREIGN KEY (legacy_account_id) REFERENCES obsolete_fk_managers(id)",
+	} {
+		if err := DB.Exec(sql).Error; err != nil {
+			t.Fatalf("failed to exec %v, got error %v", sql, err)
+		}
+	}
+
+	m := newMigrator(DB, migrator.Config{})
+	result, err := m.AutoMigrateWithResult(&ObsoleteFKUser{})
+	if err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	for _, table := range result.Tables {
+		if len(table.Operations) != 0 {
+			t.Errorf("obsolete foreign keys should be kept without the option, got %+v", result.Tables)
+		}
+	}
+
+	m.DropObsoleteForeignKeysWhenAutoMigrate = true
+	if result, err = m.AutoMigrateWithResult(&ObsoleteFKUser{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	var operations []migrator.MigrateOperation
+	for _, table := range result.Tables {
+		if table.Table == "obsolete_fk_users" {
+			operations = table.Operations
+		}
+	}
+
+	if len(operations) != 1 || operations[0].Type != "drop_constraint" || operations[0].Name != "fk_obsolete_fk_users_company" {
+		t.Errorf("only the foreign key of the removed relationship should be dropped, got %+v", operations)
+	}
+
+	if DB.Migrator().HasConstraint(&ObsoleteFKUser{}, "fk_obsolete_fk_users_company") {
+		t.Errorf("foreign key of the removed relationship should be dropped")
+	}
+
+	for _, name := range []string{"fk_obsolete_fk_users_manager", "fk_legacy_accounts"} {
+		if !DB.Migrator().HasConstraint(&ObsoleteFKUser{}, name) {
+			t.Errorf("foreign key %v should be kept", name)
+		}
+	}
+
+	if !DB.Migrator().HasColumn(&ObsoleteFKUser{}, "CompanyID") {
+		t.Errorf("column of the removed relationship should be kept")
+	}
+
+	DB.Migrator().DropTable(&ObsoleteFKUser{}, &ObsoleteFKManager{})
+}
+
+// failDropConnPool fails DROP statements, with dropped, they fail after dropping, as if by others concurrently
+type failDropConnPool struct {
+	gorm.ConnPool
+	dropped bool
+}
+
+func (pool failDropConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if strings.Contains(query, "DROP") {
+		if pool.dropped {
+			pool.ConnPool.ExecContext(ctx, query, args...)
+		}
+		return nil, errors.New("drop failed")
+	}
+	return pool.ConnPool.ExecContext(ctx, query, args...)
+}
+
+type RecreateIndexStruct struct {
+	ID   uint
+	Name string `gorm:"index:idx_recreate_index_structs_name,comment:names"`
+}
+
+type RecreateIndexStruct2 struct {
+	ID   uint
+	Name string `gorm:"index:idx_recreate_index_structs_name,unique,comment:names"`
+}
+
+func (RecreateIndexStruct2) TableName() string {
+	return "recreate_index_structs"
+}
+
+func TestAutoMigrateRecreateIndexDroppedConcurrently(t *testing.T) {
+	DB.Migrator().DropTable(&RecreateIndexStruct{})
+	if err := DB.AutoMigrate(&RecreateIndexStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	if _, err := DB.Migrator().(migrator.IndexesInterface).GetIndexes(&RecreateIndexStruct{}); errors.Is(err, gorm.ErrNotImplemented) {
+		t.Skipf("skip %v, whose migrator doesn't reflect changed indexes", DB.Dialector.Name())
+	}
+
+	tx := DB.Session(&gorm.Session{Context: context.Background()})
+	tx.Statement.ConnPool = failDropConnPool{ConnPool: tx.Statement.ConnPool, dropped: true}
+
+	result, err := tx.Migrator().(migrator.AutoMigrateInterface).AutoMigrateWithResult(&RecreateIndexStruct2{})
+	if err != nil {
+		t.Fatalf("index dropped by others should be recreated, got error %v", err)
+	}
+
+	if ops := result.Tables[0].Operations; len(ops) != 1 || ops[0].Type != "recreate_index" || ops[0].Name != "idx_recreate_index_structs_name" {
+		t.Errorf("index should be recreated, got %+v", result)
+	}
+
+	indexes, err := DB.Migrator().(migrator.IndexesInterface).GetIndexes(&RecreateIndexStruct2{})
+	if err != nil {
+		t.Fatalf("failed to get indexes, got error %v", err)
+	}
+
+	for _, idx := range indexes {
+		if idx.Name == "idx_recreate_index_structs_name" && !idx.Unique {
+			t.Errorf("index should be recreated as unique, got %+v", idx)
+		}
+	}
+
+	DB.Migrator().DropTable(&RecreateIndexStruct{})
+}
+
+func TestAutoMigrateDropUnusedColumns(t *testing.T) {
+	type UnusedColumnPet struct {
+		ID                 uint
+		UnusedColumnUserID uint
+	}
+
+	type UnusedColumnUser struct {
+		ID        uint
+		Name      string
+		Nickname  string `gorm:"-"`
+		Pets      []UnusedColumnPet
+		CreatedAt time.Time
+	}
+
+	DB.Migrator().DropTable(&UnusedColumnUser{}, &UnusedColumnPet{})
+	if err := DB.Exec("CREATE TABLE `unused_column_users` (`id` integer,`age` integer,`nickname` text,`pets` text,`name` text,`created_at` datetime,PRIMARY KEY (`id`))").Error; err != nil {
+		t.Fatalf("failed to create table, got error %v", err)
+	}
+
+	m := newMigrator(DB, migrator.Config{})
+	if err := m.AutoMigrate(&UnusedColumnUser{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	if !DB.Migrator().HasColumn(&UnusedColumnUser{}, "age") {
+		t.Fatalf("unused columns should be kept without the option")
+	}
+
+	tx := DB.Session(&gorm.Session{Context: context.Background()})
+	tx.Statement.ConnPool = failDropConnPool{ConnPool: tx.Statement.ConnPool}
+
+	m = newMigrator(tx, migrator.Config{DropUnusedColumnsWhenAutoMigrate: true})
+	if err := m.AutoMigrate(&UnusedColumnUser{}); err == nil || !strings.Contains(err.Error(), "unused_column_users.age") {
+		t.Errorf("failed drop should name the column, got %v", err)
+	}
+
+	m.DB = DB
+	result, err := m.AutoMigrateWithResult(&UnusedColumnUser{})
+	if err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	if len(result.Tables) == 0 || len(result.Tables[0].Operations) != 1 || result.Tables[0].Operations[0].Type != "drop_column" || result.Tables[0].Operations[0].Name != "age" {
+		t.Errorf("only the unused column should be dropped, got %+v", result.Tables)
+	}
+
+	if DB.Migrator().HasColumn(&UnusedColumnUser{}, "age") {
+		t.Errorf("unused column should be dropped")
+	}
+
+	for _, name := range []string{"nickname", "pets", "name", "created_at"} {
+		if !DB.Migrator().HasColumn(&UnusedColumnUser{}, name) {
+			t.Errorf("column %v should be kept", name)
+		}
+	}
+}
+
+type EmbeddedCheckAudit struct {
+	Reviewer string `gorm:"check:reviewer_checker,reviewer <> ''"`
+	Score    int    `gorm:"check:score >= 0"`
+}
+
+func TestMigrateEmbeddedCheckConstraints(t *testing.T) {
+	type EmbeddedCheckPost struct {
+		ID uint
+		EmbeddedCheckAudit
+	}
+
+	type EmbeddedCheckComment struct {
+		ID    uint
+		Audit EmbeddedCheckAudit `gorm:"embedded"`
+	}
+
+	DB.Migrator().DropTable(&EmbeddedCheckPost{}, &EmbeddedCheckComment{})
+
+	recorder := &recordSQLLogger{Interface: DB.Logger}
+	if err := DB.Session(&gorm.Session{Context: context.Background(), Logger: recorder}).AutoMigrate(&EmbeddedCheckPost{}, &EmbeddedCheckComment{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	if err := DB.AutoMigrate(&EmbeddedCheckPost{}, &EmbeddedCheckComment{}); err != nil {
+		t.Fatalf("failed to auto migrate again, got error %v", err)
+	}
+
+	names := []string{"chk_embedded_check_posts_reviewer_checker", "chk_embedded_check_posts_score", "chk_embedded_check_comments_reviewer_checker", "chk_embedded_check_comments_score"}
+	if DB.Dialector.Name() == "sqlite" {
+		// sqlite can't reflect check constraints, they are created with the table
+		for _, name := range names {
+			if !strings.Contains(strings.Join(recorder.sqls, "\n"), "CONSTRAINT `"+name+"` CHECK") {
+				t.Errorf("failed to create check %v, got %v", name, recorder.sqls)
+			}
+		}
+		return
+	}
+
+	for idx, name := range names {
+		var value interface{} = &EmbeddedCheckPost{}
+		if idx >= 2 {
+			value = &EmbeddedCheckComment{}
+		}
+
+		if !DB.Migrator().HasConstraint(value, name) {
+			t.Errorf("failed to find check %v", name)
+		}
+	}
+}
+
+func TestMigrateEmbeddedCheckWithLegacyName(t *testing.T) {
+	type EmbeddedLegacyCheckPost struct {
+		ID uint
+		EmbeddedCheckAudit
+	}
+
+	DB.Migrator().DropTable(&EmbeddedLegacyCheckPost{})
+
+	// tables created before names of embedded checks were scoped to the table
+	if err := DB.Exec(
+		"CREATE TABLE ? (id integer PRIMARY KEY, reviewer varchar(100), score integer, CONSTRAINT ? CHECK (reviewer <> ''), CONSTRAINT ? CHECK (score >= 0))",
+		clause.Table{Name: "embedded_legacy_check_posts"}, clause.Column{Name: "reviewer_checker"}, clause.Column{Name: "chk_embedded_legacy_check_posts_score"},
+	).Error; err != nil {
+		t.Fatalf("failed to create table, got error %v", err)
+	}
+
+	recorder := &recordSQLLogger{Interface: DB.Logger}
+	for i := 0; i < 2; i++ {
+		if err := DB.Session(&gorm.Session{Context: context.Background(), Logger: recorder}).AutoMigrate(&EmbeddedLegacyCheckPost{}); err != nil {
+			t.Fatalf("failed to auto migrate %v times, got error %v", i+1, err)
+		}
+	}
+
+	for _, sql := range recorder.sqls {
+		if strings.Contains(sql, "CHECK") {
+			t.Errorf("check found by legacy name shouldn't be created again, got %v", sql)
+		}
+	}
+
+	if DB.Dialector.Name() == "sqlite" {
+		t.Skip("skip sqlite due to it can't reflect check constraints of existing tables")
+	}
+
+	if err := DB.Migrator().(migrator.AutoMigrateInterface).Validate(&EmbeddedLegacyCheckPost{}); err != nil {
+		t.Errorf("check found by legacy name should be valid, got error %v", err)
+	}
+}
+
+func TestSetTableSchema(t *testing.T) {
+	type MovedCompany struct {
+		ID uint
+	}
+
+	type MovedStruct struct {
+		ID        uint
+		CompanyID uint
+		Company   MovedCompany
+	}
+
+	switch DB.Dialector.Name() {
+	case "postgres":
+		DB.Exec("CREATE SCHEMA IF NOT EXISTS tenant_moved")
+	case "mysql":
+		DB.Exec("CREATE DATABASE IF NOT EXISTS tenant_moved")
+	default:
+		if err := DB.Migrator().(migrator.TableInterface).SetTableSchema(&MovedStruct{}, "tenant_moved"); !errors.Is(err, gorm.ErrNotImplemented) {
+			t.Errorf("moving tables across schemas should be not implemented by %v, got %v", DB.Dialector.Name(), err)
+		}
+		return
+	}
+
+	moved := DB.Migrator().(migrator.SchemaInterface).WithSchema("tenant_moved")
+	moved.DropTable(&MovedStruct{})
+	DB.Migrator().DropTable(&MovedStruct{}, &MovedCompany{}, migrator.DefaultPendingConstraintsTable)
+	if err := DB.AutoMigrate(&MovedCompany{}, &MovedStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	m := newMigrator(DB, migrator.Config{AddForeignKeysNotValid: true})
+	if DB.Dialector.Name() == "postgres" {
+		if err := m.DropConstraint(&MovedStruct{}, "fk_moved_structs_company"); err != nil {
+			t.Fatalf("failed to drop constraint, got error %v", err)
+		}
+
+		if err := m.CreateConstraint(&MovedStruct{}, "fk_moved_structs_company"); err != nil {
+			t.Fatalf("failed to create constraint, got error %v", err)
+		}
+	}
+
+	if err := m.SetTableSchema(&MovedStruct{}, "tenant_moved"); err != nil {
+		t.Fatalf("failed to move table, got error %v", err)
+	}
+
+	if DB.Migrator().HasTable(&MovedStruct{}) || !moved.HasTable(&MovedStruct{}) {
+		t.Errorf("table should be moved to schema tenant_moved")
+	}
+
+	if DB.Dialector.Name() == "postgres" {
+		// the pending constraint is validated in the schema the table is moved to
+		if err := m.ValidatePendingConstraints(); err != nil {
+			t.Fatalf("failed to validate pending constraints, got error %v", err)
+		}
+
+		var count int64
+		if DB.Table(migrator.DefaultPendingConstraintsTable).Count(&count); count != 0 {
+			t.Errorf("pending constraint of the moved table should be validated, got %v", count)
+		}
+	}
+
+	moved.DropTable(&MovedStruct{})
+	DB.Migrator().DropTable(&MovedCompany{}, migrator.DefaultPendingConstraintsTable)
+}
+
+func TestAutoMigrateTableOwner(t *testing.T) {
+	type OwnedStruct struct {
+		ID   uint
+		Name string
+	}
+
+	DB.Migrator().DropTable(&OwnedStruct{})
+
+	var owner string
+	if DB.Dialector.Name() == "postgres" {
+		DB.Raw("SELECT current_user").Row().Scan(&owner)
+	} else {
+		owner = "gorm"
+	}
+
+	recorder := &recordSQLLogger{Interface: DB.Logger}
+	m := newMigrator(DB.Session(&gorm.Session{Context: context.Background(), Logger: recorder}), migrator.Config{TableOwner: owner})
+	result, err := m.AutoMigrateWithResult(&OwnedStruct{})
+	if err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	if !DB.Migrator().HasTable(&OwnedStruct{}) {
+		t.Fatalf("table should be created")
+	}
+
+	operations := result.Tables[0].Operations
+	if DB.Dialector.Name() != "postgres" {
+		if len(operations) != 2 || operations[1].Type != "skip_set_table_owner" {
+			t.Errorf("table owner should be skipped by %v, got %+v", DB.Dialector.Name(), operations)
+		}
+		return
+	}
+
+	if len(operations) != 2 || operations[1].Type != "set_table_owner" {
+		t.Errorf("table owner should be set, got %+v", operations)
+	}
+
+	if !strings.Contains(strings.Join(recorder.sqls, "\n"), `ALTER TABLE "owned_structs" OWNER TO "`+owner+`"`) {
+		t.Errorf("table owner should be set with ALTER TABLE, got %v", recorder.sqls)
+	}
+}
+
+// wrappedMigratorDialector returns migrators not embedding migrator.Migrator, which implement none of its optional interfaces
+type wrappedMigratorDialector struct {
+	gorm.Dialector
+}
+
+func (dialector wrappedMigratorDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return wrappedMigrator{dialector.Dialector.Migrator(db)}
+}
+
+type wrappedMigrator struct {
+	gorm.Migrator
+}
+
+func TestReflectWithWrappedMigrator(t *testing.T) {
+	tx := DB.Session(&gorm.Session{Context: context.Background()})
+	tx.Dialector = wrappedMigratorDialector{DB.Dialector}
+
+	m := migrator.Migrator{Config: migrator.Config{DB: tx, Dialector: DB.Dialector}}
+	hasTable, hasColumn, hasIndex := m.HasTable(&User{}), m.HasColumn(&User{}, "Name"), m.HasIndex(&User{}, "idx_users_deleted_at")
+	m.HasConstraint(&User{}, "fk_users_company")
+
+	// default reflection queries are based on information schema, which sqlite lacks
+	if DB.Dialector.Name() != "sqlite" && (!hasTable || !hasColumn || !hasIndex) {
+		t.Errorf("failed to reflect with default queries, got table %v, column %v, index %v", hasTable, hasColumn, hasIndex)
+	}
+}
+
+func TestAutoMigrateWithWrappedMigrator(t *testing.T) {
+	type WrappedMigratorStruct struct {
+		ID       uint
+		Name     string `gorm:"size:100;default:guest;comment:name of the user;index"`
+		Price    int
+		Quantity int
+		Total    int `gorm:"generated:price * quantity STORED"`
+	}
+
+	DB.Migrator().DropTable(&WrappedMigratorStruct{})
+
+	tx := DB.Session(&gorm.Session{Context: context.Background()})
+	tx.Dialector = wrappedMigratorDialector{DB.Dialector}
+
+	// optional interfaces of migrators not embedding migrator.Migrator fall back to m
+	m := migrator.Migrator{Config: migrator.Config{DB: tx, Dialector: DB.Dialector, CreateIndexAfterCreateTable: true}}
+	if err := m.RunWithValue(&WrappedMigratorStruct{}, func(stmt *gorm.Statement) error {
+		if expr := m.FullDataTypeOf(stmt.Schema.LookUpField("Total")); !strings.Contains(expr.SQL, "price * quantity") {
+			t.Errorf("generated column should be built by m, got %v", expr.SQL)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to parse model, got error %v", err)
+	}
+
+	if err := m.AutoMigrate(&WrappedMigratorStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	// default reflection queries are based on information schema, which sqlite lacks
+	if DB.Dialector.Name() != "sqlite" {
+		if err := m.AutoMigrate(&WrappedMigratorStruct{}); err != nil {
+			t.Errorf("failed to auto migrate existing table, got error %v", err)
+		}
+	}
+}
+
+func TestDefaultValueWithWrappedMigrator(t *testing.T) {
+	type WrappedDefaultStruct struct {
+		ID   uint
+		Name string `gorm:"default:jinzhu"`
+	}
+
+	tx := DB.Session(&gorm.Session{Context: context.Background()})
+	tx.Dialector = wrappedMigratorDialector{DB.Dialector}
+
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(&WrappedDefaultStruct{}); err != nil {
+		t.Fatalf("failed to parse, got error %v", err)
+	}
+
+	m := migrator.Migrator{Config: migrator.Config{DB: tx, Dialector: DB.Dialector}}
+	if expr := m.FullDataTypeOf(stmt.Schema.LookUpField("Name")); !strings.HasSuffix(expr.SQL, "DEFAULT 'jinzhu'") {
+		t.Errorf("default value should be built by Migrator, got %v", expr.SQL)
+	}
+}
+
+type defaultOnNullDialector struct {
+	gorm.Dialector
+}
+
+func (dialector defaultOnNullDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return defaultOnNullMigrator{dialector.Dialector.Migrator(db)}
+}
+
+type defaultOnNullMigrator struct {
+	gorm.Migrator
+}
+
+// DefaultValueOf Oracle style defaults, applied to explicit NULL inserts too
+func (defaultOnNullMigrator) DefaultValueOf(field *schema.Field) string {
+	if field.DefaultOnNull {
+		return "DEFAULT ON NULL " + field.DefaultValue
+	}
+	return "DEFAULT " + field.DefaultValue
+}
+
+func TestDefaultOnNullWithDialectMigrator(t *testing.T) {
+	type DefaultOnNullStruct struct {
+		ID    uint
+		Age   int `gorm:"default:18;defaultOnNull"`
+		Score int `gorm:"default:0"`
+	}
+
+	type DefaultOnNullStruct2 struct {
+		ID  uint
+		Age int `gorm:"not null;default:18;defaultOnNull"`
+	}
+
+	tx := DB.Session(&gorm.Session{Context: context.Background()})
+	tx.Dialector = defaultOnNullDialector{DB.Dialector}
+
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(&DefaultOnNullStruct{}); err != nil {
+		t.Fatalf("failed to parse, got error %v", err)
+	}
+
+	if age, score := stmt.Schema.LookUpField("Age"), stmt.Schema.LookUpField("Score"); !age.DefaultOnNull || score.DefaultOnNull {
+		t.Errorf("only fields tagged with defaultOnNull should apply defaults on NULL, got %v, %v", age.DefaultOnNull, score.DefaultOnNull)
+	}
+
+	// dialects without the hook render the standard default
+	if expr := newMigrator(DB, migrator.Config{}).FullDataTypeOf(stmt.Schema.LookUpField("Age")); !strings.HasSuffix(expr.SQL, "DEFAULT 18") {
+		t.Errorf("default value should be built by Migrator, got %v", expr.SQL)
+	}
+
+	m := migrator.Migrator{Config: migrator.Config{DB: tx, Dialector: DB.Dialector}}
+	if expr := m.FullDataTypeOf(stmt.Schema.LookUpField("Age")); !strings.HasSuffix(expr.SQL, "DEFAULT ON NULL 18") {
+		t.Errorf("default on null should be built by the dialect migrator, got %v", expr.SQL)
+	}
+
+	if expr := m.FullDataTypeOf(stmt.Schema.LookUpField("Score")); !strings.HasSuffix(expr.SQL, "DEFAULT 0") {
+		t.Errorf("default value should be built by the dialect migrator, got %v", expr.SQL)
+	}
+
+	recorder := &recordSQLLogger{Interface: DB.Logger}
+	dryRun := tx.Session(&gorm.Session{DryRun: true, Logger: recorder})
+	m = migrator.Migrator{Config: migrator.Config{DB: dryRun, Dialector: DB.Dialector}}
+	m.AlterColumnsNullability(&DefaultOnNullStruct2{}, "Age")
+
+	if updates := recorder.statementsOf("UPDATE"); len(updates) != 1 || !strings.Contains(updates[0], "= 18 WHERE") {
+		t.Errorf("NULLs should be backfilled with the default value, got %v", recorder.sqls)
+	}
+}
+
+// customDialector out of tree dialector, which renders auto increment columns with the default syntax
+type customDialector struct {
+	gorm.Dialector
+}
+
+func (customDialector) Name() string {
+	return "custom"
+}
+
+func (customDialector) DataTypeOf(*schema.Field) string {
+	return "bigint"
+}
+
+func (dialector customDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return wrappedMigrator{dialector.Dialector.Migrator(db)}
+}
+
+func TestAutoIncrementWithCustomDialector(t *testing.T) {
+	type CustomAutoIncrementStruct struct {
+		ID uint `gorm:"primaryKey;autoIncrement"`
+	}
+
+	tx := DB.Session(&gorm.Session{Context: context.Background()})
+	tx.Dialector = customDialector{DB.Dialector}
+
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(&CustomAutoIncrementStruct{}); err != nil {
+		t.Fatalf("failed to parse, got error %v", err)
+	}
+
+	m := migrator.Migrator{Config: migrator.Config{DB: tx, Dialector: tx.Dialector}}
+	if expr := m.FullDataTypeOf(stmt.Schema.LookUpField("ID")); expr.SQL != "bigint AUTO_INCREMENT" {
+		t.Errorf("auto increment should fall back to AUTO_INCREMENT, got %v", expr.SQL)
+	}
+}
+
+type configDialector struct {
+	gorm.Dialector
+}
+
+func (dialector configDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return configMigrator{dialector.Dialector.Migrator(db), migrator.Config{DB: db, Dialector: dialector}}
+}
+
+// configMigrator dialect migrator not embedding Migrator, getting options with ConfigInterface
+type configMigrator struct {
+	gorm.Migrator
+	config migrator.Config
+}
+
+func (m configMigrator) WithConfig(config migrator.Config) gorm.Migrator {
+	config.DB, config.Dialector = m.config.DB, m.config.Dialector
+	m.config = config
+	return m
+}
+
+func (m configMigrator) AddColumn(value interface{}, field string) error {
+	return migrator.Migrator{Config: m.config}.AddColumn(value, field)
+}
+
+// ColumnForeignKeyOf inline foreign key, sqlite can't add constraints to existing tables
+func (m configMigrator) ColumnForeignKeyOf(constraint *schema.Constraint) (string, []interface{}) {
+	return " CONSTRAINT ? REFERENCES ??", []interface{}{
+		clause.Table{Name: constraint.Name}, clause.Table{Name: constraint.ReferenceSchema.Table}, []interface{}{clause.Column{Name: constraint.References[0].DBName}},
+	}
+}
+
+func TestMigrateWithConfigInterface(t *testing.T) {
+	type ConfigCompany struct {
+		ID uint
+	}
+
+	type ConfigStruct struct {
+		ID              uint
+		ConfigCompanyID *uint
+		ConfigCompany   ConfigCompany
+	}
+
+	DB.Migrator().DropTable(&ConfigStruct{}, &ConfigCompany{})
+	if err := DB.Migrator().CreateTable(&ConfigCompany{}); err != nil {
+		t.Fatalf("failed to create table, got error %v", err)
+	}
+
+	if err := DB.Table("config_structs").Migrator().CreateTable(&struct{ ID uint }{}); err != nil {
+		t.Fatalf("failed to create table, got error %v", err)
+	}
+
+	recorder := &recordSQLLogger{Interface: DB.Logger}
+	tx := DB.Session(&gorm.Session{Context: context.Background(), Logger: recorder})
+	tx.Dialector = configDialector{DB.Dialector}
+
+	m := migrator.Migrator{Config: migrator.Config{DB: tx, Dialector: tx.Dialector, AddForeignKeysWithColumn: true, SkipUnsupported: true}}
+	if err := m.AutoMigrate(&ConfigStruct{}); err != nil {
+		t.Fatalf("failed to auto migrate, got error %v", err)
+	}
+
+	var added bool
+	for _, sql := range recorder.sqls {
+		added = added || regexp.MustCompile(`^ALTER TABLE .config_structs. ADD .config_company_id. .*REFERENCES .config_companies.`).MatchString(sql)
+	}
+
+	if !added {
+		t.Errorf("column should be added with the options of ConfigInterface, got %v", recorder.sqls)
+	}
+
+	DB.Migrator().DropTable(&ConfigStruct{}, &ConfigCompany{})
+}
+
+func TestMigrateEnumWithSchema(t *testing.T) {
+	if DB.Dialector.Name() != "postgres" {
+		t.Skip("skip dialects other than postgres, which supports native enum types")
+	}
+
+	type SchemaEnumStruct struct {
+		ID   uint
+		Mood string `gorm:"enum:happy,sad"`
+	}
+
+	DB.Exec("DROP SCHEMA IF EXISTS tenant_enum CASCADE")
+	DB.Exec("DROP TYPE IF EXISTS schema_enum_structs_mood")
+	if err := DB.Exec("CREATE SCHEMA tenant_enum").Error; err != nil {
+		t.Fatalf("failed to create schema, got error %v", err)
+	}
+	defer DB.Exec("DROP SCHEMA IF EXISTS tenant_enum CASCADE")
+
+	// same named type of current schema with other values
+	if err := DB.Exec("CREATE TYPE schema_enum_structs_mood AS ENUM ('angry')").Error; err != nil {
+		t.Fatalf("failed to create type, got error %v", err)
+	}
+	defer DB.Exec("DROP TYPE IF EXISTS schema_enum_structs_mood")
+
+	stmt := &gorm.Statement{DB: DB}
+	if err := stmt.Parse(&SchemaEnumStruct{}); err != nil {
+		t.Fatalf("failed to parse, got error %v", err)
+	}
+	field := stmt.Schema.LookUpField("Mood")
+
+	m, ok := newMigrator(DB, migrator.Config{}).WithSchema("tenant_enum").(migrator.EnumInterface)
+	if !ok {
+		t.Skip("skip migrators without native enum types")
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := m.MigrateEnum(&SchemaEnumStruct{}, field); err != nil {
+			t.Fatalf("failed to migrate enum, got error %v", err)
+		}
+	}
+
+	rows, err := DB.Raw("SELECT e.enumlabel FROM pg_enum e JOIN pg_type t ON t.oid = e.enumtypid JOIN pg_namespace n ON n.oid = t.typnamespace WHERE n.nspname = ? AND t.typname = ? ORDER BY e.enumsortorder", "tenant_enum", "schema_enum_structs_mood").Rows()
+	if err != nil {
+		t.Fatalf("failed to reflect enum labels, got error %v", err)
+	}
+	defer rows.Close()
+
+	var labels []string
+	for rows.Next() {
+		var label string
+		rows.Scan(&label)
+		labels = append(labels, label)
+	}
+	if strings.Join(labels, ",") != "happy,sad" {
+		t.Errorf("enum type should be created in the schema, got %v", labels)
+	}
+}